@@ -1,19 +1,32 @@
 package main
 
-import "github.com/nickwells/param.mod/v7/param"
+import (
+	"strings"
+
+	"github.com/nickwells/param.mod/v7/param"
+)
 
 // addExamples will add examples to the program help message
 func addExamples(ps *param.PSet) error {
+	ps.AddExample(
+		"findCmpRm -diff-format "+diffFmtSideBySide+
+			" -diff-white-space "+wsIgnoreChange,
+		"This will show the differences in two columns, side by side,"+
+			" ignoring any changes in the amount of white space.")
+	ps.AddExample(
+		"findCmpRm -"+paramNameUseDiffCmd,
+		"This will use the external diff command ("+dfltDiffCmd+")"+
+			" to compare the files rather than the built-in diff"+
+			" engine.")
 	ps.AddExample(
 		"findCmpRm -diff sdiff -diff-args '-w,170'",
 		"This will use sdiff to compare the files rather than the"+
-			" default program ("+dfltDiffCmd+")")
+			" built-in diff engine.")
 	ps.AddExample(
-		"findCmpRm -diff-args '-W,170,-y,--color=always'"+ //nolint:misspell
-			" -less-args=-R",
+		"LESS=-R findCmpRm -diff-args '-W,170,-y,--color=always'", //nolint:misspell
 		"This will show the differences in two columns, side by side,"+
-			" with differences highlighted in colour and with less taking"+
-			" the colour output and displaying it."+
+			" with differences highlighted in colour and with less, the"+
+			" default pager, taking the colour output and displaying it."+
 			"\n\n"+
 			"You might want to put these parameters in the configuration"+
 			" file so that you don't have to repeatedly set them on each"+
@@ -33,6 +46,33 @@ func addExamples(ps *param.PSet) error {
 		"findCmpRm -d testdata -extension .old",
 		"This will search the testdata directory for files"+
 			" with names ending with '.old'.")
+	ps.AddExample(
+		"findCmpRm -extension .orig,.bak -prefix orig_",
+		"This will search for files with names ending with '.orig'"+
+			" or '.bak' and for files with names starting with"+
+			" 'orig_'. The files found are listed grouped by"+
+			" the way that they are named.")
+	ps.AddExample(
+		"findCmpRm -"+paramNameCommon,
+		"This will search for the backup files made by commonly"+
+			" used tools: files with names ending with any of "+
+			strings.Join(commonExtensions, ", ")+
+			" and numbered backup files such as 'F.~3~'.")
+	ps.AddExample(
+		"findCmpRm -"+paramNamePlan+" -"+paramNameDupAction+" delete",
+		"This will show what would be done with each file found"+
+			" but will not delete, revert or keep any of them.")
+	ps.AddExample(
+		"findCmpRm -"+paramNamePlan+" -"+paramNameReportFmt+" "+reportJSON,
+		"This will write a JSON report of every file found, giving"+
+			" its classification, sizes, modification times and"+
+			" the action that would be taken. No action is taken.")
+	ps.AddExample(
+		"findCmpRm -"+paramNameReportFmt+" "+reportCSV+
+			" -"+paramNameReportFile+" report.csv",
+		"This will act on the files as usual and then write a CSV"+
+			" report of every file found, with the action taken,"+
+			" to the file 'report.csv'.")
 
 	return nil
 }
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/nickwells/check.mod/v2/check"
 	"github.com/nickwells/filecheck.mod/filecheck"
 	"github.com/nickwells/param.mod/v7/paction"
@@ -15,6 +19,27 @@ const (
 	paramNameDir       = "dir"
 	paramNameNoRecurse = "dont-recurse"
 	paramNameExtension = "extension"
+	paramNamePrefix    = "prefix"
+	paramNameNumbered  = "numbered-backups"
+	paramNameCommon    = "common-backups"
+
+	paramNameDiffCmd       = "diff-cmd"
+	paramNameDiffCmdParams = "diff-cmd-params"
+	paramNameUseDiffCmd    = "use-diff-cmd"
+	paramNameDiffFormat    = "diff-format"
+	paramNameDiffContext   = "diff-context"
+	paramNameDiffWhiteSpc  = "diff-white-space"
+	paramNameDiffColour    = "diff-colour"
+	paramNameDiffWidth     = "diff-width"
+
+	paramNamePlan       = "plan"
+	paramNameReportFmt  = "report-format"
+	paramNameReportFile = "report-file"
+)
+
+const (
+	paramGroupNameDiff   = "cmd-diff"
+	paramGroupNameReport = "cmd-report"
 )
 
 // addParams will add parameters to the passed ParamSet
@@ -52,14 +77,61 @@ func addParams(prog *prog) param.PSetOptFunc {
 		)
 
 		ps.Add(paramNameExtension,
-			psetter.String[string]{
-				Value: &prog.fileExtension,
-				Checks: []check.String{
-					check.StringLength[string](check.ValGT(0)),
+			psetter.StrList[string]{
+				Value: &prog.fileExtensions,
+				Checks: []check.StringSlice{
+					check.SliceAll[[]string](
+						check.StringLength[string](check.ValGT(0))),
 				},
 			},
-			"give the extension for the files to search for.",
-			param.AltNames("e", "suffix"),
+			"give the extensions for the files to search for. A file"+
+				" with a name ending with one of these extensions is"+
+				" taken to be a backup copy of the file with the"+
+				" extension removed.",
+			param.AltNames("extensions", "e", "suffix"),
+			param.SeeAlso(paramNamePrefix, paramNameNumbered,
+				paramNameCommon),
+		)
+
+		ps.Add(paramNamePrefix,
+			psetter.StrList[string]{
+				Value: &prog.filePrefixes,
+				Checks: []check.StringSlice{
+					check.SliceAll[[]string](
+						check.StringLength[string](check.ValGT(0))),
+				},
+			},
+			"give prefixes for the files to search for. A file with a"+
+				" name starting with one of these prefixes is taken to"+
+				" be a backup copy of the file, in the same directory,"+
+				" with the prefix removed.",
+			param.AltNames("prefixes"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameExtension),
+		)
+
+		ps.Add(paramNameNumbered,
+			psetter.Bool{Value: &prog.numberedBackups},
+			"also search for numbered backup files, as made by Emacs."+
+				" These have names like 'F.~3~' and are taken to be"+
+				" backup copies of the file 'F'.",
+			param.AltNames("numbered"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameExtension),
+		)
+
+		ps.Add(paramNameCommon, psetter.Nil{},
+			"search for the backup files made by commonly used tools."+
+				" This searches for files with any of the extensions: "+
+				strings.Join(commonExtensions, ", ")+
+				" and for numbered backup files.",
+			param.AltNames("all-backups"),
+			param.PostAction(
+				paction.SetVal(&prog.fileExtensions,
+					slices.Clone(commonExtensions))),
+			param.PostAction(
+				paction.SetVal(&prog.numberedBackups, true)),
+			param.SeeAlso(paramNameExtension, paramNameNumbered),
 		)
 
 		ps.Add(paramNameTidy, psetter.Nil{},
@@ -98,22 +170,114 @@ func addParams(prog *prog) param.PSetOptFunc {
 						" (the default action)",
 					string(caKeepAll): "keep all comparable files" +
 						" without prompting",
-					string(caDeleteAll): "delete all comparable files with" +
-						" the given extension" +
-						" (" + dfltExtension + " by default)" +
-						" without prompting",
+					string(caDeleteAll): "delete all comparable backup" +
+						" files (by default, those with the extension " +
+						dfltExtension + ") without prompting",
 					string(caRevertAll): "revert all comparable files back to" +
-						" the contents of the file with" +
-						" the given extension" +
-						" (" + dfltExtension + " by default)" +
-						" without prompting",
+						" the contents of their backup files" +
+						" (by default, those with the extension " +
+						dfltExtension + ") without prompting",
 				},
 			},
 			"what action should be performed with comparable files",
 			param.AltNames("cmp-action"),
 		)
 
-		ps.Add("diff-cmd",
+		ps.AddGroup(paramGroupNameDiff,
+			"parameters controlling how the differences between"+
+				" files are shown. By default the differences are"+
+				" found by a built-in diff engine but an external"+
+				" diff command can be used instead.")
+
+		ps.Add(paramNameDiffFormat,
+			psetter.Enum[string]{
+				Value: &prog.diffOpts.format,
+				AllowedVals: psetter.AllowedVals[string]{
+					diffFmtUnified: "show the differences in the" +
+						" unified diff format",
+					diffFmtSideBySide: "show the files side by side" +
+						" in two columns",
+				},
+			},
+			"how the built-in diff engine should show the differences.",
+			param.AltNames("diff-fmt"),
+			param.GroupName(paramGroupNameDiff),
+			param.SeeAlso(paramNameDiffWidth),
+		)
+
+		ps.Add(paramNameDiffContext,
+			psetter.Int[int64]{
+				Value:  &prog.diffOpts.context,
+				Checks: []check.Int64{check.ValGE[int64](0)},
+			},
+			"how many lines of unchanged text the built-in diff"+
+				" engine should show around each change.",
+			param.AltNames("context", "U"),
+			param.GroupName(paramGroupNameDiff),
+		)
+
+		ps.Add(paramNameDiffWhiteSpc,
+			psetter.Enum[string]{
+				Value: &prog.diffOpts.whiteSpace,
+				AllowedVals: psetter.AllowedVals[string]{
+					wsExact: "lines must match exactly",
+					wsIgnoreChange: "lines which differ only in the" +
+						" amount of white space match",
+					wsIgnoreAll: "white space is ignored" +
+						" when matching lines",
+				},
+			},
+			"how the built-in diff engine should treat white space"+
+				" when comparing lines.",
+			param.AltNames("diff-ws", "white-space"),
+			param.GroupName(paramGroupNameDiff),
+		)
+
+		ps.Add(paramNameDiffColour,
+			psetter.Enum[string]{
+				Value: &prog.diffOpts.colour,
+				AllowedVals: psetter.AllowedVals[string]{
+					colourAuto: "colour the differences if the" +
+						" output is a terminal",
+					colourAlways: "always colour the differences",
+					colourNever:  "never colour the differences",
+				},
+			},
+			"when the built-in diff engine should colour the"+
+				" differences. The differences are shown through the"+
+				" pager given by the PAGER environment variable. If"+
+				" the differences are coloured then the pager must be"+
+				" able to show the colours; for less this means that"+
+				" the LESS environment variable should include '-R'.",
+			param.AltNames("diff-color", "colour", "color"),
+			param.GroupName(paramGroupNameDiff),
+		)
+
+		ps.Add(paramNameDiffWidth,
+			psetter.Int[int64]{
+				Value:  &prog.diffOpts.width,
+				Checks: []check.Int64{check.ValGE[int64](minDiffWidth)},
+			},
+			"the width of the output when the built-in diff engine"+
+				" shows the files side by side.",
+			param.AltNames("width", "W"),
+			param.GroupName(paramGroupNameDiff),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameDiffFormat),
+		)
+
+		ps.Add(paramNameUseDiffCmd,
+			psetter.Bool{Value: &prog.useDiffCmd},
+			"use the external diff command rather than the built-in"+
+				" diff engine to show the differences between files."+
+				" This is set automatically if the diff command or"+
+				" its parameters are given.",
+			param.AltNames("external-diff"),
+			param.GroupName(paramGroupNameDiff),
+			param.SeeAlso(paramNameDiffCmd, paramNameDiffCmdParams),
+		)
+
+		ps.Add(paramNameDiffCmd,
 			psetter.String[string]{
 				Value: &prog.diff.name,
 				Checks: []check.String{
@@ -121,48 +285,120 @@ func addParams(prog *prog) param.PSetOptFunc {
 				},
 			},
 			"give the name of the command to use when showing the"+
-				" differences between files",
+				" differences between files. Giving this means that"+
+				" the external diff command is used rather than the"+
+				" built-in diff engine.",
 			param.AltNames("diff"),
+			param.GroupName(paramGroupNameDiff),
 			param.Attrs(param.DontShowInStdUsage),
+			param.PostAction(paction.SetVal(&prog.useDiffCmd, true)),
+			param.SeeAlso(paramNameUseDiffCmd),
 		)
 
-		ps.Add("diff-cmd-params",
+		ps.Add(paramNameDiffCmdParams,
 			psetter.StrList[string]{
 				Value: &prog.diff.params,
 				Checks: []check.StringSlice{
 					check.SliceLength[[]string](check.ValGT(0)),
 				},
 			},
-			"give any parameters to be supplied to the diff command.",
+			"give any parameters to be supplied to the diff command."+
+				" Giving this means that the external diff command is"+
+				" used rather than the built-in diff engine.",
 			param.AltNames("diff-params", "diff-args"),
+			param.GroupName(paramGroupNameDiff),
 			param.Attrs(param.DontShowInStdUsage),
+			param.PostAction(paction.SetVal(&prog.useDiffCmd, true)),
+			param.SeeAlso(paramNameUseDiffCmd),
 		)
 
-		ps.Add("less-cmd",
-			psetter.String[string]{
-				Value: &prog.less.name,
-				Checks: []check.String{
-					check.StringLength[string](check.ValGT(0)),
-				},
-			},
-			"give the name of the command to use for paginating the"+
-				" differences calculated by the diff command.",
-			param.AltNames("less"),
-			param.Attrs(param.DontShowInStdUsage),
+		ps.AddGroup(paramGroupNameReport,
+			"parameters controlling the report of the files found"+
+				" and the actions taken.")
+
+		ps.Add(paramNamePlan,
+			psetter.Bool{Value: &prog.plan},
+			"take no actions, instead report the action planned for"+
+				" each file. The planned action depends on the"+
+				" "+paramNameDupAction+" and "+paramNameCmpAction+
+				" parameters; for files where the user would be asked"+
+				" what to do the planned action is '"+actQuery+"'.",
+			param.AltNames("dry-run", "no-action"),
+			param.GroupName(paramGroupNameReport),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameReportFmt),
 		)
 
-		ps.Add("less-cmd-params",
-			psetter.StrList[string]{
-				Value: &prog.less.params,
-				Checks: []check.StringSlice{
-					check.SliceLength[[]string](check.ValGT(0)),
+		ps.Add(paramNameReportFmt,
+			psetter.Enum[string]{
+				Value: &prog.reportFormat,
+				AllowedVals: psetter.AllowedVals[string]{
+					reportText: "describe the files found and the" +
+						" actions taken in prose",
+					reportJSON: "report every file found, with its" +
+						" class, sizes, modification times and the" +
+						" action taken or planned, as a JSON object",
+					reportCSV: "report every file found, with its" +
+						" class, sizes, modification times and the" +
+						" action taken or planned, as CSV with a" +
+						" header line",
 				},
 			},
-			"give any parameters to be supplied to the less command.",
-			param.AltNames("less-params", "less-args"),
-			param.Attrs(param.DontShowInStdUsage),
+			"how the files found and the actions taken should be"+
+				" reported. If a JSON or CSV report is written to the"+
+				" standard output then all the other output is"+
+				" written to the standard error. The files cannot"+
+				" then be queried so either the "+paramNamePlan+
+				" parameter must be given or actions which do not"+
+				" query must be chosen.",
+			param.AltNames("report"),
+			param.GroupName(paramGroupNameReport),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNamePlan, paramNameReportFile),
 		)
 
+		ps.Add(paramNameReportFile,
+			psetter.Pathname{Value: &prog.reportFile},
+			"the file to write the JSON or CSV report into. If this is"+
+				" not given the report is written to the standard"+
+				" output.",
+			param.GroupName(paramGroupNameReport),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameReportFmt),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.reportFile != "" && prog.reportFormat == reportText {
+				return fmt.Errorf(
+					"the %q parameter can only be given"+
+						" with a %s or %s report",
+					paramNameReportFile, reportJSON, reportCSV)
+			}
+
+			return nil
+		})
+
+		ps.AddFinalCheck(func() error {
+			if prog.reportFormat == reportText ||
+				prog.reportFile != "" ||
+				prog.plan {
+				return nil
+			}
+
+			if prog.dupAction == daQuery ||
+				prog.cmpAction == caQuery ||
+				prog.cmpAction == caShowDiff {
+				return fmt.Errorf(
+					"the %s report is written to the standard output"+
+						" so the files cannot be queried: give the %q"+
+						" parameter, give the %q parameter or choose"+
+						" actions which do not query",
+					prog.reportFormat, paramNamePlan, paramNameReportFile)
+			}
+
+			return nil
+		})
+
 		return nil
 	}
 }
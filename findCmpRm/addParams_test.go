@@ -140,14 +140,33 @@ func TestParseParamsCmdProg(t *testing.T) {
 	{
 		testCases = append(testCases,
 			mkTestParser(nil, testhelper.MkID("good: extension"),
-				func(prog *prog) { prog.fileExtension = ".pre" }, nil, nil,
+				func(prog *prog) { prog.fileExtensions = []string{".pre"} },
+				nil, nil,
 				"-"+paramNameExtension, ".pre"))
 	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: extensions"),
+				func(prog *prog) {
+					prog.fileExtensions = []string{".pre", "~"}
+				}, nil, nil,
+				"-"+paramNameExtension, ".pre,~"))
+	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: common-backups"),
+				func(prog *prog) {
+					prog.fileExtensions = commonExtensions
+					prog.numberedBackups = true
+				}, nil, nil,
+				"-"+paramNameCommon))
+	}
 	{
 		parseErrs := errutil.ErrMap{}
 		parseErrs.AddError(
 			paramNameExtension,
-			errors.New("the length of the string (0) is incorrect:"+
+			errors.New("list entry: 0 () does not pass the test:"+
+				" the length of the string (0) is incorrect:"+
 				" the value (0) must be greater than 0\n"+
 				"At: [command line]:"+
 				` Supplied Parameter:2: "-extension" ""`))
@@ -157,6 +176,89 @@ func TestParseParamsCmdProg(t *testing.T) {
 				nil, nil, nil,
 				"-"+paramNameExtension, ""))
 	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: diff-format"),
+				func(prog *prog) {
+					prog.diffOpts.format = diffFmtSideBySide
+				}, nil, nil,
+				"-"+paramNameDiffFormat, diffFmtSideBySide))
+	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: diff-white-space"),
+				func(prog *prog) {
+					prog.diffOpts.whiteSpace = wsIgnoreAll
+				}, nil, nil,
+				"-"+paramNameDiffWhiteSpc, wsIgnoreAll))
+	}
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			paramNameDiffWidth,
+			errors.New("the value (10) must be greater than or equal to 20"+
+				"\n"+
+				"At: [command line]:"+
+				` Supplied Parameter:2: "-diff-width" "10"`))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs, testhelper.MkID("bad: diff-width"),
+				nil, nil, nil,
+				"-"+paramNameDiffWidth, "10"))
+	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: diff-cmd"),
+				func(prog *prog) {
+					prog.diff.name = "sdiff"
+					prog.useDiffCmd = true
+				}, nil, nil,
+				"-"+paramNameDiffCmd, "sdiff"))
+	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: plan"),
+				func(prog *prog) { prog.plan = true }, nil, nil,
+				"-"+paramNamePlan))
+	}
+	{
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID("good: report-format"),
+				func(prog *prog) {
+					prog.reportFormat = reportJSON
+					prog.plan = true
+				}, nil, nil,
+				"-"+paramNameReportFmt, reportJSON, "-"+paramNamePlan))
+	}
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			"Final Checks",
+			errors.New(
+				"the json report is written to the standard output"+
+					" so the files cannot be queried: give the"+
+					` "plan" parameter, give the "report-file"`+
+					" parameter or choose actions which do not query"))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs,
+				testhelper.MkID("bad: report-format, query to stdout"),
+				func(prog *prog) { prog.reportFormat = reportJSON }, nil, nil,
+				"-"+paramNameReportFmt, reportJSON))
+	}
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			paramNameReportFmt,
+			errors.New(`value is not allowed: "xml"`+"\n"+
+				"At: [command line]:"+
+				` Supplied Parameter:2: "-report-format" "xml"`))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs, testhelper.MkID("bad: report-format"),
+				nil, nil, nil,
+				"-"+paramNameReportFmt, "xml"))
+	}
 
 	for _, tc := range testCases {
 		_ = tc.Test(t)
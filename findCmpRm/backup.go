@@ -0,0 +1,197 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// The kinds of backup naming scheme
+const (
+	schemeNumbered = "numbered"
+	schemeSuffix   = "suffix"
+	schemePrefix   = "prefix"
+)
+
+// numberedBackupRE matches the name of an Emacs-style numbered backup
+// file, such as 'file.~3~', capturing the name of the base file
+var numberedBackupRE = regexp.MustCompile(`^(.+)\.~[0-9]+~$`)
+
+// commonExtensions are the extensions used for backup files by commonly
+// used tools
+var commonExtensions = []string{".orig", ".bak", "~", ".rej"}
+
+// backupScheme records a way of naming a backup copy of a file
+type backupScheme struct {
+	kind  string
+	affix string
+}
+
+// String returns a description of the names matched by the scheme
+func (bs backupScheme) String() string {
+	switch bs.kind {
+	case schemeNumbered:
+		return "*.~N~"
+	case schemePrefix:
+		return bs.affix + "*"
+	}
+
+	return "*" + bs.affix
+}
+
+// baseName returns the name of the file of which the named file is a backup
+// copy under this scheme. If the name does not match the scheme it returns
+// false.
+func (bs backupScheme) baseName(backupName string) (string, bool) {
+	dir, file := filepath.Split(backupName)
+
+	var base string
+
+	switch bs.kind {
+	case schemeNumbered:
+		m := numberedBackupRE.FindStringSubmatch(file)
+		if m == nil {
+			return "", false
+		}
+
+		base = m[1]
+	case schemeSuffix:
+		base, _ = strings.CutSuffix(file, bs.affix)
+		if base == file {
+			return "", false
+		}
+	case schemePrefix:
+		base, _ = strings.CutPrefix(file, bs.affix)
+		if base == file {
+			return "", false
+		}
+	}
+
+	if base == "" {
+		return "", false
+	}
+
+	return dir + base, true
+}
+
+// makeBackupSchemes returns the backup naming schemes in the order in which
+// they are tried. Numbered backups come first as their names would
+// otherwise match a '~' extension; then the extensions and the prefixes,
+// longest first so that the most specific scheme is chosen.
+func (prog prog) makeBackupSchemes() []backupScheme {
+	schemes := []backupScheme{}
+
+	if prog.numberedBackups {
+		schemes = append(schemes, backupScheme{kind: schemeNumbered})
+	}
+
+	byLen := func(affixes []string) []string {
+		return slices.SortedStableFunc(slices.Values(affixes),
+			func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	}
+
+	for _, ext := range byLen(prog.fileExtensions) {
+		schemes = append(schemes, backupScheme{kind: schemeSuffix, affix: ext})
+	}
+
+	for _, pfx := range byLen(prog.filePrefixes) {
+		schemes = append(schemes, backupScheme{kind: schemePrefix, affix: pfx})
+	}
+
+	return slices.Compact(schemes)
+}
+
+// setBackupSchemes records the backup naming schemes on the prog. It should
+// be called after the parameters have been parsed and before any file names
+// are matched.
+func (prog *prog) setBackupSchemes() {
+	prog.schemes = prog.makeBackupSchemes()
+}
+
+// schemeOf returns the index of the first scheme matching the name and the
+// name of the base file. If no scheme matches it returns -1.
+func (prog prog) schemeOf(backupName string) (int, string) {
+	for i, bs := range prog.schemes {
+		if base, ok := bs.baseName(backupName); ok {
+			return i, base
+		}
+	}
+
+	return -1, ""
+}
+
+// baseName returns the name of the file of which the named file is a backup
+// copy. If the name matches none of the backup schemes it is returned
+// unchanged.
+func (prog prog) baseName(backupName string) string {
+	if i, base := prog.schemeOf(backupName); i >= 0 {
+		return base
+	}
+
+	return backupName
+}
+
+// isBackupName returns an error if the name matches none of the backup
+// schemes. It is suitable for use when searching for backup files.
+func (prog prog) isBackupName(name string) error {
+	if i, _ := prog.schemeOf(name); i < 0 {
+		return fmt.Errorf("%q is not the name of a backup file", name)
+	}
+
+	return nil
+}
+
+// schemesDesc returns a description of the names matched by the backup
+// schemes
+func (prog prog) schemesDesc() string {
+	descs := []string{}
+	for _, bs := range prog.schemes {
+		descs = append(descs, bs.String())
+	}
+
+	return strings.Join(descs, ", ")
+}
+
+// sortBySchemeThenName sorts the names by the index of the backup scheme
+// that they match and then by name
+func (prog prog) sortBySchemeThenName(names []string) {
+	slices.SortStableFunc(names, func(a, b string) int {
+		ai, _ := prog.schemeOf(a)
+		bi, _ := prog.schemeOf(b)
+
+		return cmp.Or(cmp.Compare(ai, bi), strings.Compare(a, b))
+	})
+}
+
+// schemeGroup records a run of files, all matching the same backup scheme
+type schemeGroup struct {
+	scheme     string
+	start, end int
+}
+
+// groupByScheme returns the runs of the names matching the same backup
+// scheme. The names should already be sorted by scheme.
+func (prog prog) groupByScheme(names []string) []schemeGroup {
+	groups := []schemeGroup{}
+
+	for i, name := range names {
+		idx, _ := prog.schemeOf(name)
+
+		desc := "other"
+		if idx >= 0 {
+			desc = prog.schemes[idx].String()
+		}
+
+		if len(groups) > 0 && groups[len(groups)-1].scheme == desc {
+			groups[len(groups)-1].end = i + 1
+			continue
+		}
+
+		groups = append(groups, schemeGroup{scheme: desc, start: i, end: i + 1})
+	}
+
+	return groups
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestBackupSchemeBaseName(t *testing.T) {
+	numbered := backupScheme{kind: schemeNumbered}
+	orig := backupScheme{kind: schemeSuffix, affix: ".orig"}
+	tilde := backupScheme{kind: schemeSuffix, affix: "~"}
+	prefix := backupScheme{kind: schemePrefix, affix: "orig_"}
+
+	testCases := []struct {
+		testhelper.ID
+		scheme     backupScheme
+		name       string
+		expBase    string
+		expMatches bool
+	}{
+		{
+			ID:         testhelper.MkID("suffix"),
+			scheme:     orig,
+			name:       filepath.Join("d", "f.go.orig"),
+			expBase:    filepath.Join("d", "f.go"),
+			expMatches: true,
+		},
+		{
+			ID:     testhelper.MkID("suffix, no match"),
+			scheme: orig,
+			name:   filepath.Join("d", "f.go"),
+		},
+		{
+			ID:     testhelper.MkID("suffix, whole name"),
+			scheme: orig,
+			name:   filepath.Join("d", ".orig"),
+		},
+		{
+			ID:         testhelper.MkID("tilde"),
+			scheme:     tilde,
+			name:       "f.go~",
+			expBase:    "f.go",
+			expMatches: true,
+		},
+		{
+			ID:         testhelper.MkID("numbered"),
+			scheme:     numbered,
+			name:       filepath.Join("d", "f.go.~12~"),
+			expBase:    filepath.Join("d", "f.go"),
+			expMatches: true,
+		},
+		{
+			ID:     testhelper.MkID("numbered, no number"),
+			scheme: numbered,
+			name:   "f.go.~~",
+		},
+		{
+			ID:         testhelper.MkID("prefix"),
+			scheme:     prefix,
+			name:       filepath.Join("orig_d", "orig_f.go"),
+			expBase:    filepath.Join("orig_d", "f.go"),
+			expMatches: true,
+		},
+		{
+			ID:     testhelper.MkID("prefix, only in dir name"),
+			scheme: prefix,
+			name:   filepath.Join("orig_d", "f.go"),
+		},
+	}
+
+	for _, tc := range testCases {
+		base, ok := tc.scheme.baseName(tc.name)
+		testhelper.DiffBool(t, tc.IDStr(), "matches", ok, tc.expMatches)
+		testhelper.DiffString(t, tc.IDStr(), "base name", base, tc.expBase)
+	}
+}
+
+func TestSchemeOf(t *testing.T) {
+	prog := newProg()
+	prog.fileExtensions = []string{"~", ".orig", ".orig~"}
+	prog.filePrefixes = []string{"orig_"}
+	prog.numberedBackups = true
+	prog.setBackupSchemes()
+
+	testhelper.DiffString(t, "schemes", "description",
+		prog.schemesDesc(), "*.~N~, *.orig~, *.orig, *~, orig_*")
+
+	testCases := []struct {
+		testhelper.ID
+		name      string
+		expScheme int
+		expBase   string
+	}{
+		{
+			ID:        testhelper.MkID("numbered, not tilde"),
+			name:      "f.~3~",
+			expScheme: 0,
+			expBase:   "f",
+		},
+		{
+			ID:        testhelper.MkID("longest extension"),
+			name:      "f.orig~",
+			expScheme: 1,
+			expBase:   "f",
+		},
+		{
+			ID:        testhelper.MkID("extension"),
+			name:      "f.orig",
+			expScheme: 2,
+			expBase:   "f",
+		},
+		{
+			ID:        testhelper.MkID("extension before prefix"),
+			name:      "orig_f~",
+			expScheme: 3,
+			expBase:   "orig_f",
+		},
+		{
+			ID:        testhelper.MkID("prefix"),
+			name:      "orig_f",
+			expScheme: 4,
+			expBase:   "f",
+		},
+		{
+			ID:        testhelper.MkID("no match"),
+			name:      "f",
+			expScheme: -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		scheme, base := prog.schemeOf(tc.name)
+		testhelper.DiffInt(t, tc.IDStr(), "scheme", scheme, tc.expScheme)
+		testhelper.DiffString(t, tc.IDStr(), "base name", base, tc.expBase)
+	}
+}
+
+func TestGetFilesBySchemes(t *testing.T) {
+	tempTestDir := filepath.Join("testdata", "tempTestDir")
+
+	prog := newProg()
+	prog.searchDir = tempTestDir
+	prog.fileExtensions = commonExtensions
+	prog.filePrefixes = []string{"orig_"}
+	prog.numberedBackups = true
+	prog.setBackupSchemes()
+
+	defer getFilesTestCleanup(t, "getFiles by schemes", prog, nil)
+
+	for _, fp := range []struct {
+		name, backup, content string
+	}{
+		{name: "f1", backup: "f1.orig", content: "Hello"},
+		{name: "f2", backup: "f2~", content: "Hello"},
+		{name: "f3", backup: "f3.~2~", content: "World"},
+		{name: "f4", backup: "orig_f4", content: "Hello"},
+		{name: "f5", backup: "f5.bak", content: "World"},
+	} {
+		err := makeTestDir(tempTestDir, "",
+			filePairInfo{
+				name:        fp.backup,
+				origDetails: &fileInfo{contents: fp.content},
+			},
+			filePairInfo{
+				name:        fp.name,
+				origDetails: &fileInfo{contents: "World"},
+			})
+		if err != nil {
+			t.Fatal("unexpected makeTestDir error: ", err)
+		}
+	}
+
+	comparables, duplicates, badFiles, errs := prog.getFiles()
+	if len(errs) != 0 {
+		t.Fatal("unexpected getFiles errors: ", errs)
+	}
+
+	testhelper.DiffInt(t, "getFiles by schemes", "bad files",
+		len(badFiles), 0)
+	testhelper.DiffStringSlice(t, "getFiles by schemes", "duplicates",
+		duplicates, []string{
+			filepath.Join(tempTestDir, "f3.~2~"),
+			filepath.Join(tempTestDir, "f5.bak"),
+		})
+	testhelper.DiffStringSlice(t, "getFiles by schemes", "comparables",
+		comparables, []string{
+			filepath.Join(tempTestDir, "f1.orig"),
+			filepath.Join(tempTestDir, "f2~"),
+			filepath.Join(tempTestDir, "orig_f4"),
+		})
+
+	fakeIO := setupFakeIO(t, prog, "getFiles by schemes", "comparable", "")
+
+	prog.showComparableFiles(comparables)
+
+	stdout, _ := getFakeIO(t, "getFiles by schemes", "comparable", fakeIO)
+	testhelper.DiffString(t, "getFiles by schemes", "comparable stdout",
+		string(stdout),
+		"3 comparable files found\n"+
+			"in testdata/tempTestDir\n"+
+			"    backups named *.orig:\n"+
+			"        - 1: f1.orig\n"+
+			"    backups named *~:\n"+
+			"        - 2: f2~\n"+
+			"    backups named orig_*:\n"+
+			"        - 3: orig_f4\n")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nickwells/pager.mod/pager"
+	"github.com/nickwells/utilities/internal/textdiff"
+)
+
+// The values of the diff-format parameter
+const (
+	diffFmtUnified    = "unified"
+	diffFmtSideBySide = "side-by-side"
+)
+
+// The values of the diff-white-space parameter
+const (
+	wsExact        = textdiff.WSExact
+	wsIgnoreChange = textdiff.WSIgnoreChange
+	wsIgnoreAll    = textdiff.WSIgnoreAll
+)
+
+// The values of the diff-colour parameter
+const (
+	colourAuto   = "auto"
+	colourAlways = "always"
+	colourNever  = "never"
+)
+
+const (
+	dfltDiffContext = 3
+	dfltDiffWidth   = 130
+	minDiffWidth    = 20
+)
+
+// diffOpts records how the built-in diff engine shows the differences
+type diffOpts struct {
+	format     string
+	context    int64
+	whiteSpace string
+	colour     string
+	width      int64
+}
+
+// isBinary returns true if the content looks like binary data rather than
+// text
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// makeDiff reads the two files and returns the differences between them
+// formatted as set by the diff options.
+func (prog prog) makeDiff(nameOrig, nameNew string) (string, error) {
+	origContent, err := os.ReadFile(nameOrig)
+	if err != nil {
+		return "", err
+	}
+
+	newContent, err := os.ReadFile(nameNew)
+	if err != nil {
+		return "", err
+	}
+
+	if isBinary(origContent) || isBinary(newContent) {
+		return fmt.Sprintf("Binary files %s and %s differ\n",
+			nameOrig, nameNew), nil
+	}
+
+	ops := textdiff.Diff(textdiff.SplitLines(string(origContent)),
+		textdiff.SplitLines(string(newContent)),
+		prog.diffOpts.whiteSpace)
+	hunks := textdiff.Hunks(ops, int(prog.diffOpts.context))
+
+	if len(hunks) == 0 {
+		if prog.diffOpts.whiteSpace == wsExact {
+			return fmt.Sprintf("No differences found between %s and %s\n",
+				nameOrig, nameNew), nil
+		}
+
+		return fmt.Sprintf("%s and %s differ only in white space\n",
+			nameOrig, nameNew), nil
+	}
+
+	dc := textdiff.NewColours(prog.useColour())
+
+	if prog.diffOpts.format == diffFmtSideBySide {
+		return textdiff.SideBySide(nameOrig, nameNew, ops, hunks,
+			int(prog.diffOpts.width), dc), nil
+	}
+
+	return textdiff.Unified(nameOrig, nameNew, ops, hunks, dc), nil
+}
+
+// useColour returns true if the diff output should be coloured. If the
+// colour parameter is set to auto then the output is coloured only if the
+// output is a terminal.
+func (prog prog) useColour() bool {
+	switch prog.diffOpts.colour {
+	case colourAlways:
+		return true
+	case colourNever:
+		return false
+	}
+
+	f, ok := prog.outW.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// builtinDiffs finds the differences between the two files using the
+// built-in diff engine and pages them
+func (prog prog) builtinDiffs(nameOrig, nameNew string) error {
+	diff, err := prog.makeDiff(nameOrig, nameNew)
+	if err != nil {
+		return fmt.Errorf("the differences could not be found: %w", err)
+	}
+
+	pw := pager.W()
+	pw.Start()
+	defer pw.Done()
+
+	_, err = io.WriteString(pw.StdW(), diff)
+
+	return err
+}
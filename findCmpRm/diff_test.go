@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMakeDiff(t *testing.T) {
+	dir := t.TempDir()
+
+	mkFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal("can't make the test file: ", err)
+		}
+
+		return path
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		origContent string
+		newContent  string
+		whiteSpace  string
+		expDiff     string
+	}{
+		{
+			ID:          testhelper.MkID("differences"),
+			origContent: "a\nb\n",
+			newContent:  "a\nc\n",
+			whiteSpace:  wsExact,
+			expDiff: "--- F.orig\n+++ F\n" +
+				"@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+		{
+			ID:          testhelper.MkID("white space only"),
+			origContent: "a b\n",
+			newContent:  "a\tb\n",
+			whiteSpace:  wsIgnoreChange,
+			expDiff:     "F.orig and F differ only in white space\n",
+		},
+		{
+			ID:          testhelper.MkID("binary"),
+			origContent: "a\x00b\n",
+			newContent:  "a\n",
+			whiteSpace:  wsExact,
+			expDiff:     "Binary files F.orig and F differ\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		origName := mkFile("F.orig", tc.origContent)
+		newName := mkFile("F", tc.newContent)
+
+		prog := newProg()
+		prog.diffOpts.whiteSpace = tc.whiteSpace
+		prog.diffOpts.colour = colourNever
+
+		diff, err := prog.makeDiff(origName, newName)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "diff",
+				strings.ReplaceAll(diff, dir+string(filepath.Separator), ""),
+				tc.expDiff)
+		}
+	}
+
+	prog := newProg()
+
+	_, err := prog.makeDiff(filepath.Join(dir, "nonesuch"),
+		filepath.Join(dir, "F"))
+	if err == nil {
+		t.Log("test: missing file")
+		t.Errorf("\t: an error was expected but none was returned")
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"sort"
+	"slices"
 	"strings"
 
 	"github.com/nickwells/check.mod/v2/check"
@@ -13,6 +15,7 @@ import (
 	"github.com/nickwells/dirsearch.mod/v2/dirsearch"
 	"github.com/nickwells/english.mod/english"
 	"github.com/nickwells/mathutil.mod/v2/mathutil"
+	"github.com/nickwells/pager.mod/pager"
 	"github.com/nickwells/twrap.mod/twrap"
 	"github.com/nickwells/verbose.mod/verbose"
 )
@@ -23,9 +26,9 @@ const (
 	dfltExtension = ".orig"
 	dfltDir       = "."
 	dfltDiffCmd   = "diff"
-	dfltLessCmd   = "less"
 
 	filenameIndent = 8
+	schemeIndent   = 4
 )
 
 // dupAction records the action to perform on a duplicate file
@@ -61,12 +64,18 @@ type prog struct {
 	stack *verbose.Stack
 
 	// parameters
-	searchDir     string
-	searchSubDirs bool
-	fileExtension string
+	searchDir       string
+	searchSubDirs   bool
+	fileExtensions  []string
+	filePrefixes    []string
+	numberedBackups bool
 
-	diff cmdInfo
-	less cmdInfo
+	// the backup naming schemes, set from the parameters
+	schemes []backupScheme
+
+	diff       cmdInfo
+	useDiffCmd bool
+	diffOpts   diffOpts
 
 	showDiffR  responder.Responder
 	deleteDupR responder.Responder
@@ -75,7 +84,17 @@ type prog struct {
 	dupAction dupAction
 	cmpAction cmpAction
 
+	plan         bool
+	reportFormat string
+	reportFile   string
+
+	// the machine-readable report
+	reportW     io.Writer
+	fileActions map[string]fileAction
+	fileStats   map[string]fileStat
+
 	// display
+	outW   io.Writer
 	twc    *twrap.TWConf
 	indent int
 
@@ -85,23 +104,35 @@ type prog struct {
 
 // newProg returns a new Prog instance with the default values set
 func newProg() *prog {
-	return &prog{
+	prog := &prog{
 		stack: &verbose.Stack{},
 
-		searchDir:     dfltDir,
-		searchSubDirs: true,
-		fileExtension: dfltExtension,
+		searchDir:      dfltDir,
+		searchSubDirs:  true,
+		fileExtensions: []string{dfltExtension},
 
 		diff: cmdInfo{name: dfltDiffCmd},
-		less: cmdInfo{name: dfltLessCmd},
+		diffOpts: diffOpts{
+			format:     diffFmtUnified,
+			context:    dfltDiffContext,
+			whiteSpace: wsExact,
+			colour:     colourAuto,
+			width:      dfltDiffWidth,
+		},
 
 		dupAction: daQuery,
 		cmpAction: caQuery,
 
-		twc: twrap.NewTWConfOrPanic(),
+		reportFormat: reportText,
+
+		outW: os.Stdout,
+		twc:  twrap.NewTWConfOrPanic(),
 
 		status: InitStatus(),
 	}
+	prog.setBackupSchemes()
+
+	return prog
 }
 
 // setResponders sets the responders on the Prog
@@ -112,10 +143,10 @@ func (prog *prog) setResponders() {
 			'y': "to show differences",
 			'n': "to skip this file",
 			'd': "delete this and all subsequent files" +
-				" with extension " + prog.fileExtension,
+				" named " + prog.schemesDesc(),
 			'r': "revert this and all subsequent base files to" +
-				" the contents of the files with extension " +
-				prog.fileExtension,
+				" the contents of the files named " +
+				prog.schemesDesc(),
 			'q': "to quit, keeping all subsequent files",
 		},
 		responder.SetDefault('y'))
@@ -133,8 +164,8 @@ func (prog *prog) setResponders() {
 	prog.deleteDupR = responder.NewOrPanic(
 		"delete all duplicate files",
 		map[rune]string{
-			'y': "to delete all duplicates files with extension " +
-				prog.fileExtension,
+			'y': "to delete all duplicates files named " +
+				prog.schemesDesc(),
 			'n': "to keep these duplicates",
 		},
 		responder.SetDefault('y'))
@@ -151,8 +182,14 @@ func main() {
 	ps := makeParamSet(prog)
 
 	ps.Parse()
+	prog.setBackupSchemes()
 	prog.setResponders()
 
+	showText := prog.reportFormat == reportText
+	if !showText {
+		prog.startReport()
+	}
+
 	filenames, duplicates, badFiles, errs := prog.getFiles()
 
 	if len(errs) != 0 {
@@ -165,13 +202,41 @@ func main() {
 		os.Exit(1)
 	}
 
-	prog.showBadFiles(badFiles)
+	// the files are always listed before any action is taken on them
+	showLists := showText || !prog.plan
 
-	prog.showDuplicateFiles(duplicates)
-	prog.processDuplicateFiles(duplicates)
+	if showLists {
+		prog.showBadFiles(badFiles)
+		prog.showDuplicateFiles(duplicates)
+	}
+
+	if !prog.plan {
+		prog.processDuplicateFiles(duplicates)
+	}
+
+	if showLists {
+		prog.showComparableFiles(filenames)
+	}
+
+	if !prog.plan {
+		prog.processComparableFiles(filenames)
+	}
 
-	prog.showComparableFiles(filenames)
-	prog.processComparableFiles(filenames)
+	r := prog.makeReport(filenames, duplicates, badFiles)
+
+	if !showText {
+		if err := prog.writeReport(r); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't write the report: %v\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if prog.plan {
+		prog.showPlan(r)
+		return
+	}
 
 	prog.status.Report()
 }
@@ -211,18 +276,26 @@ func (prog *prog) showBadFiles(badFiles []badFile) {
 	}
 
 	shortNames, maxNameLen := prog.shortNames(filenames)
-	reportFiles(len(filenames), "problem", "found")
+	reportFiles(prog.outW, len(filenames), "problem", "found")
+
+	fmt.Fprintln(prog.outW, "in", prog.searchDir)
 
-	fmt.Println("in", prog.searchDir)
+	groups := prog.groupByScheme(filenames)
 
-	for i, name := range shortNames {
-		fmt.Printf("%s%*s - %s\n",
-			strings.Repeat(" ", filenameIndent),
-			maxNameLen,
-			name, badFiles[i].problem)
+	for _, g := range groups {
+		if len(groups) > 1 {
+			showSchemeHeading(prog.outW, g.scheme)
+		}
+
+		for i := g.start; i < g.end; i++ {
+			fmt.Fprintf(prog.outW, "%s%*s - %s\n",
+				strings.Repeat(" ", filenameIndent),
+				maxNameLen,
+				shortNames[i], badFiles[i].problem)
+		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 }
 
 // showDuplicateFiles displays the list of duplicate files and prompts the user
@@ -236,10 +309,9 @@ func (prog *prog) showDuplicateFiles(dupFiles []string) {
 
 	prog.status.dupFile.total = len(dupFiles)
 
-	shortNames, _ := prog.shortNames(dupFiles)
-	reportFiles(len(dupFiles), "duplicate", "found")
-	fmt.Println("in", prog.searchDir)
-	prog.twc.NoRptPathList(shortNames, filenameIndent)
+	reportFiles(prog.outW, len(dupFiles), "duplicate", "found")
+	fmt.Fprintln(prog.outW, "in", prog.searchDir)
+	prog.showFileList(dupFiles, false)
 }
 
 // processDuplicateFiles checks the duplicate action and then either deletes
@@ -260,7 +332,7 @@ func (prog *prog) processDuplicateFiles(dupFiles []string) {
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 }
 
 // showComparableFiles loops over the files prompting the user to compare
@@ -275,11 +347,51 @@ func (prog *prog) showComparableFiles(cmpFiles []string) {
 
 	prog.status.cmpFile.total = len(cmpFiles)
 
-	shortNames, _ := prog.shortNames(cmpFiles)
+	reportFiles(prog.outW, len(cmpFiles), "comparable", "found")
+	fmt.Fprintln(prog.outW, "in", prog.searchDir)
+	prog.showFileList(cmpFiles, true)
+}
 
-	reportFiles(len(cmpFiles), "comparable", "found")
-	fmt.Println("in", prog.searchDir)
-	prog.twc.IdxNoRptPathList(shortNames, filenameIndent)
+// showSchemeHeading shows the heading for the files named using a backup
+// scheme
+func showSchemeHeading(w io.Writer, scheme string) {
+	fmt.Fprintf(w, "%sbackups named %s:\n",
+		strings.Repeat(" ", schemeIndent), scheme)
+}
+
+// showFileList shows the list of files. If the files match more than one
+// backup scheme they are shown grouped by scheme. If indexed is true each
+// file is shown with its position in the list.
+func (prog *prog) showFileList(filenames []string, indexed bool) {
+	shortNames, _ := prog.shortNames(filenames)
+	groups := prog.groupByScheme(filenames)
+
+	if len(groups) <= 1 {
+		if indexed {
+			prog.twc.IdxNoRptPathList(shortNames, filenameIndent)
+		} else {
+			prog.twc.NoRptPathList(shortNames, filenameIndent)
+		}
+
+		return
+	}
+
+	digits := mathutil.Digits(int64(len(filenames)))
+
+	for _, g := range groups {
+		showSchemeHeading(prog.outW, g.scheme)
+
+		if !indexed {
+			prog.twc.NoRptPathList(shortNames[g.start:g.end], filenameIndent)
+			continue
+		}
+
+		for i := g.start; i < g.end; i++ {
+			fmt.Fprintf(prog.outW, "%s%s%*d: %s\n",
+				strings.Repeat(" ", filenameIndent), prog.twc.ListPrefix,
+				digits, i+1, shortNames[i])
+		}
+	}
 }
 
 // processComparableFiles checks the comparable action and then, for each
@@ -304,17 +416,19 @@ func (prog *prog) processComparableFiles(cmpFiles []string) {
 
 loop:
 	for i, nameOrig := range cmpFiles {
-		nameNew := strings.TrimSuffix(nameOrig, prog.fileExtension)
+		nameNew := prog.baseName(nameOrig)
 
 		switch prog.cmpAction {
 		case caQuery:
-			fmt.Printf(nameFormat, i+1, len(cmpFiles), shortNames[i])
+			fmt.Fprintf(prog.outW,
+				nameFormat, i+1, len(cmpFiles), shortNames[i])
 
 			if prog.queryShowDiff() {
 				prog.showDiff(nameOrig, nameNew, &prog.status.cmpFile)
 			}
 		case caShowDiff:
-			fmt.Printf(nameFormat, i+1, len(cmpFiles), shortNames[i])
+			fmt.Fprintf(prog.outW,
+				nameFormat, i+1, len(cmpFiles), shortNames[i])
 			prog.showDiff(nameOrig, nameNew, &prog.status.cmpFile)
 		}
 
@@ -326,13 +440,13 @@ loop:
 			prog.deleteFile(nameOrig, &prog.status.cmpFile)
 		case caKeepAll:
 			filesRemaining := len(cmpFiles) - i
-			reportFiles(filesRemaining, "comparable", "kept")
+			reportFiles(prog.outW, filesRemaining, "comparable", "kept")
 
 			break loop
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 }
 
 // fileContentsDiffer returns true if the file contents differ
@@ -355,7 +469,7 @@ func fileContentsDiffer(f1, f2 []byte) bool {
 func (prog *prog) queryDeleteDuplicates() rune {
 	response := prog.deleteDupR.GetResponseIndentOrDie(0, prog.indent)
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 
 	return response
 }
@@ -383,7 +497,7 @@ func (prog *prog) showDiff(nameOrig, nameNew string, counts *Counts) {
 func (prog *prog) queryShowDiff() bool {
 	response := prog.showDiffR.GetResponseIndentOrDie(0, prog.indent)
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 
 	switch response {
 	case 'y':
@@ -424,7 +538,7 @@ func (prog *prog) setKeepAll() {
 func (prog *prog) queryDeleteFile(nameOrig, nameNew string) {
 	response := prog.postDiffR.GetResponseIndentOrDie(prog.indent, prog.indent)
 
-	fmt.Println()
+	fmt.Fprintln(prog.outW)
 
 	switch response {
 	case 'y':
@@ -442,8 +556,8 @@ func (prog *prog) deleteAllFiles(filenames []string, count *Counts) {
 		prog.deleteFile(fName, count)
 	}
 
-	reportFiles(count.deleted, count.name, "deleted")
-	reportFiles(count.delErrs, count.name, "could not be deleted")
+	reportFiles(prog.outW, count.deleted, count.name, "deleted")
+	reportFiles(prog.outW, count.delErrs, count.name, "could not be deleted")
 }
 
 // deleteFile deletes the named file, reporting any errors
@@ -458,12 +572,16 @@ func (prog *prog) deleteFile(name string, counts *Counts) {
 
 		counts.delErrs++
 
+		prog.recordAction(name, actDeleteFailed, err)
+
 		return
 	}
 
 	prog.verboseMsg(name + " deleted")
 
 	counts.deleted++
+
+	prog.recordAction(name, actDeleted, nil)
 }
 
 // revertFile reverts the file to its original contents, reporting any
@@ -479,22 +597,26 @@ func (prog *prog) revertFile(nameOrig, nameNew string, counts *Counts) {
 
 		counts.revErrs++
 
+		prog.recordAction(nameOrig, actRevertFailed, err)
+
 		return
 	}
 
 	prog.verboseMsg(nameNew + " reverted to " + nameOrig)
 
 	counts.reverted++
+
+	prog.recordAction(nameOrig, actReverted, nil)
 }
 
 // reportFiles reports the number of files, their type and the action
 // performed on them
-func reportFiles(count int, desc, action string) {
+func reportFiles(w io.Writer, count int, desc, action string) {
 	if count == 0 {
 		return
 	}
 
-	fmt.Printf("%d %s %s %s\n", count, desc,
+	fmt.Fprintf(w, "%d %s %s %s\n", count, desc,
 		english.Plural("file", count), action)
 }
 
@@ -505,48 +627,37 @@ func (prog prog) verboseMsg(msg string) {
 	}
 }
 
-// diffs runs a diff command against the two filenames and pipes the
-// output to less
+// diffs shows the differences between the two files, paging them through
+// the pager. The built-in diff engine is used unless the external diff
+// command has been chosen.
 func (prog prog) diffs(nameOrig, nameNew string) error {
-	// create the commands
+	if prog.useDiffCmd {
+		return prog.externalDiffs(nameOrig, nameNew)
+	}
+
+	return prog.builtinDiffs(nameOrig, nameNew)
+}
+
+// externalDiffs runs a diff command against the two filenames and pages the
+// output
+func (prog prog) externalDiffs(nameOrig, nameNew string) error {
 	dcp := prog.diff.params
 	dcp = append(dcp, nameOrig, nameNew)
 	diffCmd := exec.Command(prog.diff.name, dcp...) //nolint:gosec
 	diffCmdStr := fmt.Sprintf("the diff command (%q)",
 		prog.diff.name+" "+strings.Join(dcp, " "))
-	lessCmd := exec.Command(prog.less.name, prog.less.params...) //nolint:gosec
-	lessCmdStr := fmt.Sprintf("the less command (%q)",
-		prog.less.name+" "+strings.Join(prog.less.params, " "))
 
-	// connect the output of diff to the input of less
-	wStdout, err := diffCmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("%s could not get the StdoutPipe: %w",
-			diffCmdStr, err)
-	}
+	pw := pager.W()
+	pw.Start()
+	defer pw.Done()
 
-	lessCmd.Stdin = wStdout
-	lessCmd.Stdout = os.Stdout
+	diffCmd.Stdout = pw.StdW()
 
-	// start the commands
-	err = diffCmd.Start()
+	err := diffCmd.Start()
 	if err != nil {
 		return fmt.Errorf("%s could not be started: %w", diffCmdStr, err)
 	}
 
-	err = lessCmd.Start()
-	if err != nil {
-		_ = diffCmd.Wait()
-		return fmt.Errorf("%s could not be started: %w", lessCmdStr, err)
-	}
-
-	// wait for less to finish
-	err = lessCmd.Wait()
-	if err != nil {
-		return fmt.Errorf("%s finished with an error: %w", lessCmdStr, err)
-	}
-
-	// wait for diff to finish
 	err = diffCmd.Wait()
 	// the diff command returns an exit status of 1 if the files differ. This
 	// does not indicate an error
@@ -569,8 +680,10 @@ func (prog prog) makeFileLists(entries map[string]os.FileInfo) (
 	duplicates = make([]string, 0, len(entries))
 	badFiles = make([]badFile, 0, len(entries))
 
-	for nameOrig := range entries {
-		nameNew := strings.TrimSuffix(nameOrig, prog.fileExtension)
+	for nameOrig, origInfo := range entries {
+		prog.recordStat(nameOrig, origInfo)
+
+		nameNew := prog.baseName(nameOrig)
 
 		info, err := os.Stat(nameNew)
 		if errors.Is(err, os.ErrNotExist) {
@@ -593,6 +706,8 @@ func (prog prog) makeFileLists(entries map[string]os.FileInfo) (
 			continue
 		}
 
+		prog.recordStat(nameNew, info)
+
 		if info.IsDir() {
 			badFiles = append(badFiles,
 				badFile{
@@ -633,18 +748,21 @@ func (prog prog) makeFileLists(entries map[string]os.FileInfo) (
 		filenames = append(filenames, nameOrig)
 	}
 
-	sort.Strings(filenames)
-	sort.Strings(duplicates)
-	sort.Slice(badFiles,
-		func(i, j int) bool {
-			return badFiles[i].name < badFiles[j].name
+	prog.sortBySchemeThenName(filenames)
+	prog.sortBySchemeThenName(duplicates)
+	slices.SortFunc(badFiles,
+		func(a, b badFile) int {
+			ai, _ := prog.schemeOf(a.name)
+			bi, _ := prog.schemeOf(b.name)
+
+			return cmp.Or(cmp.Compare(ai, bi), strings.Compare(a.name, b.name))
 		})
 
 	return filenames, duplicates, badFiles
 }
 
-// getFiles finds all the regular files in the directory with the given
-// extension
+// getFiles finds all the regular files in the directory with names matching
+// any of the backup schemes
 func (prog prog) getFiles() (
 	filenames, duplicates []string, badFiles []badFile, errs []error,
 ) {
@@ -655,7 +773,7 @@ func (prog prog) getFiles() (
 	}
 
 	entries, errs := findFunc(prog.searchDir,
-		check.FileInfoName(check.StringHasSuffix[string](prog.fileExtension)),
+		check.FileInfoName(prog.isBackupName),
 		check.FileInfoIsRegular)
 
 	if len(errs) != 0 {
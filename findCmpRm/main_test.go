@@ -158,6 +158,8 @@ func setupFakeIO(t *testing.T, prog *prog, testname, testpart, input string,
 		t.Fatal(err)
 	}
 
+	prog.outW = os.Stdout
+
 	if err := twrap.SetWriter(os.Stdout)(prog.twc); err != nil {
 		t.Log(testname)
 		t.Log("\t:", "setting twrap writer")
@@ -247,7 +249,7 @@ func TestGetFiles(t *testing.T) {
 				return nil
 			},
 			pre: func(prog *prog) error {
-				return makeTestDir(prog.searchDir, prog.fileExtension,
+				return makeTestDir(prog.searchDir, dfltExtension,
 					filePairInfo{
 						name:           "f1",
 						origDetails:    &fileInfo{contents: "Hello"},
@@ -339,7 +341,7 @@ func TestGetFiles(t *testing.T) {
 				return nil
 			},
 			pre: func(prog *prog) error {
-				return makeTestDir(prog.searchDir, prog.fileExtension,
+				return makeTestDir(prog.searchDir, dfltExtension,
 					filePairInfo{
 						name:           "f1",
 						origDetails:    &fileInfo{contents: "Hello"},
@@ -570,6 +572,7 @@ func TestProcessDuplicateFiles(t *testing.T) {
 
 		err = testhelper.DiffVals(*prog, *expProg,
 			[]string{"deleteDupR"},
+			[]string{"outW"},
 			[]string{"twc"})
 		if err != nil {
 			t.Log(tc.IDStr())
@@ -580,7 +583,7 @@ func TestProcessDuplicateFiles(t *testing.T) {
 		count, errs := dirsearch.CountRecurse(tempTestDir,
 			check.FileInfoIsRegular,
 			check.FileInfoName(
-				check.StringHasSuffix[string](prog.fileExtension)))
+				check.StringHasSuffix[string](dfltExtension)))
 		if len(errs) != 0 {
 			t.Log(tc.IDStr())
 			t.Log("\t: errors: ", errs)
@@ -674,6 +677,8 @@ func TestProcessComparableFiles(t *testing.T) {
 			response: 'y',
 			setProg: func(prog *prog) error {
 				prog.diff.name = nosuchDiff
+				prog.useDiffCmd = true
+
 				return nil
 			},
 			expProg: func() *prog {
@@ -681,6 +686,7 @@ func TestProcessComparableFiles(t *testing.T) {
 				prog.status.cmpFile.total = len(cmpFiles)
 				prog.status.cmpFile.cmpErrs = len(cmpFiles)
 				prog.diff.name = nosuchDiff
+				prog.useDiffCmd = true
 
 				return prog
 			}(),
@@ -758,6 +764,7 @@ func TestProcessComparableFiles(t *testing.T) {
 			[]string{"searchDir"},
 			[]string{"showDiffR"},
 			[]string{"indent"},
+			[]string{"outW"},
 			[]string{"twc"})
 		if err != nil {
 			t.Log(tc.IDStr())
@@ -768,7 +775,7 @@ func TestProcessComparableFiles(t *testing.T) {
 		count, errs := dirsearch.CountRecurse(tempTestDir,
 			check.FileInfoIsRegular,
 			check.FileInfoName(
-				check.StringHasSuffix[string](prog.fileExtension)))
+				check.StringHasSuffix[string](dfltExtension)))
 		if len(errs) != 0 {
 			t.Log(tc.IDStr())
 			t.Log("\t: errors: ", errs)
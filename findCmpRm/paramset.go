@@ -22,12 +22,14 @@ func makeParamSet(prog *prog) *param.PSet {
 
 		param.SetProgramDescription(
 			"This finds any files in the given directory"+
-				" (by default: "+dfltDir+") with the given extension"+
-				" (by default: "+dfltExtension+"). It presents each"+
+				" (by default: "+dfltDir+") with any of the given"+
+				" extensions (by default: "+dfltExtension+") or"+
+				" named using any of the other backup naming"+
+				" schemes chosen. It presents each"+
 				" file and gives the user the chance to compare it"+
-				" with the corresponding file without the"+
-				" extension. The user is then asked whether to"+
-				" remove the file with the extension. The command name"+
+				" with the corresponding base file (for instance,"+
+				" the file without the extension). The user is then"+
+				" asked whether to remove the backup file. The command name"+
 				" echoes this: find, compare, remove. You will also have"+
 				" the opportunity to revert the file back to the original"+
 				" contents."),
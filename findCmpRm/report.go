@@ -0,0 +1,348 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nickwells/twrap.mod/twrap"
+)
+
+// The values of the report-format parameter
+const (
+	reportText = "text"
+	reportJSON = "json"
+	reportCSV  = "csv"
+)
+
+// The classes of file given in the report
+const (
+	classBad        = "bad"
+	classDuplicate  = "duplicate"
+	classComparable = "comparable"
+)
+
+// The actions given in the report. The planned actions are given when no
+// actions are taken, otherwise the outcome of the action is given.
+const (
+	actDelete = "delete"
+	actRevert = "revert"
+	actKeep   = "keep"
+	actQuery  = "query"
+	actNone   = "none"
+
+	actDeleted      = "deleted"
+	actReverted     = "reverted"
+	actKept         = "kept"
+	actDeleteFailed = "delete-failed"
+	actRevertFailed = "revert-failed"
+)
+
+// fileAction records the outcome of an action taken on a file
+type fileAction struct {
+	action string
+	err    error
+}
+
+// fileReport records the details of a file given in the report. The sizes
+// and modification times are only given if the file can be found.
+type fileReport struct {
+	Name        string     `json:"name"`
+	Base        string     `json:"base"`
+	Scheme      string     `json:"scheme"`
+	Class       string     `json:"class"`
+	Problem     string     `json:"problem,omitempty"`
+	Size        *int64     `json:"size,omitempty"`
+	ModTime     *time.Time `json:"modTime,omitempty"`
+	BaseSize    *int64     `json:"baseSize,omitempty"`
+	BaseModTime *time.Time `json:"baseModTime,omitempty"`
+	Action      string     `json:"action"`
+	ActionError string     `json:"actionError,omitempty"`
+}
+
+// report records the details of all the files found
+type report struct {
+	Dir     string       `json:"dir"`
+	Planned bool         `json:"planned"`
+	Files   []fileReport `json:"files"`
+}
+
+// csvHeader gives the column names for the CSV report
+var csvHeader = []string{
+	"name", "base", "scheme", "class", "problem",
+	"size", "modTime", "baseSize", "baseModTime",
+	"action", "actionError",
+}
+
+// recordAction records the outcome of the action taken on the file. The
+// outcome is only recorded if a machine-readable report is to be written.
+func (prog *prog) recordAction(name, action string, err error) {
+	if prog.fileActions == nil {
+		return
+	}
+
+	prog.fileActions[name] = fileAction{action: action, err: err}
+}
+
+// plannedDupAction returns the action planned for a duplicate file
+func (prog prog) plannedDupAction() string {
+	switch prog.dupAction {
+	case daDelete:
+		return actDelete
+	case daKeep:
+		return actKeep
+	}
+
+	return actQuery
+}
+
+// plannedCmpAction returns the action planned for a comparable file
+func (prog prog) plannedCmpAction() string {
+	switch prog.cmpAction {
+	case caDeleteAll:
+		return actDelete
+	case caRevertAll:
+		return actRevert
+	case caKeepAll:
+		return actKeep
+	}
+
+	return actQuery
+}
+
+// fileStat records the size and modification time of a regular file
+type fileStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// recordStat records the size and modification time of the file if it is
+// a regular file. They are only recorded if a machine-readable report is to
+// be written. They are recorded when the files are found so that the report
+// gives the files as they were before any action was taken.
+func (prog *prog) recordStat(name string, info os.FileInfo) {
+	if prog.fileStats == nil || !info.Mode().IsRegular() {
+		return
+	}
+
+	prog.fileStats[name] = fileStat{size: info.Size(), modTime: info.ModTime()}
+}
+
+// statOf returns the recorded size and modification time of the named
+// file. If none were recorded then nil values are returned.
+func (prog prog) statOf(name string) (*int64, *time.Time) {
+	fs, ok := prog.fileStats[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return &fs.size, &fs.modTime
+}
+
+// makeFileReport returns the report of the named file, setting the action
+// to that planned, or if actions have been taken, to their outcome
+func (prog prog) makeFileReport(name, class, problem string) fileReport {
+	fr := fileReport{
+		Name:    name,
+		Base:    prog.baseName(name),
+		Scheme:  "other",
+		Class:   class,
+		Problem: problem,
+	}
+
+	if i, _ := prog.schemeOf(name); i >= 0 {
+		fr.Scheme = prog.schemes[i].String()
+	}
+
+	fr.Size, fr.ModTime = prog.statOf(name)
+	fr.BaseSize, fr.BaseModTime = prog.statOf(fr.Base)
+
+	switch class {
+	case classBad:
+		fr.Action = actNone
+	case classDuplicate:
+		fr.Action = prog.plannedDupAction()
+	case classComparable:
+		fr.Action = prog.plannedCmpAction()
+	}
+
+	if prog.plan || fr.Action == actNone {
+		return fr
+	}
+
+	fr.Action = actKept
+
+	if fa, ok := prog.fileActions[name]; ok {
+		fr.Action = fa.action
+		if fa.err != nil {
+			fr.ActionError = fa.err.Error()
+		}
+	}
+
+	return fr
+}
+
+// makeReport returns the report of all the files found
+func (prog prog) makeReport(cmpFiles, dupFiles []string, badFiles []badFile,
+) report {
+	r := report{
+		Dir:     prog.searchDir,
+		Planned: prog.plan,
+		Files: make([]fileReport, 0,
+			len(badFiles)+len(dupFiles)+len(cmpFiles)),
+	}
+
+	for _, bf := range badFiles {
+		r.Files = append(r.Files,
+			prog.makeFileReport(bf.name, classBad, bf.problem))
+	}
+
+	for _, name := range dupFiles {
+		r.Files = append(r.Files,
+			prog.makeFileReport(name, classDuplicate, ""))
+	}
+
+	for _, name := range cmpFiles {
+		r.Files = append(r.Files,
+			prog.makeFileReport(name, classComparable, ""))
+	}
+
+	return r
+}
+
+// writeJSON writes the report in JSON format
+func (r report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+
+	return enc.Encode(r)
+}
+
+// writeCSV writes the report in CSV format, one line per file with a
+// header line giving the column names. Missing sizes and times are left
+// empty.
+func (r report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	sizeStr := func(size *int64) string {
+		if size == nil {
+			return ""
+		}
+
+		return strconv.FormatInt(*size, 10)
+	}
+	timeStr := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+
+		return t.Format(time.RFC3339Nano)
+	}
+
+	for _, fr := range r.Files {
+		err := cw.Write([]string{
+			fr.Name, fr.Base, fr.Scheme, fr.Class, fr.Problem,
+			sizeStr(fr.Size), timeStr(fr.ModTime),
+			sizeStr(fr.BaseSize), timeStr(fr.BaseModTime),
+			fr.Action, fr.ActionError,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// showPlan shows the action planned for each file
+func (prog *prog) showPlan(r report) {
+	if len(r.Files) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(r.Files))
+	for _, fr := range r.Files {
+		names = append(names, fr.Name)
+	}
+
+	shortNames, _ := prog.shortNames(names)
+
+	actionWidth, classWidth := 0, 0
+	for _, fr := range r.Files {
+		actionWidth = max(actionWidth, len(fr.Action))
+		classWidth = max(classWidth, len(fr.Class))
+	}
+
+	fmt.Fprintln(prog.outW, "Planned actions (no action has been taken)")
+
+	for i, fr := range r.Files {
+		fmt.Fprintf(prog.outW, "%s%-*s %-*s %s\n",
+			strings.Repeat(" ", schemeIndent),
+			actionWidth, fr.Action, classWidth, fr.Class, shortNames[i])
+	}
+
+	fmt.Fprintln(prog.outW)
+}
+
+// encode writes the report in the machine-readable format
+func (r report) encode(w io.Writer, format string) error {
+	if format == reportCSV {
+		return r.writeCSV(w)
+	}
+
+	return r.writeJSON(w)
+}
+
+// writeReport writes the machine-readable report to the report file or, if
+// that is not given, to the report writer
+func (prog *prog) writeReport(r report) error {
+	if prog.reportFile == "" {
+		return r.encode(prog.reportW, prog.reportFormat)
+	}
+
+	const reportPerms = 0o644
+
+	f, err := os.OpenFile(prog.reportFile,
+		os.O_WRONLY|os.O_CREATE|os.O_TRUNC, reportPerms)
+	if err != nil {
+		return err
+	}
+
+	if err := r.encode(f, prog.reportFormat); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// startReport prepares for writing a machine-readable report. The details
+// of each file found and the outcome of each action taken are recorded. If
+// the report is written to the standard output then all the other output
+// is written to the standard error so that it does not get mixed in with
+// the report.
+func (prog *prog) startReport() {
+	prog.fileActions = map[string]fileAction{}
+	prog.fileStats = map[string]fileStat{}
+	prog.reportW = os.Stdout
+
+	if prog.reportFile == "" {
+		prog.outW = os.Stderr
+
+		if err := twrap.SetWriter(os.Stderr)(prog.twc); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't set the output: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
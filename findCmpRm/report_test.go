@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMakeReport(t *testing.T) {
+	tempTestDir := filepath.Join("testdata", "tempTestDir")
+
+	f1Orig := filepath.Join(tempTestDir, "f1"+dfltExtension)
+	f2Orig := filepath.Join(tempTestDir, "f2"+dfltExtension)
+	f3Orig := filepath.Join(tempTestDir, "f3"+dfltExtension)
+
+	testCases := []struct {
+		testhelper.ID
+		plan       bool
+		dupAction  dupAction
+		cmpAction  cmpAction
+		actions    map[string]fileAction
+		expActions []string
+		expErrs    []string
+	}{
+		{
+			ID:         testhelper.MkID("plan, query"),
+			plan:       true,
+			dupAction:  daQuery,
+			cmpAction:  caShowDiff,
+			expActions: []string{actNone, actQuery, actQuery},
+			expErrs:    []string{"", "", ""},
+		},
+		{
+			ID:         testhelper.MkID("plan, delete and revert"),
+			plan:       true,
+			dupAction:  daDelete,
+			cmpAction:  caRevertAll,
+			expActions: []string{actNone, actDelete, actRevert},
+			expErrs:    []string{"", "", ""},
+		},
+		{
+			ID:         testhelper.MkID("actions taken"),
+			dupAction:  daQuery,
+			cmpAction:  caQuery,
+			actions:    map[string]fileAction{},
+			expActions: []string{actNone, actKept, actKept},
+			expErrs:    []string{"", "", ""},
+		},
+		{
+			ID:        testhelper.MkID("actions taken, with errors"),
+			dupAction: daDelete,
+			cmpAction: caRevertAll,
+			actions: map[string]fileAction{
+				f2Orig: {action: actDeleted},
+				f3Orig: {
+					action: actRevertFailed,
+					err:    errors.New("rename failed"),
+				},
+			},
+			expActions: []string{actNone, actDeleted, actRevertFailed},
+			expErrs:    []string{"", "", "rename failed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		err := makeTestDir(tempTestDir, dfltExtension,
+			filePairInfo{
+				name:        "f1",
+				origDetails: &fileInfo{contents: "Hello"},
+			},
+			filePairInfo{
+				name:           "f2",
+				origDetails:    &fileInfo{contents: "Hello"},
+				nonOrigDetails: &fileInfo{contents: "Hello"},
+			},
+			filePairInfo{
+				name:           "f3",
+				origDetails:    &fileInfo{contents: "Hello"},
+				nonOrigDetails: &fileInfo{contents: "Hello, World"},
+			},
+		)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Fatal("\t: unexpected makeTestDir error: ", err)
+		}
+
+		prog := newProg()
+		prog.searchDir = tempTestDir
+		prog.plan = tc.plan
+		prog.dupAction = tc.dupAction
+		prog.cmpAction = tc.cmpAction
+		prog.fileActions = tc.actions
+		prog.fileStats = map[string]fileStat{}
+
+		cmpFiles, dupFiles, badFiles, errs := prog.getFiles()
+		if len(errs) != 0 {
+			t.Log(tc.IDStr())
+			t.Fatal("\t: unexpected getFiles errors: ", errs)
+		}
+
+		// the report should give the files as they were when found
+		if err := os.Remove(f2Orig); err != nil {
+			t.Log(tc.IDStr())
+			t.Fatal("\t: can't remove the duplicate file: ", err)
+		}
+
+		r := prog.makeReport(cmpFiles, dupFiles, badFiles)
+
+		testhelper.DiffBool(t, tc.IDStr(), "planned", r.Planned, tc.plan)
+
+		var names, classes, actions, actErrs []string
+
+		for _, fr := range r.Files {
+			names = append(names, fr.Name)
+			classes = append(classes, fr.Class)
+			actions = append(actions, fr.Action)
+			actErrs = append(actErrs, fr.ActionError)
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "names",
+			names, []string{f1Orig, f2Orig, f3Orig})
+		testhelper.DiffStringSlice(t, tc.IDStr(), "classes",
+			classes, []string{classBad, classDuplicate, classComparable})
+		testhelper.DiffStringSlice(t, tc.IDStr(), "actions",
+			actions, tc.expActions)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "action errors",
+			actErrs, tc.expErrs)
+
+		bad, dup, cmp := r.Files[0], r.Files[1], r.Files[2]
+		testhelper.DiffString(t, tc.IDStr(), "bad: problem",
+			bad.Problem, fmt.Sprintf("there is no file named %q",
+				filepath.Join(tempTestDir, "f1")))
+		testhelper.DiffString(t, tc.IDStr(), "scheme",
+			bad.Scheme, "*"+dfltExtension)
+
+		if bad.Size == nil || *bad.Size != int64(len("Hello")) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad: the size should be %d", len("Hello"))
+		}
+
+		if dup.Size == nil || *dup.Size != int64(len("Hello")) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: duplicate: the size should be %d", len("Hello"))
+		}
+
+		if bad.BaseSize != nil || bad.BaseModTime != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: bad: there should be no base size or time")
+		}
+
+		if cmp.BaseSize == nil || *cmp.BaseSize != int64(len("Hello, World")) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: comparable: the base size should be %d",
+				len("Hello, World"))
+		}
+
+		getFilesTestCleanup(t, tc.IDStr(), prog, nil)
+	}
+}
+
+// testReport returns a report with fixed values for testing the output
+// formats
+func testReport() report {
+	size, baseSize := int64(5), int64(12)
+	modTime := time.Date(2026, time.March, 4, 5, 6, 7, 0, time.UTC)
+	baseModTime := modTime.Add(time.Hour)
+
+	return report{
+		Dir:     "d",
+		Planned: true,
+		Files: []fileReport{
+			{
+				Name:    "d/f1.orig",
+				Base:    "d/f1",
+				Scheme:  "*.orig",
+				Class:   classBad,
+				Problem: `there is no file named "d/f1"`,
+				Size:    &size,
+				ModTime: &modTime,
+				Action:  actNone,
+			},
+			{
+				Name:        "d/f2~",
+				Base:        "d/f2",
+				Scheme:      "*~",
+				Class:       classComparable,
+				Size:        &size,
+				ModTime:     &modTime,
+				BaseSize:    &baseSize,
+				BaseModTime: &baseModTime,
+				Action:      actQuery,
+			},
+		},
+	}
+}
+
+func TestReportFormats(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		format    string
+		expReport string
+	}{
+		{
+			ID:     testhelper.MkID("csv"),
+			format: reportCSV,
+			expReport: "name,base,scheme,class,problem," +
+				"size,modTime,baseSize,baseModTime,action,actionError\n" +
+				`d/f1.orig,d/f1,*.orig,bad,"there is no file named ""d/f1"""` +
+				",5,2026-03-04T05:06:07Z,,,none,\n" +
+				"d/f2~,d/f2,*~,comparable,,5,2026-03-04T05:06:07Z," +
+				"12,2026-03-04T06:06:07Z,query,\n",
+		},
+		{
+			ID:     testhelper.MkID("json"),
+			format: reportJSON,
+			expReport: `{
+    "dir": "d",
+    "planned": true,
+    "files": [
+        {
+            "name": "d/f1.orig",
+            "base": "d/f1",
+            "scheme": "*.orig",
+            "class": "bad",
+            "problem": "there is no file named \"d/f1\"",
+            "size": 5,
+            "modTime": "2026-03-04T05:06:07Z",
+            "action": "none"
+        },
+        {
+            "name": "d/f2~",
+            "base": "d/f2",
+            "scheme": "*~",
+            "class": "comparable",
+            "size": 5,
+            "modTime": "2026-03-04T05:06:07Z",
+            "baseSize": 12,
+            "baseModTime": "2026-03-04T06:06:07Z",
+            "action": "query"
+        }
+    ]
+}
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var out strings.Builder
+
+		err := testReport().encode(&out, tc.format)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %v", err)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "report",
+			out.String(), tc.expReport)
+	}
+}
+
+func TestWriteReportFile(t *testing.T) {
+	prog := newProg()
+	prog.reportFormat = reportCSV
+	prog.reportFile = filepath.Join(t.TempDir(), "report.csv")
+
+	if err := prog.writeReport(testReport()); err != nil {
+		t.Fatal("unexpected error writing the report: ", err)
+	}
+
+	content, err := os.ReadFile(prog.reportFile)
+	if err != nil {
+		t.Fatal("can't read the report: ", err)
+	}
+
+	testhelper.DiffInt(t, "report file", "line count",
+		strings.Count(string(content), "\n"), 3)
+
+	prog.reportFile = filepath.Join(t.TempDir(), "nonesuch", "report.csv")
+	if err := prog.writeReport(testReport()); err == nil {
+		t.Log("test: report file in a missing directory")
+		t.Errorf("\t: an error was expected but none was returned")
+	}
+}
+
+func TestShowPlan(t *testing.T) {
+	prog := newProg()
+	prog.searchDir = "d"
+
+	fakeIO := setupFakeIO(t, prog, "show plan", "", "")
+
+	prog.showPlan(testReport())
+
+	stdout, _ := getFakeIO(t, "show plan", "", fakeIO)
+	testhelper.DiffString(t, "show plan", "stdout", string(stdout),
+		"Planned actions (no action has been taken)\n"+
+			"    none  bad        f1.orig\n"+
+			"    query comparable f2~\n"+
+			"\n")
+}
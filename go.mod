@@ -12,6 +12,7 @@ require (
 	github.com/nickwells/gogen.mod v1.11.40
 	github.com/nickwells/location.mod v1.2.37
 	github.com/nickwells/mathutil.mod/v2 v2.5.11
+	github.com/nickwells/pager.mod v1.1.0
 	github.com/nickwells/snippet.mod v1.2.20
 	github.com/nickwells/testhelper.mod/v2 v2.6.1
 	github.com/nickwells/timer.mod v1.2.7 // indirect
@@ -35,7 +36,6 @@ require github.com/nickwells/strdist.mod/v2 v2.1.2 // indirect
 
 require (
 	github.com/nickwells/fileparse.mod v1.1.39 // indirect
-	github.com/nickwells/pager.mod v1.1.0 // indirect
 	golang.org/x/exp v0.0.0-20260410095643-746e56fc9e2f // indirect
 	golang.org/x/term v0.42.0 // indirect
 )
@@ -10,9 +10,64 @@ func addExamples(ps *param.PSet) error {
 		"This will compare the standard collection of snippets"+
 			" with those in the target directory")
 
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir -diff",
+		"This will compare the standard collection of snippets"+
+			" with those in the target directory and show the"+
+			" differences between the installed snippets and the"+
+			" standard ones")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir -summary",
+		"This will show how many of the standard snippets are new,"+
+			" the same as or different from those in the target"+
+			" directory")
+
 	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir -install",
 		"This will install the standard collection of snippets"+
 			" into the target directory")
 
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir"+
+		" -install -dir timer -dry-run",
+		"This will show what would be done to install the standard"+
+			" snippets in the timer sub-directory into the target"+
+			" directory without changing anything")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir"+
+		" -install -from team-snippets.tar.gz",
+		"This will install the snippets from the gzipped tar archive"+
+			" into the target directory. This lets a collection of"+
+			" snippets be shared as a single file")
+
+	ps.AddExample("gosh.snippet -target $snipDir -check -from $mySnippets",
+		"This will check the snippets in the mySnippets directory,"+
+			" reporting any problems with the snippet comments,"+
+			" the imported packages or the code")
+
+	ps.AddExample("gosh.snippet -target $snipDir -install"+
+		" -"+paramNameCheckFirst+" -from $mySnippets",
+		"This will check the snippets in the mySnippets directory"+
+			" and, only if none have problems, install them into"+
+			" the target directory")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir -tidy -keep 2",
+		"This will list the timestamped copies of snippets in the"+
+			" target directory and ask whether to remove all but the"+
+			" newest two copies of each snippet")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir"+
+		" -tidy -keep 0 -older-than 30 -tidy-mode delete",
+		"This will remove all the timestamped copies of snippets in"+
+			" the target directory which are more than 30 days old"+
+			" without asking")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir"+
+		" -catalogue -catalogue-from target -catalogue-file SNIPPETS.md",
+		"This will write a Markdown catalogue of the snippets in the"+
+			" target directory into the SNIPPETS.md file")
+
+	ps.AddExample(snipDir+"\ngosh.snippet -target $snipDir -uninstall",
+		"This will remove the snippets installed by gosh.snippet"+
+			" from the target directory. Any snippets which have been"+
+			" changed since they were installed are kept")
+
 	return nil
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/nickwells/check.mod/v2/check"
+	"github.com/nickwells/english.mod/english"
 	"github.com/nickwells/filecheck.mod/filecheck"
 	"github.com/nickwells/param.mod/v7/paction"
 	"github.com/nickwells/param.mod/v7/param"
@@ -13,10 +15,41 @@ import (
 const (
 	paramNameAction     = "action"
 	paramNameInstall    = "install"
+	paramNameUninstall  = "uninstall"
+	paramNameCheck      = "check"
+	paramNameCheckFirst = "check-first"
 	paramNameTarget     = "target"
 	paramNameSource     = "source"
 	paramNameMaxSubDirs = "max-sub-dirs"
 	paramNameNoCopy     = "no-copy"
+
+	paramNameShowDiff    = "show-diff"
+	paramNameDiffContext = "diff-context"
+	paramNameColour      = "colour"
+	paramNameCmpSummary  = "summary"
+
+	paramNameInclude    = "include"
+	paramNameExclude    = "exclude"
+	paramNameIncludeDir = "include-dir"
+	paramNameExcludeDir = "exclude-dir"
+	paramNameIncludeTag = "include-tag"
+	paramNameExcludeTag = "exclude-tag"
+	paramNameDryRun     = "dry-run"
+
+	paramNameTidy       = "tidy"
+	paramNameTidyKeep   = "tidy-keep"
+	paramNameTidyMaxAge = "tidy-older-than"
+	paramNameTidyMode   = "tidy-mode"
+
+	paramNameCatalogue     = "catalogue"
+	paramNameCatalogueFrom = "catalogue-from"
+	paramNameCatalogueFile = "catalogue-file"
+)
+
+const (
+	paramGroupNameFilter = "cmd-filter"
+	paramGroupNameTidy   = "cmd-tidy"
+	paramGroupNameCat    = "cmd-catalogue"
 )
 
 // addParams will add parameters to the passed ParamSet
@@ -28,8 +61,20 @@ func addParams(prog *prog) param.PSetOptFunc {
 				AllowedVals: psetter.AllowedVals[string]{
 					installAction: "install the default snippets in" +
 						" the target directory",
+					uninstallAction: "remove the snippets installed" +
+						" by this program from the target directory," +
+						" leaving any snippets which have been changed" +
+						" since they were installed and any snippets" +
+						" which were not installed by this program",
 					cmpAction: "compare the default snippets with" +
 						" those in the target directory",
+					tidyAction: "remove old timestamped copies of" +
+						" snippets from the target directory",
+					catAction: "write a Markdown catalogue of the" +
+						" snippets",
+					checkAction: "check the default snippets," +
+						" reporting any problems with the snippet" +
+						" comments, the imports or the code",
 				},
 			},
 			"what action should be performed",
@@ -44,6 +89,32 @@ func addParams(prog *prog) param.PSetOptFunc {
 			param.SeeAlso(paramNameAction),
 		)
 
+		ps.Add(paramNameUninstall, psetter.Nil{},
+			"uninstall the snippets.",
+			param.PostAction(paction.SetVal(&prog.action, uninstallAction)),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameAction),
+		)
+
+		ps.Add(paramNameCheck, psetter.Nil{},
+			"check the snippets.",
+			param.PostAction(paction.SetVal(&prog.action, checkAction)),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameAction),
+		)
+
+		ps.Add(paramNameCheckFirst,
+			psetter.Bool{Value: &prog.checkFirst},
+			"check the snippets before installing them. If any have"+
+				" problems then none are installed. Note that the"+
+				" check needs the Go command and may need network"+
+				" access to find any imported packages which are"+
+				" not in the standard library.",
+			param.AltNames("check-before-install"),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(paramNameCheck),
+		)
+
 		ps.Add(paramNameTarget,
 			psetter.Pathname{
 				Value: &prog.toDir,
@@ -58,12 +129,23 @@ func addParams(prog *prog) param.PSetOptFunc {
 
 		ps.Add(paramNameSource,
 			psetter.Pathname{
-				Value:       &prog.fromDir,
-				Expectation: filecheck.DirExists(),
+				Value: &prog.fromDir,
+				Expectation: filecheck.Provisos{
+					Existence: filecheck.MustExist,
+				},
 			},
-			"set the directory where the snippets are to be found."+
-				" If this is not set then the standard collection of"+
-				" snippets will be used.",
+			"set the directory or archive where the snippets are to be"+
+				" found. If this is not set then the standard collection"+
+				" of snippets will be used."+
+				"\n\n"+
+				"An archive can be a gzipped tar file, a zip file or"+
+				" a git bundle, the kind of archive is given by the"+
+				" file name suffix which must be one of: "+
+				english.Join(archiveSuffixList(), ", ", " or ")+"."+
+				" A git bundle is read using the git command and the"+
+				" snippets are taken from the HEAD of the bundle. If"+
+				" the archive holds a single directory and nothing"+
+				" else then the snippets are taken from that directory.",
 			param.AltNames("from", "from-dir", "f"),
 			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
 		)
@@ -94,6 +176,273 @@ func addParams(prog *prog) param.PSetOptFunc {
 			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
 		)
 
+		ps.Add(paramNameShowDiff,
+			psetter.Bool{Value: &prog.showDiff},
+			"when comparing snippets, show the differences between"+
+				" each installed snippet and the snippet from the"+
+				" source as a unified diff.",
+			param.AltNames("diff"),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameDiffContext, paramNameColour),
+		)
+
+		ps.Add(paramNameDiffContext,
+			psetter.Int[int64]{
+				Value:  &prog.diffContext,
+				Checks: []check.Int64{check.ValGE[int64](0)},
+			},
+			"how many lines of unchanged text to show around each"+
+				" change when showing the differences between snippets.",
+			param.AltNames("context", "U"),
+			param.PostAction(paction.SetVal(&prog.showDiff, true)),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameShowDiff),
+		)
+
+		ps.Add(paramNameColour,
+			psetter.Enum[string]{
+				Value: &prog.colour,
+				AllowedVals: psetter.AllowedVals[string]{
+					colourAuto: "colour the differences if the" +
+						" output is a terminal",
+					colourAlways: "always colour the differences",
+					colourNever:  "never colour the differences",
+				},
+			},
+			"when to colour the differences between snippets.",
+			param.AltNames("color"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameShowDiff),
+		)
+
+		ps.Add(paramNameCmpSummary,
+			psetter.Bool{Value: &prog.cmpSummary},
+			"when comparing snippets, show only the number of"+
+				" snippets in each category rather than listing"+
+				" each snippet.",
+			param.AltNames("summary-only"),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.AddGroup(paramGroupNameFilter,
+			"parameters for choosing which snippets are compared"+
+				" or installed.")
+
+		ps.Add(paramNameInclude,
+			psetter.StrListAppender{Value: &prog.filter.names},
+			"compare or install only those snippets whose names match"+
+				" one of these patterns. The name of a snippet includes"+
+				" any sub-directories it is in but a pattern with no"+
+				" '/' is also matched against just the last part of"+
+				" the name. The patterns use the same syntax as"+
+				" shell file name patterns.",
+			param.AltNames("name", "only"),
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameExclude,
+			psetter.StrListAppender{Value: &prog.filter.excludeNames},
+			"do not compare or install those snippets whose names match"+
+				" one of these patterns.",
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameInclude),
+		)
+
+		ps.Add(paramNameIncludeDir,
+			psetter.StrListAppender{Value: &prog.filter.dirs},
+			"compare or install only those snippets in one of these"+
+				" sub-directories (or their sub-directories).",
+			param.AltNames("dir"),
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameExcludeDir,
+			psetter.StrListAppender{Value: &prog.filter.excludeDirs},
+			"do not compare or install those snippets in one of these"+
+				" sub-directories (or their sub-directories).",
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameIncludeTag,
+			psetter.StrListAppender{Value: &prog.filter.tags},
+			"compare or install only those snippets having at least"+
+				" one of these tags in their snippet comments. The tag"+
+				" names are matched ignoring case.",
+			param.AltNames("tag"),
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameExcludeTag,
+			psetter.StrListAppender{Value: &prog.filter.excludeTags},
+			"do not compare or install those snippets having any of"+
+				" these tags in their snippet comments.",
+			param.GroupName(paramGroupNameFilter),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameDryRun,
+			psetter.Bool{Value: &prog.dryRun},
+			"when installing snippets, show what would be done but"+
+				" don't change anything. Each file that would be"+
+				" written, removed, renamed or timestamped is listed.",
+			param.AltNames("no-action"),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.AddGroup(paramGroupNameTidy,
+			"parameters for tidying the old copies of snippets.")
+
+		ps.Add(paramNameTidy, psetter.Nil{},
+			"remove the old timestamped copies of snippets."+
+				" When an installed snippet is replaced the original"+
+				" is kept in a file with the same name but with '"+
+				backupSuffix+"' added. If there is already a copy"+
+				" then a timestamp is added as well. This finds"+
+				" these timestamped copies in the target directory"+
+				" and removes the older ones. The copies without a"+
+				" timestamp are never removed.",
+			param.PostAction(paction.SetVal(&prog.action, tidyAction)),
+			param.GroupName(paramGroupNameTidy),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameAction),
+		)
+
+		ps.Add(paramNameTidyKeep,
+			psetter.Int[int64]{
+				Value:  &prog.tidyKeep,
+				Checks: []check.Int64{check.ValGE[int64](0)},
+			},
+			"how many of the newest timestamped copies of each"+
+				" snippet to keep when tidying.",
+			param.AltNames("keep"),
+			param.GroupName(paramGroupNameTidy),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameTidyMaxAge,
+			psetter.Int[int64]{
+				Value:  &prog.tidyMaxAgeDays,
+				Checks: []check.Int64{check.ValGE[int64](0)},
+			},
+			"when tidying, only remove the timestamped copies which"+
+				" are older than this many days. If this is zero then"+
+				" the age of the copies is ignored.",
+			param.AltNames("older-than"),
+			param.ValueName("days"),
+			param.GroupName(paramGroupNameTidy),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameTidyMode,
+			psetter.Enum[string]{
+				Value: &prog.tidyMode,
+				AllowedVals: psetter.AllowedVals[string]{
+					tidyQuery: "list the copies and ask" +
+						" before removing any",
+					tidyDelete: "list the copies and remove" +
+						" the old ones without asking",
+					tidyList: "just list the copies," +
+						" showing which would be removed",
+				},
+			},
+			"how the old timestamped copies should be handled.",
+			param.GroupName(paramGroupNameTidy),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.AddGroup(paramGroupNameCat,
+			"parameters for the catalogue of snippets.")
+
+		ps.Add(paramNameCatalogue, psetter.Nil{},
+			"write a Markdown catalogue of the snippets. There is a"+
+				" section for each directory of snippets and, for each"+
+				" snippet, the documentation, imports, expected"+
+				" and followed snippets and tags from the snippet"+
+				" comments are shown along with the code.",
+			param.AltNames("catalog"),
+			param.PostAction(paction.SetVal(&prog.action, catAction)),
+			param.GroupName(paramGroupNameCat),
+			param.Attrs(param.CommandLineOnly),
+			param.SeeAlso(paramNameAction),
+		)
+
+		ps.Add(paramNameCatalogueFrom,
+			psetter.Enum[string]{
+				Value: &prog.catalogueFrom,
+				AllowedVals: psetter.AllowedVals[string]{
+					catFromSource: "catalogue the source snippets",
+					catFromTarget: "catalogue the snippets in" +
+						" the target directory",
+				},
+			},
+			"which snippets should be catalogued.",
+			param.AltNames("catalog-from"),
+			param.GroupName(paramGroupNameCat),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.Add(paramNameCatalogueFile,
+			psetter.Pathname{Value: &prog.catalogueFile},
+			"the file to write the catalogue into. If this is not"+
+				" given the catalogue is written to the standard output.",
+			param.AltNames("catalog-file"),
+			param.GroupName(paramGroupNameCat),
+			param.Attrs(param.CommandLineOnly),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if prog.action != cmpAction &&
+				(prog.showDiff || prog.cmpSummary) {
+				return fmt.Errorf(
+					"the %q and %q parameters can only be used"+
+						" when comparing snippets",
+					paramNameShowDiff, paramNameCmpSummary)
+			}
+
+			if prog.checkFirst && prog.action != installAction {
+				return fmt.Errorf(
+					"the %q parameter can only be used"+
+						" when installing snippets",
+					paramNameCheckFirst)
+			}
+
+			if prog.dryRun && prog.action != installAction {
+				return fmt.Errorf(
+					"the %q parameter can only be used"+
+						" when installing snippets",
+					paramNameDryRun)
+			}
+
+			if !prog.filter.isEmpty() &&
+				prog.action != installAction && prog.action != cmpAction {
+				return errors.New("snippets can only be filtered" +
+					" when comparing or installing snippets")
+			}
+
+			for _, patterns := range [][]string{
+				prog.filter.names, prog.filter.excludeNames,
+			} {
+				if bad := badGlobs(patterns); len(bad) > 0 {
+					return fmt.Errorf("bad snippet name %s: %s",
+						english.Plural("pattern", len(bad)),
+						english.JoinQuoted(bad, ", ", " and ", `"`))
+				}
+			}
+
+			if prog.showDiff && prog.cmpSummary {
+				return fmt.Errorf(
+					"the %q and %q parameters cannot both be given",
+					paramNameShowDiff, paramNameCmpSummary)
+			}
+
+			return nil
+		})
+
 		return nil
 	}
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+)
+
+// The kinds of archive which can be used as a source of snippets
+const (
+	archiveTarGz     = "tar.gz"
+	archiveZip       = "zip"
+	archiveGitBundle = "git bundle"
+)
+
+// archiveSuffixes maps the file name suffixes to the kind of archive
+var archiveSuffixes = []struct {
+	suffix string
+	kind   string
+}{
+	{".tar.gz", archiveTarGz},
+	{".tgz", archiveTarGz},
+	{".zip", archiveZip},
+	{".bundle", archiveGitBundle},
+}
+
+// archiveKind returns the kind of archive the file holds, as given by the
+// file name suffix. It returns an empty string if the name does not have
+// one of the archive suffixes.
+func archiveKind(name string) string {
+	lcName := strings.ToLower(name)
+
+	for _, as := range archiveSuffixes {
+		if strings.HasSuffix(lcName, as.suffix) {
+			return as.kind
+		}
+	}
+
+	return ""
+}
+
+// archiveSuffixList returns the archive suffixes as a list for use in
+// messages
+func archiveSuffixList() []string {
+	suffixes := make([]string, 0, len(archiveSuffixes))
+	for _, as := range archiveSuffixes {
+		suffixes = append(suffixes, as.suffix)
+	}
+
+	return suffixes
+}
+
+// openArchiveFS returns a file system holding the contents of the archive.
+// If the archive holds a single directory and nothing else then the file
+// system holds the contents of that directory.
+func openArchiveFS(name string) (fs.FS, error) {
+	var (
+		fsys fs.FS
+		err  error
+	)
+
+	switch archiveKind(name) {
+	case archiveTarGz:
+		fsys, err = tarGzFS(name)
+	case archiveZip:
+		fsys, err = zipFS(name)
+	case archiveGitBundle:
+		fsys, err = gitBundleFS(name)
+	default:
+		return nil,
+			fmt.Errorf("%q is not a directory or a recognised archive", name)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the %s archive %q: %w",
+			archiveKind(name), name, err)
+	}
+
+	return singleDirContent(fsys)
+}
+
+// singleDirContent returns the sub-directory of the file system if the
+// file system holds that sub-directory and nothing else. Otherwise the
+// file system is returned unchanged.
+func singleDirContent(fsys fs.FS) (fs.FS, error) {
+	dirEnts, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dirEnts) != 1 || !dirEnts[0].IsDir() {
+		return fsys, nil
+	}
+
+	return fs.Sub(fsys, dirEnts[0].Name())
+}
+
+// zipFS returns the zip archive as a file system. The archive is read into
+// memory so that no file is left open.
+func zipFS(name string) (fs.FS, error) {
+	content, err := os.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	return zip.NewReader(bytes.NewReader(content), int64(len(content)))
+}
+
+// tarGzFS returns the gzipped tar archive as a file system
+func tarGzFS(name string) (fs.FS, error) {
+	f, err := os.Open(name) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close() //nolint:errcheck
+
+	return tarFS(zr)
+}
+
+// gitBundleFS returns the contents of the HEAD of the git bundle as a file
+// system. The bundle is cloned into a temporary directory using the git
+// command and the contents are taken from an archive of the clone.
+func gitBundleFS(name string) (fs.FS, error) {
+	dir, err := os.MkdirTemp("", "gosh.snippet-bundle-*.d")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	cloneDir := filepath.Join(dir, "clone")
+
+	out, err := exec.Command( //nolint:gosec
+		"git", "clone", "--quiet", name, cloneDir).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git clone failed: %w: %s",
+			err, strings.TrimSpace(string(out)))
+	}
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command( //nolint:gosec
+		"git", "-C", cloneDir, "archive", "--format=tar", "HEAD")
+	cmd.Stderr = &stderr
+
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git archive failed: %w: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+
+	return tarFS(bytes.NewReader(out))
+}
+
+// tarFS reads the tar archive into a file system. Only the directories and
+// regular files are kept.
+func tarFS(r io.Reader) (fs.FS, error) {
+	mfs := fstest.MapFS{}
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return mfs, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			mfs[name] = &fstest.MapFile{
+				Mode:    fs.ModeDir | dfltDirPerms,
+				ModTime: hdr.ModTime,
+			}
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+
+			mfs[name] = &fstest.MapFile{
+				Data:    content,
+				Mode:    hdr.FileInfo().Mode().Perm(),
+				ModTime: hdr.ModTime,
+			}
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// archiveTestFiles holds the files written into the test archives
+var archiveTestFiles = []struct {
+	name    string
+	content string
+}{
+	{"snippets/a", "// snippet: Doc: a\nfmt.Println(1)\n"},
+	{"snippets/dir/b", "// snippet: Doc: b\nfmt.Println(2)\n"},
+	{"snippets/dir/sub/c", ""},
+}
+
+// writeTarGz writes the test files into a gzipped tar archive
+func writeTarGz(t *testing.T, name string) {
+	t.Helper()
+
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal("cannot create the tar file: ", err)
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+
+	for _, tf := range archiveTestFiles {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     tf.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0o644,
+			Size:     int64(len(tf.content)),
+		})
+		if err != nil {
+			t.Fatal("cannot write the tar header: ", err)
+		}
+
+		if _, err := tw.Write([]byte(tf.content)); err != nil {
+			t.Fatal("cannot write the tar content: ", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal("cannot close the tar writer: ", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal("cannot close the gzip writer: ", err)
+	}
+}
+
+// writeZip writes the test files into a zip archive
+func writeZip(t *testing.T, name string) {
+	t.Helper()
+
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal("cannot create the zip file: ", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	for _, tf := range archiveTestFiles {
+		w, err := zw.Create(tf.name)
+		if err != nil {
+			t.Fatal("cannot create the zip entry: ", err)
+		}
+
+		if _, err := w.Write([]byte(tf.content)); err != nil {
+			t.Fatal("cannot write the zip content: ", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal("cannot close the zip writer: ", err)
+	}
+}
+
+// writeGitBundle writes the test files into a git repository and bundles
+// it. The test is skipped if there is no git command.
+func writeGitBundle(t *testing.T, name string) {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("the git command is not available")
+	}
+
+	repo := t.TempDir()
+
+	for _, tf := range archiveTestFiles {
+		path := filepath.Join(repo, filepath.FromSlash(tf.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal("cannot create the repository directory: ", err)
+		}
+
+		if err := os.WriteFile(path, []byte(tf.content), 0o600); err != nil {
+			t.Fatal("cannot write the repository file: ", err)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{
+			"-c", "user.name=test", "-c", "user.email=test@example.com",
+			"commit", "--quiet", "-m", "snippets",
+		},
+		{"bundle", "create", "--quiet", name, "HEAD"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+}
+
+func TestArchiveFS(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		fileName string
+		write    func(*testing.T, string)
+	}{
+		{
+			ID:       testhelper.MkID("tar.gz"),
+			fileName: "snippets.tar.gz",
+			write:    writeTarGz,
+		},
+		{
+			ID:       testhelper.MkID("zip"),
+			fileName: "snippets.zip",
+			write:    writeZip,
+		},
+		{
+			ID:       testhelper.MkID("git bundle"),
+			fileName: "snippets.bundle",
+			write:    writeGitBundle,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.IDStr(), func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), tc.fileName)
+			tc.write(t, name)
+
+			fsys, err := openArchiveFS(name)
+			if err != nil {
+				t.Fatal("cannot open the archive: ", err)
+			}
+
+			err = fstest.TestFS(fsys, "a", "dir/b", "dir/sub/c")
+			if err != nil {
+				t.Error("bad file system: ", err)
+			}
+
+			for _, tf := range archiveTestFiles {
+				fName, _ := filepath.Rel("snippets", tf.name)
+
+				content, err := fs.ReadFile(fsys, filepath.ToSlash(fName))
+				if err != nil {
+					t.Error("cannot read the file: ", err)
+					continue
+				}
+
+				testhelper.DiffString(t, tc.IDStr(), fName,
+					string(content), tf.content)
+			}
+		})
+	}
+}
+
+func TestArchiveKind(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		name    string
+		expKind string
+	}{
+		{ID: testhelper.MkID("tar.gz"), name: "x.tar.gz", expKind: archiveTarGz},
+		{ID: testhelper.MkID("tgz"), name: "x.TGZ", expKind: archiveTarGz},
+		{ID: testhelper.MkID("zip"), name: "x.zip", expKind: archiveZip},
+		{
+			ID:      testhelper.MkID("bundle"),
+			name:    "x.bundle",
+			expKind: archiveGitBundle,
+		},
+		{ID: testhelper.MkID("other"), name: "x.tar", expKind: ""},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "archive kind",
+			archiveKind(tc.name), tc.expKind)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/errutil.mod/errutil"
+	snippetmod "github.com/nickwells/snippet.mod/snippet"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// The values of the catalogue-from parameter
+const (
+	catFromSource = "source"
+	catFromTarget = "target"
+)
+
+// snippetTag records a tag from the snippet comments
+type snippetTag struct {
+	name  string
+	value string
+}
+
+// snippetInfo records the parts of a snippet shown in the catalogue
+type snippetInfo struct {
+	name    string
+	doc     []string
+	imports []string
+	expects []string
+	follows []string
+	tags    []snippetTag
+	code    []string
+}
+
+// newSnippetInfo returns the parts of the parsed snippet shown in the
+// catalogue. The tags are given in order of their names.
+func newSnippetInfo(name string, ps parsedSnippet) snippetInfo {
+	si := snippetInfo{
+		name:    name,
+		doc:     ps.Docs(),
+		imports: ps.Imports(),
+		expects: ps.Expects(),
+		follows: ps.Follows(),
+		code:    trimBlankLines(ps.Text()),
+	}
+
+	tags := ps.Tags()
+	for _, tagName := range slices.Sorted(maps.Keys(tags)) {
+		for _, v := range tags[tagName] {
+			si.tags = append(si.tags, snippetTag{name: tagName, value: v})
+		}
+	}
+
+	return si
+}
+
+// trimBlankLines returns the lines with any leading and trailing blank
+// lines removed
+func trimBlankLines(lines []string) []string {
+	isBlank := func(l string) bool { return strings.TrimSpace(l) == "" }
+
+	for len(lines) > 0 && isBlank(lines[0]) {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// mdEscaper escapes the characters which have a meaning in Markdown text
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+	"#", `\#`,
+	"|", `\|`,
+)
+
+// mdParagraphs returns the lines as Markdown paragraphs. The lines are
+// joined and a blank line starts a new paragraph.
+func mdParagraphs(lines []string) string {
+	paras := []string{}
+	para := []string{}
+
+	for _, l := range append(lines, "") {
+		if l != "" {
+			para = append(para, mdEscaper.Replace(l))
+			continue
+		}
+
+		if len(para) > 0 {
+			paras = append(paras, strings.Join(para, " "))
+			para = para[:0]
+		}
+	}
+
+	return strings.Join(paras, "\n\n")
+}
+
+// mdCodeList returns the values as a comma-separated list of Markdown code
+// spans
+func mdCodeList(vals []string) string {
+	spans := make([]string, 0, len(vals))
+	for _, v := range vals {
+		spans = append(spans, "`"+v+"`")
+	}
+
+	return strings.Join(spans, ", ")
+}
+
+// mdCodeFence returns a code fence which is longer than any run of
+// backquotes in the code
+func mdCodeFence(code []string) string {
+	const minFenceLen = 3
+
+	longest := 0
+
+	for _, l := range code {
+		run := 0
+
+		for _, r := range l {
+			if r != '`' {
+				run = 0
+				continue
+			}
+
+			run++
+			longest = max(longest, run)
+		}
+	}
+
+	return strings.Repeat("`", max(minFenceLen, longest+1))
+}
+
+// mdAnchor returns the anchor that Markdown renderers such as GitHub's
+// generate for the heading
+func mdAnchor(heading string) string {
+	var anchor strings.Builder
+
+	for _, r := range strings.ToLower(heading) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			anchor.WriteRune(r)
+		case r == ' ':
+			anchor.WriteRune('-')
+		}
+	}
+
+	return anchor.String()
+}
+
+// catDirHeading returns the heading for the section holding the snippets
+// in the directory
+func catDirHeading(dir string) string {
+	if dir == "" {
+		return "Top-level snippets"
+	}
+
+	return filepath.ToSlash(dir)
+}
+
+// writeSnippetEntry writes the catalogue entry for the snippet
+func writeSnippetEntry(cat *strings.Builder, si snippetInfo) {
+	fmt.Fprintf(cat, "### %s\n\n", mdEscaper.Replace(filepath.ToSlash(si.name)))
+
+	if len(si.doc) > 0 {
+		cat.WriteString(mdParagraphs(si.doc) + "\n\n")
+	}
+
+	items := []struct {
+		label string
+		vals  []string
+	}{
+		{"Imports", si.imports},
+		{"Expects", si.expects},
+		{"Follows", si.follows},
+	}
+
+	listed := false
+
+	for _, item := range items {
+		if len(item.vals) > 0 {
+			fmt.Fprintf(cat, "- **%s:** %s\n", item.label, mdCodeList(item.vals))
+			listed = true
+		}
+	}
+
+	if len(si.tags) > 0 {
+		cat.WriteString("- **Tags:**\n")
+
+		for _, t := range si.tags {
+			fmt.Fprintf(cat, "    - %s: %s\n",
+				mdEscaper.Replace(t.name), mdEscaper.Replace(t.value))
+		}
+
+		listed = true
+	}
+
+	if listed {
+		cat.WriteString("\n")
+	}
+
+	if len(si.code) > 0 {
+		fence := mdCodeFence(si.code)
+		cat.WriteString(fence + "go\n")
+		cat.WriteString(strings.Join(si.code, "\n") + "\n")
+		cat.WriteString(fence + "\n\n")
+	}
+}
+
+// makeCatalogue returns a Markdown catalogue of the parsed snippets. There
+// is a section for each directory holding the snippets in it. Snippets
+// which could not be parsed are not shown.
+func makeCatalogue(title string, snips sSet, parsed map[string]parsedSnippet,
+) string {
+	byDir := map[string][]string{}
+	count := 0
+
+	for _, name := range snips.names {
+		if _, ok := parsed[name]; !ok {
+			continue
+		}
+
+		s := snips.files[name]
+		byDir[s.dirName] = append(byDir[s.dirName], name)
+		count++
+	}
+
+	dirs := slices.Sorted(maps.Keys(byDir))
+
+	var cat strings.Builder
+
+	fmt.Fprintf(&cat, "# %s\n\n", mdEscaper.Replace(title))
+	fmt.Fprintf(&cat, "%d %s in %d %s\n\n",
+		count, english.Plural("snippet", count),
+		len(dirs), english.Plural("directory", len(dirs)))
+
+	for _, d := range dirs {
+		heading := catDirHeading(d)
+		fmt.Fprintf(&cat, "- [%s](#%s)\n", mdEscaper.Replace(heading),
+			mdAnchor(heading))
+	}
+
+	cat.WriteString("\n")
+
+	for _, d := range dirs {
+		fmt.Fprintf(&cat, "## %s\n\n", mdEscaper.Replace(catDirHeading(d)))
+
+		names := byDir[d]
+		slices.Sort(names)
+
+		for _, name := range names {
+			writeSnippetEntry(&cat, newSnippetInfo(name, parsed[name]))
+		}
+	}
+
+	return strings.TrimSuffix(cat.String(), "\n")
+}
+
+// catalogueSnippets returns the name and the directory of the source of
+// the snippets to be shown in the catalogue and the snippets. The copies
+// kept when installed snippets were replaced are not included.
+func (prog *prog) catalogueSnippets() (string, string, sSet) {
+	if prog.catalogueFrom == catFromSource {
+		return prog.sourceName(), "", prog.sourceSnippets
+	}
+
+	snips := sSet{files: map[string]snippet{}}
+
+	for _, name := range prog.targetSnippets.names {
+		if _, _, ok := backupCopyOf(name); ok ||
+			strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+
+		snips.names = append(snips.names, name)
+		snips.files[name] = prog.targetSnippets.files[name]
+	}
+
+	return prog.toDir, prog.toDir, snips
+}
+
+// parseCatalogueSnippets parses the snippets to be shown in the catalogue
+// from the directory or, if that is not given, from the snippet source. Any
+// snippets which cannot be parsed are reported.
+func (prog *prog) parseCatalogueSnippets(dir string, snips sSet,
+) map[string]parsedSnippet {
+	if dir == "" {
+		srcDir, cleanup, err := prog.sourceDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read the snippets: %v\n", err)
+			os.Exit(1)
+		}
+		defer cleanup()
+
+		dir = srcDir
+	}
+
+	errs := errutil.NewErrMap()
+	parsed := parseSnippets(&snippetmod.Cache{}, dir, snips.names, errs)
+
+	if errs.HasErrors() {
+		errs.Report(os.Stderr, "Reading the snippets")
+	}
+
+	return parsed
+}
+
+// writeCatalogue writes the Markdown catalogue of the snippets, either to
+// the catalogue file or, if that is not given, to the standard output.
+func (prog *prog) writeCatalogue() {
+	verbose.Println("writing the snippet catalogue")
+
+	from, dir, snips := prog.catalogueSnippets()
+	parsed := prog.parseCatalogueSnippets(dir, snips)
+
+	cat := makeCatalogue("Snippets from "+from, snips, parsed)
+
+	if prog.catalogueFile == "" {
+		fmt.Println(cat)
+		return
+	}
+
+	const catPerms = 0o644
+
+	err := os.WriteFile(prog.catalogueFile, []byte(cat+"\n"), catPerms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't write the catalogue: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMakeCatalogue(t *testing.T) {
+	snips := sSet{
+		files: map[string]snippet{
+			"ruler":        {name: "ruler"},
+			"timer/1-init": {name: "timer/1-init", dirName: "timer"},
+			"timer/2-end":  {name: "timer/2-end", dirName: "timer"},
+			"unparsed":     {name: "unparsed"},
+		},
+		names: []string{"timer/2-end", "timer/1-init", "ruler", "unparsed"},
+	}
+	parsed := map[string]parsedSnippet{
+		"ruler": testSnippet{
+			docs:    []string{"print a ruler", "of dashes", "", "uses __w"},
+			imports: []string{"fmt"},
+			text:    []string{"", `fmt.Println("---")`},
+		},
+		"timer/1-init": testSnippet{
+			docs:    []string{"first part"},
+			expects: []string{"timer/2-end"},
+			tags: map[string][]string{
+				"Env":      {"GOSH_T"},
+				"Declares": {"__start the start"},
+			},
+			text: []string{"__start := time.Now()"},
+		},
+		"timer/2-end": testSnippet{
+			follows: []string{"timer/1-init"},
+			text:    []string{"s := \"```\""},
+		},
+	}
+
+	expCat := "# Snippets from test\n" +
+		"\n" +
+		"3 snippets in 2 directories\n" +
+		"\n" +
+		"- [Top-level snippets](#top-level-snippets)\n" +
+		"- [timer](#timer)\n" +
+		"\n" +
+		"## Top-level snippets\n" +
+		"\n" +
+		"### ruler\n" +
+		"\n" +
+		"print a ruler of dashes\n" +
+		"\n" +
+		"uses \\_\\_w\n" +
+		"\n" +
+		"- **Imports:** `fmt`\n" +
+		"\n" +
+		"```go\n" +
+		"fmt.Println(\"---\")\n" +
+		"```\n" +
+		"\n" +
+		"## timer\n" +
+		"\n" +
+		"### timer/1-init\n" +
+		"\n" +
+		"first part\n" +
+		"\n" +
+		"- **Expects:** `timer/2-end`\n" +
+		"- **Tags:**\n" +
+		"    - Declares: \\_\\_start the start\n" +
+		"    - Env: GOSH\\_T\n" +
+		"\n" +
+		"```go\n" +
+		"__start := time.Now()\n" +
+		"```\n" +
+		"\n" +
+		"### timer/2-end\n" +
+		"\n" +
+		"- **Follows:** `timer/1-init`\n" +
+		"\n" +
+		"````go\n" +
+		"s := \"```\"\n" +
+		"````\n"
+
+	testhelper.DiffString(t, "catalogue", "markdown",
+		makeCatalogue("Snippets from test", snips, parsed), expCat)
+}
+
+func TestMdAnchor(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		heading   string
+		expAnchor string
+	}{
+		{
+			ID:        testhelper.MkID("simple"),
+			heading:   "timer",
+			expAnchor: "timer",
+		},
+		{
+			ID:        testhelper.MkID("spaces and punctuation"),
+			heading:   "Top-level snippets",
+			expAnchor: "top-level-snippets",
+		},
+		{
+			ID:        testhelper.MkID("sub-directory"),
+			heading:   "a/b_c",
+			expAnchor: "ab_c",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "anchor",
+			mdAnchor(tc.heading), tc.expAnchor)
+	}
+}
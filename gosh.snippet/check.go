@@ -0,0 +1,469 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/errutil.mod/errutil"
+	snippetmod "github.com/nickwells/snippet.mod/snippet"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// goshVarsFile declares the variables which gosh provides to the code it
+// generates. These mirror the variables declared by gosh so that snippets
+// using them can be type-checked.
+const goshVarsFile = `package main
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+var (
+	_arg string
+	_rw  http.ResponseWriter
+	_req *http.Request
+	_w   *os.File
+	_l   *bufio.Scanner
+	_lp  []string
+	_fl  int
+	_fn  string
+	_f   *os.File
+	_err error
+	_sre *regexp.Regexp
+)
+`
+
+// ignoredTypeErrs holds fragments of the type-checker error messages which
+// are not reported. Variables and imports may be used by later snippets and
+// imports which cannot be found are reported separately.
+var ignoredTypeErrs = []string{
+	"declared and not used",
+	"imported and not used",
+	"could not import",
+}
+
+// undefinedTypeErr is the start of the type-checker error message for a
+// name which is not declared. Snippets may use names declared in the code
+// they are added to and so bare names are only reported in verbose
+// mode. A qualified name (such as fmt.Prinln) cannot be declared by gosh
+// or by another snippet and so it is reported as an error.
+const undefinedTypeErr = "undefined: "
+
+// parsedSnippet is the snippet as parsed from the snippet file by the
+// snippet cache, giving the snippet comments and code just as gosh sees
+// them
+type parsedSnippet interface {
+	Docs() []string
+	Imports() []string
+	Expects() []string
+	Follows() []string
+	Tags() map[string][]string
+	Text() []string
+}
+
+// checkSourceSnippets checks the snippets in the source set which are
+// chosen by the filter. It checks that the snippet comments are valid, that
+// the imported packages can be found, that the snippets they expect or
+// follow are in the set and that the code type-checks. Any problems are
+// reported and it returns false if there were any.
+func (prog *prog) checkSourceSnippets() bool {
+	verbose.Println("checking snippets")
+
+	errs := errutil.NewErrMap()
+
+	dir, cleanup, err := prog.sourceDir()
+	if err != nil {
+		errs.AddError("Snippet source", err)
+		errs.Report(os.Stderr, "Checking snippets")
+
+		return false
+	}
+	defer cleanup()
+
+	names := prog.selectedSourceNames()
+	cache := &snippetmod.Cache{}
+	snips := parseSnippets(cache, dir, names, errs)
+
+	cache.Check(errs)
+
+	checkFollows(snips, errs)
+	checkImports(snips, errs)
+	checkCode(snips, errs)
+
+	errCount, _ := errs.CountErrors()
+	badCount := len(errs.Keys())
+
+	fmt.Println(len(names), english.Plural("snippet", len(names)),
+		"checked,", badCount, "with problems")
+
+	if errCount == 0 {
+		return true
+	}
+
+	errs.Report(os.Stderr, "Checking snippets")
+
+	return false
+}
+
+// sourceDir returns the name of a directory holding the source snippets
+// and a func to be called when the directory is no longer needed. If the
+// snippets are not in a directory they are copied into a temporary
+// directory which the cleanup func will remove.
+func (prog *prog) sourceDir() (string, func(), error) {
+	if prog.fromDir != "" && !prog.fromArchive {
+		return prog.fromDir, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "gosh.snippet-source-*.d")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	if err := os.CopyFS(dir, prog.sourceFS); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return dir, cleanup, nil
+}
+
+// parseSnippets parses the named snippets in the directory using the
+// snippet cache. Any snippets which cannot be parsed are recorded in the
+// error map and are not returned.
+func parseSnippets(cache *snippetmod.Cache, dir string, names []string,
+	errs *errutil.ErrMap,
+) map[string]parsedSnippet {
+	snips := map[string]parsedSnippet{}
+
+	for _, name := range names {
+		s, err := cache.Add([]string{dir}, name)
+		if err != nil {
+			errs.AddError(name, err)
+			continue
+		}
+
+		snips[name] = s
+	}
+
+	return snips
+}
+
+// checkFollows checks that the snippets which each snippet follows are in
+// the set
+func checkFollows(snips map[string]parsedSnippet, errs *errutil.ErrMap) {
+	for _, name := range slices.Sorted(maps.Keys(snips)) {
+		for _, f := range snips[name].Follows() {
+			if _, ok := snips[f]; !ok {
+				errs.AddError(name,
+					fmt.Errorf("it follows %q which is not in the set", f))
+			}
+		}
+	}
+}
+
+// checkImports checks that the packages imported by the snippets are
+// either in the standard library or can be found by the Go command
+func checkImports(snips map[string]parsedSnippet, errs *errutil.ErrMap) {
+	std, err := stdPackages()
+	if err != nil {
+		errs.AddError("Imports", err)
+		return
+	}
+
+	resolved := map[string]error{}
+
+	for _, name := range slices.Sorted(maps.Keys(snips)) {
+		for _, imp := range snips[name].Imports() {
+			path := importPath(imp)
+			if std[path] {
+				continue
+			}
+
+			resErr, ok := resolved[path]
+			if !ok {
+				resErr = resolvePackage(path)
+				resolved[path] = resErr
+			}
+
+			if resErr != nil {
+				errs.AddError(name,
+					fmt.Errorf("the imported package %q cannot be found: %w",
+						path, resErr))
+			}
+		}
+	}
+}
+
+// importPath returns the package path from the import. The import may
+// have a package name before the path and the path may be quoted.
+func importPath(imp string) string {
+	parts := strings.Fields(imp)
+	if len(parts) == 0 {
+		return ""
+	}
+
+	path := parts[len(parts)-1]
+	if p, err := strconv.Unquote(path); err == nil {
+		return p
+	}
+
+	return path
+}
+
+// stdPackages returns the set of standard library packages
+func stdPackages() (map[string]bool, error) {
+	out, err := exec.Command("go", "list", "std").Output()
+	if err != nil {
+		return nil,
+			fmt.Errorf("cannot list the standard library packages: %w", err)
+	}
+
+	std := map[string]bool{}
+	for _, p := range strings.Fields(string(out)) {
+		std[p] = true
+	}
+
+	return std, nil
+}
+
+// resolvePackage checks that the Go command can find the package. It
+// creates a temporary module and gets the package into it.
+func resolvePackage(path string) error {
+	dir, err := os.MkdirTemp("", "gosh.snippet-mod-*.d")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck
+
+	for _, args := range [][]string{
+		{"mod", "init", "gosh.snippet.check"},
+		{"get", path},
+	} {
+		cmd := exec.Command("go", args...) //nolint:gosec
+		cmd.Dir = dir
+
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.New(strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
+
+// checkCode type-checks the code of each snippet. A snippet which is
+// followed by other snippets is not checked by itself but as part of the
+// code of the snippets which follow it.
+func checkCode(snips map[string]parsedSnippet, errs *errutil.ErrMap) {
+	followed := map[string]bool{}
+
+	for _, s := range snips {
+		for _, f := range s.Follows() {
+			followed[f] = true
+		}
+	}
+
+	imp := stdImporter{importer.Default()}
+
+	for _, name := range slices.Sorted(maps.Keys(snips)) {
+		if followed[name] {
+			continue
+		}
+
+		chain := snippetChain(name, snips)
+
+		var (
+			code    []string
+			imports []string
+		)
+
+		for _, n := range chain {
+			code = append(code, snips[n].Text()...)
+			imports = append(imports, snips[n].Imports()...)
+		}
+
+		typeErrs, undefined := typeCheck(strings.Join(code, "\n"),
+			imports, imp)
+		for _, err := range typeErrs {
+			if len(chain) > 1 {
+				err = fmt.Errorf("%w (checked with %s)", err,
+					english.Join(chain[:len(chain)-1], ", ", " and "))
+			}
+
+			errs.AddError(name, err)
+		}
+
+		if len(undefined) > 0 {
+			verbose.Println("\t", name, ": uses names declared elsewhere: ",
+				strings.Join(undefined, ", "))
+		}
+	}
+}
+
+// snippetChain returns the names of the snippets which the named snippet
+// follows, in the order they should appear, followed by the snippet itself
+func snippetChain(name string, snips map[string]parsedSnippet) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+
+	var addFollowed func(n string)
+
+	addFollowed = func(n string) {
+		if seen[n] {
+			return
+		}
+
+		seen[n] = true
+
+		s, ok := snips[n]
+		if !ok {
+			return
+		}
+
+		for _, f := range s.Follows() {
+			addFollowed(f)
+		}
+
+		chain = append(chain, n)
+	}
+
+	addFollowed(name)
+
+	return chain
+}
+
+// typeCheck wraps the code in a main func, or if it is not valid there,
+// adds it at the top level of a file, and type-checks it. It returns the
+// errors found and the names which are used but not declared.
+func typeCheck(code string, imports []string, imp types.Importer,
+) ([]error, []string) {
+	fset := token.NewFileSet()
+
+	varsFile, err := parser.ParseFile(fset, "gosh.go", goshVarsFile, 0)
+	if err != nil {
+		return []error{err}, nil
+	}
+
+	var importDecls strings.Builder
+
+	for _, i := range slices.Compact(slices.Sorted(slices.Values(imports))) {
+		path := importPath(i)
+		if path == "" {
+			continue
+		}
+
+		importDecls.WriteString("import " + strconv.Quote(path) + "\n")
+	}
+
+	stmtPrefix := "package main\n\n" + importDecls.String() +
+		"\nfunc main() {\nfor range 1 {\n"
+	declPrefix := "package main\n\n" + importDecls.String() + "\n"
+
+	prefix := stmtPrefix
+
+	f, err := parser.ParseFile(fset, "snippet.go",
+		stmtPrefix+code+"\n}\n}\n", 0)
+	if err != nil {
+		var declErr error
+
+		prefix = declPrefix
+
+		f, declErr = parser.ParseFile(fset, "snippet.go",
+			declPrefix+code+"\n\nfunc main() {}\n", 0)
+		if declErr != nil {
+			var errList scanner.ErrorList
+			if errors.As(err, &errList) && len(errList) > 0 {
+				return []error{
+					codeError(errList[0].Pos, stmtPrefix,
+						"the code does not parse: "+errList[0].Msg),
+				}, nil
+			}
+
+			return []error{fmt.Errorf("the code does not parse: %w", err)},
+				nil
+		}
+	}
+
+	var (
+		typeErrs  []error
+		undefined []string
+	)
+
+	conf := types.Config{
+		Importer: imp,
+		Error: func(err error) {
+			te, ok := err.(types.Error) //nolint:errorlint
+			if !ok {
+				typeErrs = append(typeErrs, err)
+				return
+			}
+
+			for _, ignored := range ignoredTypeErrs {
+				if strings.Contains(te.Msg, ignored) {
+					return
+				}
+			}
+
+			name, ok := strings.CutPrefix(te.Msg, undefinedTypeErr)
+			if ok && !strings.Contains(name, ".") {
+				undefined = append(undefined, name)
+				return
+			}
+
+			typeErrs = append(typeErrs,
+				codeError(te.Fset.Position(te.Pos), prefix, te.Msg))
+		},
+	}
+
+	_, _ = conf.Check("main", fset, []*ast.File{varsFile, f}, nil)
+
+	return typeErrs, undefined
+}
+
+// codeError returns an error reporting the message at the position in the
+// code of the snippet. The position is in the generated file whose text
+// before the snippet code is given by the prefix.
+func codeError(pos token.Position, prefix, msg string) error {
+	line := pos.Line - strings.Count(prefix, "\n")
+	if line < 1 {
+		return errors.New(msg)
+	}
+
+	return fmt.Errorf("line %d: %s", line, msg)
+}
+
+// stdImporter imports only the standard library packages. The packages
+// imported by the snippets which are not in the standard library are
+// checked separately.
+type stdImporter struct {
+	imp types.Importer
+}
+
+// Import imports the package if it is in the standard library. A package
+// whose path has a dot in the first element is not in the standard library
+// and an error is returned.
+func (si stdImporter) Import(path string) (*types.Package, error) {
+	first, _, _ := strings.Cut(path, "/")
+	if strings.Contains(first, ".") {
+		return nil, fmt.Errorf("could not import %q: not checked", path)
+	}
+
+	return si.imp.Import(path)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"go/importer"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+// testSnippet is a parsedSnippet used in tests
+type testSnippet struct {
+	docs    []string
+	imports []string
+	expects []string
+	follows []string
+	tags    map[string][]string
+	text    []string
+}
+
+func (ts testSnippet) Docs() []string            { return ts.docs }
+func (ts testSnippet) Imports() []string         { return ts.imports }
+func (ts testSnippet) Expects() []string         { return ts.expects }
+func (ts testSnippet) Follows() []string         { return ts.follows }
+func (ts testSnippet) Tags() map[string][]string { return ts.tags }
+func (ts testSnippet) Text() []string            { return ts.text }
+
+func TestImportPath(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		imp     string
+		expPath string
+	}{
+		{
+			ID:      testhelper.MkID("plain"),
+			imp:     "fmt",
+			expPath: "fmt",
+		},
+		{
+			ID:      testhelper.MkID("quoted"),
+			imp:     `"net/http"`,
+			expPath: "net/http",
+		},
+		{
+			ID:      testhelper.MkID("named"),
+			imp:     `str "strings"`,
+			expPath: "strings",
+		},
+		{
+			ID:      testhelper.MkID("empty"),
+			imp:     " ",
+			expPath: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "import path",
+			importPath(tc.imp), tc.expPath)
+	}
+}
+
+func TestSnippetChain(t *testing.T) {
+	snips := map[string]parsedSnippet{
+		"t/1": testSnippet{},
+		"t/2": testSnippet{follows: []string{"t/1"}},
+		"t/3": testSnippet{follows: []string{"t/2", "t/1"}},
+		"x":   testSnippet{follows: []string{"missing"}},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		name     string
+		expChain []string
+	}{
+		{
+			ID:       testhelper.MkID("follows nothing"),
+			name:     "t/1",
+			expChain: []string{"t/1"},
+		},
+		{
+			ID:       testhelper.MkID("follows a chain"),
+			name:     "t/3",
+			expChain: []string{"t/1", "t/2", "t/3"},
+		},
+		{
+			ID:       testhelper.MkID("follows a missing snippet"),
+			name:     "x",
+			expChain: []string{"x"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffStringSlice(t, tc.IDStr(), "chain",
+			snippetChain(tc.name, snips), tc.expChain)
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	imp := stdImporter{importer.Default()}
+
+	testCases := []struct {
+		testhelper.ID
+		code         string
+		imports      []string
+		expErrs      []string
+		expUndefined []string
+	}{
+		{
+			ID:      testhelper.MkID("good statements"),
+			code:    "x := strings.ToUpper(_fn)\nif _fl > 0 {\ncontinue\n}",
+			imports: []string{"strings"},
+		},
+		{
+			ID:      testhelper.MkID("good declarations"),
+			code:    "func f() string {\nreturn _l.Text()\n}",
+			imports: []string{"fmt"},
+		},
+		{
+			ID:      testhelper.MkID("not checked import"),
+			code:    "x := pkg.F()",
+			imports: []string{"example.com/pkg"},
+		},
+		{
+			ID:           testhelper.MkID("undefined names"),
+			code:         "x := a / b",
+			expUndefined: []string{"a", "b"},
+		},
+		{
+			ID:      testhelper.MkID("misspelt package member"),
+			code:    "fmt.Prinln(_fn)",
+			imports: []string{"fmt"},
+			expErrs: []string{"line 1: undefined: fmt.Prinln"},
+		},
+		{
+			ID:      testhelper.MkID("bad type"),
+			code:    "var i int\ni = _fn",
+			expErrs: []string{"line 2: cannot use _fn"},
+		},
+		{
+			ID:      testhelper.MkID("bad syntax"),
+			code:    "x := )\ny := 1",
+			expErrs: []string{"line 1: the code does not parse"},
+		},
+	}
+
+	for _, tc := range testCases {
+		errs, undefined := typeCheck(tc.code, tc.imports, imp)
+
+		testhelper.DiffInt(t, tc.IDStr(), "error count",
+			len(errs), len(tc.expErrs))
+
+		for i, err := range errs {
+			if i < len(tc.expErrs) &&
+				!strings.HasPrefix(err.Error(), tc.expErrs[i]) {
+				t.Log(tc.IDStr())
+				t.Logf("\t: expected: %s...", tc.expErrs[i])
+				t.Logf("\t:      got: %s", err)
+				t.Error("\t: unexpected error")
+			}
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "undefined names",
+			undefined, tc.expUndefined)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// The values of the colour parameter
+const (
+	colourAuto   = "auto"
+	colourAlways = "always"
+	colourNever  = "never"
+)
+
+// The categories of snippet reported by the compare action
+const (
+	cmpNew             = "New"
+	cmpDuplicate       = "Duplicate"
+	cmpDiffers         = "Differs"
+	cmpUpstreamChanged = "Upstream changed"
+	cmpLocallyModified = "Locally modified"
+	cmpBothChanged     = "Both changed"
+	cmpLocallyDeleted  = "Locally deleted"
+	cmpExtra           = "Extra"
+	cmpObsolete        = "Obsolete"
+	cmpUserCreated     = "User-created"
+
+	cmpCategoryWidth = len(cmpUpstreamChanged)
+)
+
+// cmpCategories lists the compare categories in the order they are shown
+// in the summary
+var cmpCategories = []string{
+	cmpNew,
+	cmpDuplicate,
+	cmpDiffers,
+	cmpUpstreamChanged,
+	cmpLocallyModified,
+	cmpBothChanged,
+	cmpLocallyDeleted,
+	cmpExtra,
+	cmpObsolete,
+	cmpUserCreated,
+}
+
+// compareSnippets compares the snippets in the from directory with those in
+// the to directory reporting any differences. If the diff is to be shown
+// then the differences between each installed snippet and the
+// corresponding snippet from the source are shown as a unified diff. If
+// only the summary is wanted then just the number of snippets in each
+// category is shown. Only the snippets chosen by the filter are compared.
+func (prog *prog) compareSnippets() {
+	verbose.Println("comparing snippets")
+
+	counts := map[string]int{}
+	useColour := prog.useColour()
+
+	for _, name := range prog.selectedSourceNames() {
+		fromS := prog.sourceSnippets.files[name]
+		toS, installed := prog.targetSnippets.files[name]
+		base, hasBaseline := prog.manifest.baseline(name)
+
+		cat := sourceCmpCategory(fromS, toS, installed, base, hasBaseline)
+		counts[cat]++
+
+		if prog.cmpSummary {
+			continue
+		}
+
+		fmt.Printf("%*s: %s\n", cmpCategoryWidth, cat, name)
+
+		if prog.showDiff && installed && cat != cmpDuplicate {
+			fmt.Print(unifiedDiff("installed/"+name, "source/"+name,
+				string(toS.content), string(fromS.content),
+				int(prog.diffContext), useColour))
+		}
+	}
+
+	for _, name := range prog.targetSnippets.names {
+		if !prog.filter.selects(prog.targetSnippets.files[name]) {
+			continue
+		}
+
+		if _, ok := prog.sourceSnippets.files[name]; !ok {
+			_, hasBaseline := prog.manifest.baseline(name)
+
+			cat := targetCmpCategory(prog.manifest.isEmpty(), hasBaseline)
+			counts[cat]++
+
+			if prog.cmpSummary {
+				continue
+			}
+
+			fmt.Printf("%*s: %s\n", cmpCategoryWidth, cat, name)
+		}
+	}
+
+	if prog.cmpSummary {
+		reportCmpSummary(counts)
+	}
+}
+
+// reportCmpSummary prints the number of snippets in each category. Only
+// the categories with some snippets are shown.
+func reportCmpSummary(counts map[string]int) {
+	for _, cat := range cmpCategories {
+		if counts[cat] > 0 {
+			fmt.Printf("%*s:%4d\n", cmpCategoryWidth, cat, counts[cat])
+		}
+	}
+}
+
+// useColour returns true if the diff output should be coloured. If the
+// colour parameter is set to auto then the output is coloured only if the
+// standard output is a terminal.
+func (prog *prog) useColour() bool {
+	switch prog.colour {
+	case colourAlways:
+		return true
+	case colourNever:
+		return false
+	}
+
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// sourceCmpCategory returns the compare category of a snippet from the
+// source. The baseline is the manifest entry recorded when the snippet was
+// last installed; if there is one it is used to tell whether the snippet
+// has been changed locally, in the source or in both places.
+func sourceCmpCategory(fromS, toS snippet, installed bool,
+	base manifestEntry, hasBaseline bool,
+) string {
+	if !installed {
+		if hasBaseline {
+			return cmpLocallyDeleted
+		}
+
+		return cmpNew
+	}
+
+	if string(toS.content) == string(fromS.content) {
+		return cmpDuplicate
+	}
+
+	if !hasBaseline {
+		return cmpDiffers
+	}
+
+	if contentHash(toS.content) == base.Hash {
+		return cmpUpstreamChanged
+	}
+
+	if contentHash(fromS.content) == base.Hash {
+		return cmpLocallyModified
+	}
+
+	return cmpBothChanged
+}
+
+// targetCmpCategory returns the compare category of a snippet which is in
+// the target but not in the source. If there is no manifest then it cannot
+// be told whether or not the snippet was installed by gosh.snippet.
+func targetCmpCategory(noManifest, hasBaseline bool) string {
+	if noManifest {
+		return cmpExtra
+	}
+
+	if hasBaseline {
+		return cmpObsolete
+	}
+
+	return cmpUserCreated
+}
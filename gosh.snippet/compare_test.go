@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSourceCmpCategory(t *testing.T) {
+	orig := snippet{content: []byte("orig\n")}
+	local := snippet{content: []byte("local\n")}
+	upstream := snippet{content: []byte("upstream\n")}
+	base := manifestEntry{Hash: contentHash(orig.content)}
+
+	testCases := []struct {
+		testhelper.ID
+		fromS       snippet
+		toS         snippet
+		installed   bool
+		hasBaseline bool
+		expCat      string
+	}{
+		{
+			ID:     testhelper.MkID("new"),
+			fromS:  orig,
+			expCat: cmpNew,
+		},
+		{
+			ID:          testhelper.MkID("locally deleted"),
+			fromS:       orig,
+			hasBaseline: true,
+			expCat:      cmpLocallyDeleted,
+		},
+		{
+			ID:          testhelper.MkID("duplicate"),
+			fromS:       orig,
+			toS:         orig,
+			installed:   true,
+			hasBaseline: true,
+			expCat:      cmpDuplicate,
+		},
+		{
+			ID:        testhelper.MkID("differs, no baseline"),
+			fromS:     upstream,
+			toS:       orig,
+			installed: true,
+			expCat:    cmpDiffers,
+		},
+		{
+			ID:          testhelper.MkID("upstream changed"),
+			fromS:       upstream,
+			toS:         orig,
+			installed:   true,
+			hasBaseline: true,
+			expCat:      cmpUpstreamChanged,
+		},
+		{
+			ID:          testhelper.MkID("locally modified"),
+			fromS:       orig,
+			toS:         local,
+			installed:   true,
+			hasBaseline: true,
+			expCat:      cmpLocallyModified,
+		},
+		{
+			ID:          testhelper.MkID("both changed"),
+			fromS:       upstream,
+			toS:         local,
+			installed:   true,
+			hasBaseline: true,
+			expCat:      cmpBothChanged,
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "category",
+			sourceCmpCategory(tc.fromS, tc.toS, tc.installed,
+				base, tc.hasBaseline),
+			tc.expCat)
+	}
+}
+
+func TestTargetCmpCategory(t *testing.T) {
+	testhelper.DiffString(t, "no manifest", "category",
+		targetCmpCategory(true, false), cmpExtra)
+	testhelper.DiffString(t, "in the manifest", "category",
+		targetCmpCategory(false, true), cmpObsolete)
+	testhelper.DiffString(t, "not in the manifest", "category",
+		targetCmpCategory(false, false), cmpUserCreated)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"github.com/nickwells/utilities/internal/textdiff"
+)
+
+// unifiedDiff returns the differences between the texts in the unified
+// diff format with the given number of lines of context around each change.
+// If useColour is true the output is coloured. An empty string is returned
+// if the texts are the same.
+func unifiedDiff(aName, bName, aText, bText string,
+	context int, useColour bool,
+) string {
+	ops := textdiff.Diff(textdiff.SplitLines(aText),
+		textdiff.SplitLines(bText), textdiff.WSExact)
+	hunks := textdiff.Hunks(ops, context)
+
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	return textdiff.Unified(aName, bName, ops, hunks,
+		textdiff.NewColours(useColour))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+	"github.com/nickwells/utilities/internal/textdiff"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	dc := textdiff.NewColours(true)
+
+	testCases := []struct {
+		testhelper.ID
+		aText     string
+		bText     string
+		context   int
+		useColour bool
+		expDiff   string
+	}{
+		{
+			ID:    testhelper.MkID("same"),
+			aText: "a\nb\n",
+			bText: "a\nb\n",
+		},
+		{
+			ID:      testhelper.MkID("one change"),
+			aText:   "1\n2\n3\n4\n5\n6\n7\n",
+			bText:   "1\n2\n3\nX\n5\n6\n7\n",
+			context: 1,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -3,3 +3,3 @@\n" +
+				" 3\n-4\n+X\n 5\n",
+		},
+		{
+			ID:      testhelper.MkID("two hunks"),
+			aText:   "1\n2\n3\n4\n5\n6\n7\n",
+			bText:   "X\n2\n3\n4\n5\n6\n",
+			context: 1,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -1,2 +1,2 @@\n" +
+				"-1\n+X\n 2\n" +
+				"@@ -6,2 +6 @@\n" +
+				" 6\n-7\n",
+		},
+		{
+			ID:      testhelper.MkID("changes merged into one hunk"),
+			aText:   "1\n2\n3\n4\n5\n",
+			bText:   "X\n2\n3\n4\nY\n",
+			context: 2,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -1,5 +1,5 @@\n" +
+				"-1\n+X\n 2\n 3\n 4\n-5\n+Y\n",
+		},
+		{
+			ID:      testhelper.MkID("from empty, no newline"),
+			bText:   "a",
+			context: 3,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -0,0 +1 @@\n" +
+				"+a\n" + textdiff.NoNewlineMsg + "\n",
+		},
+		{
+			ID:        testhelper.MkID("coloured"),
+			aText:     "a\n",
+			bText:     "b\n",
+			useColour: true,
+			expDiff: dc.Header + "--- a" + dc.Reset + "\n" +
+				dc.Header + "+++ b" + dc.Reset + "\n" +
+				dc.Hunk + "@@ -1 +1 @@" + dc.Reset + "\n" +
+				dc.Del + "-a" + dc.Reset + "\n" +
+				dc.Ins + "+b" + dc.Reset + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		diff := unifiedDiff("a", "b", tc.aText, tc.bText,
+			tc.context, tc.useColour)
+		testhelper.DiffString(t, tc.IDStr(), "diff", diff, tc.expDiff)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/errutil.mod/errutil"
+	snippetmod "github.com/nickwells/snippet.mod/snippet"
+)
+
+// snippetFilter records the criteria used to choose which snippets are
+// compared or installed. A snippet is chosen if, for each kind of
+// criterion, it matches at least one of the values to include (if any are
+// given) and none of the values to exclude.
+type snippetFilter struct {
+	names        []string
+	excludeNames []string
+	dirs         []string
+	excludeDirs  []string
+	tags         []string
+	excludeTags  []string
+}
+
+// isEmpty returns true if no filter criteria have been given
+func (sf snippetFilter) isEmpty() bool {
+	return len(sf.names) == 0 && len(sf.excludeNames) == 0 &&
+		len(sf.dirs) == 0 && len(sf.excludeDirs) == 0 &&
+		len(sf.tags) == 0 && len(sf.excludeTags) == 0
+}
+
+// selects returns true if the snippet is chosen by the filter
+func (sf snippetFilter) selects(s snippet) bool {
+	if len(sf.names) > 0 && !matchesAnyGlob(sf.names, s.name) {
+		return false
+	}
+
+	if matchesAnyGlob(sf.excludeNames, s.name) {
+		return false
+	}
+
+	if len(sf.dirs) > 0 && !inAnyDir(sf.dirs, s.name) {
+		return false
+	}
+
+	if inAnyDir(sf.excludeDirs, s.name) {
+		return false
+	}
+
+	if len(sf.tags) > 0 && !hasAnyTag(sf.tags, s.tags) {
+		return false
+	}
+
+	return !hasAnyTag(sf.excludeTags, s.tags)
+}
+
+// badGlobs returns those of the patterns which are not valid
+func badGlobs(patterns []string) []string {
+	bad := []string{}
+
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			bad = append(bad, p)
+		}
+	}
+
+	return bad
+}
+
+// matchesAnyGlob returns true if the snippet name matches any of the
+// patterns. A pattern with no path separator is also matched against the
+// last part of the name so that snippets in sub-directories can be chosen
+// without giving the directory.
+func matchesAnyGlob(patterns []string, name string) bool {
+	base := filepath.Base(name)
+
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+
+		if strings.ContainsRune(p, filepath.Separator) {
+			continue
+		}
+
+		if ok, _ := filepath.Match(p, base); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inAnyDir returns true if the snippet name is in any of the directories
+// or their sub-directories
+func inAnyDir(dirs []string, name string) bool {
+	for _, d := range dirs {
+		d = filepath.Clean(d)
+		if d == "." || strings.HasPrefix(name, d+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasAnyTag returns true if any of the wanted tags is in the snippet
+// tags. Tag names are compared ignoring case.
+func hasAnyTag(wanted, tags []string) bool {
+	for _, w := range wanted {
+		if slices.ContainsFunc(tags,
+			func(t string) bool { return strings.EqualFold(w, t) }) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// usesTags returns true if the filter chooses snippets by their tags
+func (sf snippetFilter) usesTags() bool {
+	return len(sf.tags) > 0 || len(sf.excludeTags) > 0
+}
+
+// addTags records the names of the tags given in the snippet comments of
+// each snippet in the set. The snippets are parsed from the directory. Any
+// snippets which cannot be parsed are reported and are taken to have no
+// tags.
+func addTags(snips *sSet, dir string) {
+	errs := errutil.NewErrMap()
+
+	parsed := parseSnippets(&snippetmod.Cache{}, dir, snips.names, errs)
+	for name, ps := range parsed {
+		s := snips.files[name]
+		s.tags = slices.Sorted(maps.Keys(ps.Tags()))
+		snips.files[name] = s
+	}
+
+	if errs.HasErrors() {
+		errs.Report(os.Stderr, "Reading the snippet tags")
+	}
+}
+
+// selectedSourceNames returns the names of the source snippets chosen by
+// the filter
+func (prog *prog) selectedSourceNames() []string {
+	if prog.filter.isEmpty() {
+		return prog.sourceSnippets.names
+	}
+
+	names := []string{}
+
+	for _, name := range prog.sourceSnippets.names {
+		if prog.filter.selects(prog.sourceSnippets.files[name]) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
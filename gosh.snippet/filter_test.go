@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestAddTags(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"timer": "// snippet: Tag: Declares: __x the x\n" +
+			"// snippet: Tag: Env: GOSH_X\n" +
+			"// snippet: Tag: Declares: __y the y\n" +
+			"// Tag: Author: not a snippet comment\n",
+		"ruler": "// snippet: Doc: x\nfmt.Println()\n",
+	}
+
+	snips := sSet{files: map[string]snippet{}}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)
+		if err != nil {
+			t.Fatal("cannot create the snippet file: ", err)
+		}
+
+		snips.names = append(snips.names, name)
+		snips.files[name] = snippet{name: name}
+	}
+
+	addTags(&snips, dir)
+
+	testhelper.DiffStringSlice(t, "tags", "timer",
+		snips.files["timer"].tags, []string{"Declares", "Env"})
+	testhelper.DiffStringSlice(t, "tags", "ruler",
+		snips.files["ruler"].tags, []string{})
+}
+
+func TestSnippetFilter(t *testing.T) {
+	timer := snippet{
+		name: "timer/1-init",
+		tags: []string{"Declares"},
+	}
+	ruler := snippet{name: "ruler60"}
+	nested := snippet{name: "a/b/c"}
+
+	testCases := []struct {
+		testhelper.ID
+		filter   snippetFilter
+		snippets []snippet
+		expNames []string
+	}{
+		{
+			ID:       testhelper.MkID("no filter"),
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init", "ruler60", "a/b/c"},
+		},
+		{
+			ID:       testhelper.MkID("include by full name"),
+			filter:   snippetFilter{names: []string{"timer/*"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init"},
+		},
+		{
+			ID:       testhelper.MkID("include by last part of name"),
+			filter:   snippetFilter{names: []string{"ruler*", "c"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"ruler60", "a/b/c"},
+		},
+		{
+			ID:       testhelper.MkID("exclude by name"),
+			filter:   snippetFilter{excludeNames: []string{"*init"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"ruler60", "a/b/c"},
+		},
+		{
+			ID:       testhelper.MkID("include by dir"),
+			filter:   snippetFilter{dirs: []string{"a/", "timer"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init", "a/b/c"},
+		},
+		{
+			ID:       testhelper.MkID("exclude by dir"),
+			filter:   snippetFilter{excludeDirs: []string{"a/b"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init", "ruler60"},
+		},
+		{
+			ID:       testhelper.MkID("include by tag"),
+			filter:   snippetFilter{tags: []string{"declares"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init"},
+		},
+		{
+			ID:       testhelper.MkID("exclude by tag"),
+			filter:   snippetFilter{excludeTags: []string{"Declares"}},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"ruler60", "a/b/c"},
+		},
+		{
+			ID: testhelper.MkID("include and exclude"),
+			filter: snippetFilter{
+				dirs:         []string{"timer", "a"},
+				excludeNames: []string{"c"},
+			},
+			snippets: []snippet{timer, ruler, nested},
+			expNames: []string{"timer/1-init"},
+		},
+	}
+
+	for _, tc := range testCases {
+		prog := newProg()
+		prog.filter = tc.filter
+		prog.sourceSnippets.files = map[string]snippet{}
+
+		for _, s := range tc.snippets {
+			prog.sourceSnippets.names = append(prog.sourceSnippets.names,
+				s.name)
+			prog.sourceSnippets.files[s.name] = s
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "selected names",
+			prog.selectedSourceNames(), tc.expNames)
+	}
+}
+
+func TestBadGlobs(t *testing.T) {
+	testhelper.DiffStringSlice(t, "bad globs", "patterns",
+		badGlobs([]string{"a*", "[", "b?", "c[x"}),
+		[]string{"[", "c[x"})
+}
@@ -6,12 +6,15 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"testing/fstest"
 	"time"
 
+	"github.com/nickwells/cli.mod/cli/responder"
 	"github.com/nickwells/english.mod/english"
 	"github.com/nickwells/errutil.mod/errutil"
 	"github.com/nickwells/filecheck.mod/filecheck"
@@ -22,30 +25,78 @@ import (
 // Created: Wed May 26 22:30:48 2021
 
 const (
-	installAction = "install"
-	cmpAction     = "compare"
+	installAction   = "install"
+	uninstallAction = "uninstall"
+	cmpAction       = "compare"
+	checkAction     = "check"
+	tidyAction      = "tidy"
+	catAction       = "catalogue"
 )
 
 const (
-	dfltMaxSubDirs = 10
+	stdSnippetSource = "the standard collection"
+)
+
+const (
+	dfltMaxSubDirs  = 10
+	dfltDiffContext = 3
 )
 
 const (
 	listItemIndent = 8
 )
 
+// The names of the copies kept when installed snippets are replaced. The
+// copy has the backup suffix and, if there is already a copy, the
+// timestamp as well. Every field in the timestamp format has a fixed width
+// so a formatted timestamp is the same length as the format.
+const (
+	backupSuffix    = ".orig"
+	timestampFormat = ".20060102-150405.000"
+	timestampLen    = len(timestampFormat)
+)
+
+// The actions reported in a dry run
+const (
+	dryRunMkdir     = "create dir"
+	dryRunWrite     = "write"
+	dryRunRemove    = "remove"
+	dryRunRename    = "rename"
+	dryRunTimestamp = "timestamp"
+
+	dryRunActionWidth = len(dryRunMkdir)
+)
+
 const (
 	dfltDirPerms = 0o755 // User: Read/Write/Search, the rest: Read/Search
 )
 
 // prog holds program data and parameter values
 type prog struct {
-	fromDir string
-	toDir   string
-	action  string
+	fromDir     string
+	fromArchive bool
+	toDir       string
+	action      string
 
 	maxSubDirs int64
 	noCopy     bool
+	checkFirst bool
+	dryRun     bool
+
+	filter snippetFilter
+
+	tidyKeep       int64
+	tidyMaxAgeDays int64
+	tidyMode       string
+	tidyR          responder.Responder
+
+	catalogueFrom string
+	catalogueFile string
+
+	showDiff    bool
+	diffContext int64
+	colour      string
+	cmpSummary  bool
 
 	status Status
 
@@ -56,19 +107,39 @@ type prog struct {
 
 	sourceSnippets sSet
 	targetSnippets sSet
+
+	manifest *manifest
+
+	dryRunDirs map[string]bool
 }
 
 // newProg creates an initialised Prog struct
 func newProg() *prog {
 	return &prog{
-		action:     cmpAction,
-		maxSubDirs: dfltMaxSubDirs,
+		action:      cmpAction,
+		maxSubDirs:  dfltMaxSubDirs,
+		diffContext: dfltDiffContext,
+		colour:      colourAuto,
+
+		dryRunDirs: map[string]bool{},
+
+		catalogueFrom: catFromSource,
+
+		tidyKeep: dfltTidyKeep,
+		tidyMode: tidyQuery,
+		tidyR: responder.NewOrPanic(
+			"remove the old copies",
+			map[rune]string{
+				'y': "to remove the copies marked 'remove'",
+				'n': "to keep all the copies",
+			},
+			responder.SetDefault('n')),
 
 		status: Status{
 			errs: errutil.NewErrMap(),
 		},
 
-		timestamp: time.Now().Format(".20060102-150405.000"),
+		timestamp: time.Now().Format(timestampFormat),
 	}
 }
 
@@ -77,13 +148,25 @@ func newProg() *prog {
 func (prog *prog) getFileSystems() {
 	prog.createTargetFS()
 
+	var err error
+
 	if prog.fromDir != "" {
-		prog.sourceFS = os.DirFS(prog.fromDir)
+		if filecheck.DirExists().StatusCheck(prog.fromDir) == nil {
+			prog.sourceFS = os.DirFS(prog.fromDir)
+			return
+		}
+
+		prog.sourceFS, err = openArchiveFS(prog.fromDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't use the snippet source: %v\n", err)
+			os.Exit(1)
+		}
+
+		prog.fromArchive = true
+
 		return
 	}
 
-	var err error
-
 	prog.sourceFS, err = fs.Sub(snippetsDir, "_snippets")
 	if err != nil {
 		fmt.Fprintf(os.Stderr,
@@ -92,6 +175,20 @@ func (prog *prog) getFileSystems() {
 	}
 }
 
+// sourceName returns a description of the source of the snippets. This is
+// recorded in the manifest.
+func (prog *prog) sourceName() string {
+	if prog.fromDir == "" {
+		return stdSnippetSource
+	}
+
+	if dir, err := filepath.Abs(prog.fromDir); err == nil {
+		return dir
+	}
+
+	return prog.fromDir
+}
+
 // getSnippetSets populates the source and target snippet sets from the
 // corresponding file systems
 func (prog *prog) getSnippetSets() {
@@ -101,14 +198,43 @@ func (prog *prog) getSnippetSets() {
 		os.Exit(1)
 	}
 
+	if prog.filter.usesTags() {
+		dir, cleanup, err := prog.sourceDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read the snippet tags: %v\n", err)
+			os.Exit(1)
+		}
+
+		addTags(&prog.sourceSnippets, dir)
+		cleanup()
+	}
+
+	if prog.action == installAction && len(prog.selectedSourceNames()) == 0 {
+		fmt.Fprintln(os.Stderr, "None of the snippets match the filters")
+		os.Exit(1)
+	}
+
 	prog.targetSnippets = prog.getFSContent(prog.targetFS, "Snippet target")
+	if prog.filter.usesTags() {
+		addTags(&prog.targetSnippets, prog.toDir)
+	}
+
 	prog.reportSnippetCounts()
+
+	var err error
+
+	prog.manifest, err = readManifest(prog.toDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't read the manifest: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 type snippet struct {
 	content []byte
 	dirName string
 	name    string
+	tags    []string
 }
 
 type sSet struct {
@@ -140,12 +266,16 @@ type Status struct {
 	newCount         int
 	dupCount         int
 	diffCount        int
+	updatedCount     int
+	keptCount        int
+	mergedCount      int
 	clearCount       int
 	timestampedCount int
 
 	removedFiles []string
 	renamedFiles []string
 	badInstalls  []string
+	conflicts    []string
 
 	errs *errutil.ErrMap
 }
@@ -183,12 +313,32 @@ func (l Status) report(dir string) {
 		fmt.Printf("\t        New:%4d\n", l.newCount)
 		fmt.Printf("\t  Duplicate:%4d\n", l.dupCount)
 		fmt.Printf("\t    Changed:%4d\n", l.diffCount)
+		fmt.Printf("\t    Updated:%4d\n", l.updatedCount)
+		fmt.Printf("\t       Kept:%4d\n", l.keptCount)
+		fmt.Printf("\t     Merged:%4d\n", l.mergedCount)
+		fmt.Printf("\t  Conflicts:%4d\n", len(l.conflicts))
 		fmt.Printf("\tTimestamped:%4d\n", l.timestampedCount)
 		fmt.Printf("\t   Failures:%4d\n", len(l.badInstalls))
 	}
 
 	twc := twrap.NewTWConfOrPanic()
 
+	if len(l.conflicts) > 0 {
+		twc.Wrap("The following snippets have been changed both locally"+
+			" and in the new version and the changes conflict. The"+
+			" installed snippets contain both sets of changes between"+
+			" conflict markers ('"+conflictLocalMarker+"' and"+
+			" '"+conflictNewMarker+"'). You will need to edit them to"+
+			" resolve the conflicts.", 0)
+		fmt.Println()
+		fmt.Println(len(l.conflicts),
+			english.Plural("snippet", len(l.conflicts)),
+			"with conflicts")
+		fmt.Println("in", dir)
+		twc.List(l.conflicts, listItemIndent)
+		fmt.Println()
+	}
+
 	if l.clearCount > 0 {
 		fmt.Printf("Existing snippets cleared:%4d\n", l.clearCount)
 		fmt.Printf("         snippets changed:%4d\n", l.diffCount)
@@ -229,23 +379,27 @@ func (l Status) report(dir string) {
 			if l.timestampedCount > 0 {
 				twc.Wrap("\nNote that some files have a timestamped copy"+
 					" indicating that there were previous copies kept."+
-					" You should consider cleaning up these old copies.", 0)
+					" You should consider cleaning up these old copies;"+
+					" the '-"+paramNameTidy+"' parameter will help"+
+					" with this.", 0)
 			}
 		}
 	}
 }
 
-// reportErrors prints any errors
-func (l Status) reportErrors() {
+// reportErrors prints any errors. The failure description says what
+// could not be done to the failed snippets and the error intro describes
+// the action being performed.
+func (l Status) reportErrors(failureDesc, errIntro string) {
 	twc := twrap.NewTWConfOrPanic(twrap.SetWriter(os.Stderr))
 
 	if len(l.badInstalls) > 0 {
-		twc.Wrap("The following snippets could not be installed", 0)
+		twc.Wrap("The following snippets could not be "+failureDesc, 0)
 		twc.List(l.badInstalls, listItemIndent)
 	}
 
 	if l.errs.HasErrors() {
-		l.errs.Report(os.Stderr, "Installing snippets")
+		l.errs.Report(os.Stderr, errIntro)
 	}
 }
 
@@ -264,7 +418,23 @@ func main() {
 	case cmpAction:
 		prog.compareSnippets()
 	case installAction:
+		if prog.checkFirst && !prog.checkSourceSnippets() {
+			fmt.Fprintln(os.Stderr,
+				"The snippets have problems, none have been installed")
+			os.Exit(1)
+		}
+
 		prog.installSnippets()
+	case uninstallAction:
+		prog.uninstallSnippets()
+	case checkAction:
+		if !prog.checkSourceSnippets() {
+			os.Exit(1)
+		}
+	case tidyAction:
+		prog.tidyBackups()
+	case catAction:
+		prog.writeCatalogue()
 	}
 }
 
@@ -287,6 +457,20 @@ func (prog *prog) createTargetFS() {
 		return
 	}
 
+	if prog.action == uninstallAction || prog.action == tidyAction ||
+		(prog.action == catAction && prog.catalogueFrom == catFromTarget) {
+		fmt.Fprintf(os.Stderr,
+			"The target directory does not exist: %q\n", prog.toDir)
+		os.Exit(1)
+	}
+
+	if prog.dryRun {
+		reportDryRunAction(dryRunMkdir, prog.toDir)
+		prog.targetFS = fstest.MapFS{}
+
+		return
+	}
+
 	verbose.Println("creating the target directory: ", prog.toDir)
 
 	err := os.MkdirAll(prog.toDir, dfltDirPerms)
@@ -299,39 +483,16 @@ func (prog *prog) createTargetFS() {
 	prog.targetFS = os.DirFS(prog.toDir)
 }
 
-// compareSnippets compares the snippets in the from directory with those in
-// the to directory reporting any differences.
-func (prog *prog) compareSnippets() {
-	verbose.Println("comparing snippets")
-
-	for _, name := range prog.sourceSnippets.names {
-		fromS := prog.sourceSnippets.files[name]
-		if toS, ok := prog.targetSnippets.files[name]; ok {
-			if string(toS.content) == string(fromS.content) {
-				fmt.Println("Duplicate: ", name)
-			} else {
-				fmt.Println("  Differs: ", name)
-			}
-		} else {
-			fmt.Println("      New: ", name)
-		}
-	}
-
-	for _, name := range prog.targetSnippets.names {
-		if _, ok := prog.sourceSnippets.files[name]; !ok {
-			fmt.Println("    Extra: ", name)
-		}
-	}
-}
-
 // installSnippets installs the snippets from the source directory into
-// the target directory, reporting any differences.
+// the target directory, reporting any differences. Only the snippets chosen
+// by the filter are installed. In a dry run the changes that would be made
+// are reported but nothing is changed.
 func (prog *prog) installSnippets() {
 	verbose.Println("Installing snippets into ", prog.toDir)
 
 	var err error
 
-	for _, snippetName := range prog.sourceSnippets.names {
+	for _, snippetName := range prog.selectedSourceNames() {
 		verbose.Println("\tinstalling ", snippetName)
 		fromS := prog.sourceSnippets.files[snippetName]
 		toS, toFileExists := prog.targetSnippets.files[snippetName]
@@ -342,14 +503,12 @@ func (prog *prog) installSnippets() {
 			if string(toS.content) == string(fromS.content) {
 				// duplicate snippet
 				prog.status.dupCount++
+				prog.manifest.record(fromS, prog.sourceName())
+
 				continue
 			}
 			// changed snippet
-			prog.status.diffCount++
-			if prog.clearFile(snippetName, fileName) {
-				err = writeSnippet(fromS, fileName)
-				prog.status.handleErr(err, "Write failure", snippetName)
-			}
+			prog.installChangedSnippet(fromS, toS, fileName)
 
 			continue
 		}
@@ -361,14 +520,92 @@ func (prog *prog) installSnippets() {
 			continue
 		}
 
-		err = writeSnippet(fromS, fileName)
+		err = prog.writeSnippetFile(fromS, fileName, "")
 		if prog.status.handleErr(err, "Write failure", snippetName) {
 			continue
 		}
+
+		prog.manifest.record(fromS, prog.sourceName())
+	}
+
+	if prog.dryRun {
+		fmt.Println()
+		fmt.Println("This was a dry run, nothing has been changed")
+
+		return
+	}
+
+	prog.manifest.Installed = time.Now()
+
+	err = prog.manifest.write(prog.toDir)
+	if err != nil {
+		prog.status.errs.AddError("Manifest write failure", err)
 	}
 
 	prog.status.report(prog.toDir)
-	prog.status.reportErrors()
+	prog.status.reportErrors("installed", "Installing snippets")
+}
+
+// installChangedSnippet installs a snippet which differs from the one
+// already installed. The manifest records the version of the snippet which
+// was last installed (the baseline). If the installed snippet has not been
+// changed since then it is simply replaced. If only the installed snippet
+// has been changed it is kept. If both have been changed then the changes
+// are merged into the installed snippet, marking any conflicts. If there is
+// no baseline then the installed snippet is replaced.
+func (prog *prog) installChangedSnippet(fromS, toS snippet, fileName string) {
+	snippetName := fromS.name
+	base, hasBaseline := prog.manifest.baseline(snippetName)
+
+	prog.status.diffCount++
+
+	switch {
+	case !hasBaseline:
+		if prog.clearFile(snippetName, fileName) {
+			prog.writeAndRecord(fromS, fromS, fileName, "")
+		}
+	case contentHash(toS.content) == base.Hash:
+		verbose.Println("\t\tnot changed locally, updating")
+
+		prog.status.updatedCount++
+		prog.writeAndRecord(fromS, fromS, fileName, "")
+	case contentHash(fromS.content) == base.Hash:
+		verbose.Println("\t\tonly changed locally, keeping")
+
+		prog.status.keptCount++
+	default:
+		verbose.Println("\t\tchanged locally, merging")
+
+		merged, conflicts := merge3(
+			base.Content, string(toS.content), string(fromS.content))
+
+		if conflicts > 0 {
+			prog.status.conflicts = append(prog.status.conflicts, snippetName)
+		} else {
+			prog.status.mergedCount++
+		}
+
+		mergedS := fromS
+		mergedS.content = []byte(merged)
+
+		if prog.clearFile(snippetName, fileName) {
+			prog.writeAndRecord(mergedS, fromS, fileName,
+				fmt.Sprintf("(merged, %d %s)",
+					conflicts, english.Plural("conflict", conflicts)))
+		}
+	}
+}
+
+// writeAndRecord writes the snippet into the named file and, if that
+// succeeds, records the baseline snippet in the manifest. The note is
+// shown with the file name in a dry run.
+func (prog *prog) writeAndRecord(s, baseline snippet, fileName, note string) {
+	err := prog.writeSnippetFile(s, fileName, note)
+	if prog.status.handleErr(err, "Write failure", s.name) {
+		return
+	}
+
+	prog.manifest.record(baseline, prog.sourceName())
 }
 
 // makeSubDir creates the snippet's corresponding sub-directory in the target
@@ -383,6 +620,15 @@ func (prog *prog) makeSubDir(s snippet) error {
 		return nil
 	}
 
+	if prog.dryRun {
+		if !prog.dryRunDirs[subDirName] {
+			reportDryRunAction(dryRunMkdir, subDirName)
+			prog.dryRunDirs[subDirName] = true
+		}
+
+		return nil
+	}
+
 	err := os.MkdirAll(subDirName, dfltDirPerms)
 	if err == nil {
 		return nil
@@ -415,26 +661,56 @@ func (prog *prog) clearFile(snippetName, fileName string) bool {
 
 	if prog.noCopy {
 		prog.status.removedFiles = append(prog.status.removedFiles, fileName)
+
+		if prog.dryRun {
+			reportDryRunAction(dryRunRemove, fileName)
+			return true
+		}
+
 		err := os.Remove(fileName)
 
 		return !prog.status.handleErr(err, "Remove failure", snippetName)
 	}
 
 	exists := filecheck.Provisos{Existence: filecheck.MustExist}
-	copyName := fileName + ".orig"
+	copyName := fileName + backupSuffix
+
+	action := dryRunRename
 
 	if exists.StatusCheck(copyName) == nil {
 		copyName += prog.timestamp
 		prog.status.timestampedCount++
+		action = dryRunTimestamp
 	}
 
 	prog.status.renamedFiles = append(prog.status.renamedFiles, copyName)
 
+	if prog.dryRun {
+		reportDryRunAction(action, fileName+" -> "+filepath.Base(copyName))
+		return true
+	}
+
 	err := os.Rename(fileName, copyName)
 
 	return !prog.status.handleErr(err, "Rename failure", snippetName)
 }
 
+// writeSnippetFile writes the snippet into the named file. In a dry run the
+// file is not written but is reported along with the note.
+func (prog *prog) writeSnippetFile(s snippet, name, note string) error {
+	if prog.dryRun {
+		reportDryRunAction(dryRunWrite, strings.TrimSpace(name+" "+note))
+		return nil
+	}
+
+	return writeSnippet(s, name)
+}
+
+// reportDryRunAction reports the action that would have been taken
+func reportDryRunAction(action, detail string) {
+	fmt.Printf("%*s: %s\n", dryRunActionWidth, action, detail)
+}
+
 // writeSnippet creates the named file and writes the snippet into it
 func writeSnippet(s snippet, name string) error {
 	f, err := os.Create(name) //nolint:gosec
@@ -476,6 +752,10 @@ func (prog *prog) getFSContent(f fs.FS, name string) sSet {
 			continue
 		}
 
+		if strings.HasPrefix(de.Name(), manifestName) {
+			continue
+		}
+
 		err := addSnippet(f, de, []string{}, &snipSet)
 		if err != nil {
 			errs.AddError("addSnippet", err)
@@ -504,7 +784,7 @@ func readSnippet(f fs.FS, de fs.DirEntry) (snippet, error) {
 
 	s.content = make([]byte, fi.Size())
 
-	_, err = file.Read(s.content)
+	_, err = io.ReadFull(file, s.content)
 	if err != nil {
 		return s, err
 	}
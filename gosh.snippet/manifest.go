@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	manifestName = ".gosh.snippet.manifest"
+
+	manifestPerms = 0o644 // User: Read/Write, the rest: Read
+)
+
+// manifestEntry records the details of an installed snippet: where it was
+// installed from and the hash of its content. The content is the baseline
+// used when merging a new version of the snippet with any local changes.
+type manifestEntry struct {
+	Source  string `json:"source"`
+	Hash    string `json:"hash"`
+	Content string `json:"content"`
+}
+
+// manifest records the snippets installed by gosh.snippet in the target
+// directory
+type manifest struct {
+	Installed time.Time                `json:"installed"`
+	Snippets  map[string]manifestEntry `json:"snippets"`
+}
+
+// newManifest returns an empty manifest
+func newManifest() *manifest {
+	return &manifest{
+		Snippets: map[string]manifestEntry{},
+	}
+}
+
+// contentHash returns the hash of the content
+func contentHash(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+// record adds the snippet, installed from the given source, to the manifest
+func (m *manifest) record(s snippet, source string) {
+	m.Snippets[s.name] = manifestEntry{
+		Source:  source,
+		Hash:    contentHash(s.content),
+		Content: string(s.content),
+	}
+}
+
+// baseline returns the manifest entry for the named snippet and true if
+// there is one, or an empty entry and false otherwise
+func (m *manifest) baseline(name string) (manifestEntry, bool) {
+	me, ok := m.Snippets[name]
+	return me, ok
+}
+
+// remove removes the named snippet from the manifest
+func (m *manifest) remove(name string) {
+	delete(m.Snippets, name)
+}
+
+// isEmpty returns true if the manifest records no snippets
+func (m *manifest) isEmpty() bool {
+	return len(m.Snippets) == 0
+}
+
+// readManifest reads the manifest from the directory. If there is no
+// manifest an empty manifest is returned.
+func readManifest(dir string) (*manifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return newManifest(), nil
+		}
+
+		return nil, err
+	}
+
+	m := newManifest()
+	if err := json.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("bad manifest: %q: %w",
+			filepath.Join(dir, manifestName), err)
+	}
+
+	if m.Snippets == nil {
+		m.Snippets = map[string]manifestEntry{}
+	}
+
+	return m, nil
+}
+
+// write writes the manifest into the directory. It is written to a
+// temporary file first which then replaces any existing manifest. If the
+// manifest is empty any existing manifest is removed instead.
+func (m *manifest) write(dir string) error {
+	if m.isEmpty() {
+		err := os.Remove(filepath.Join(dir, manifestName))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, manifestName+".*")
+	if err != nil {
+		return err
+	}
+
+	tmpName := f.Name()
+
+	_, err = f.Write(append(content, '\n'))
+	err = errors.Join(err, f.Close())
+
+	if err == nil {
+		err = os.Chmod(tmpName, manifestPerms)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpName, filepath.Join(dir, manifestName))
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+
+	return err
+}
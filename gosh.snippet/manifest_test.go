@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := readManifest(dir)
+	if err != nil {
+		t.Fatal("a missing manifest should not be an error: ", err)
+	}
+
+	testhelper.DiffInt(t, "missing manifest", "snippet count",
+		len(m.Snippets), 0)
+
+	s := snippet{name: filepath.Join("dir", "snip"), content: []byte("x\n")}
+	m.record(s, "src")
+
+	if err := m.write(dir); err != nil {
+		t.Fatal("cannot write the manifest: ", err)
+	}
+
+	m, err = readManifest(dir)
+	if err != nil {
+		t.Fatal("cannot read the manifest: ", err)
+	}
+
+	me, ok := m.baseline(s.name)
+	if !ok {
+		t.Fatal("the snippet is not in the manifest")
+	}
+
+	testhelper.DiffString(t, "written manifest", "source",
+		me.Source, "src")
+	testhelper.DiffString(t, "written manifest", "hash",
+		me.Hash, contentHash(s.content))
+	testhelper.DiffString(t, "written manifest", "content",
+		me.Content, string(s.content))
+
+	m.remove(s.name)
+
+	if err := m.write(dir); err != nil {
+		t.Fatal("cannot write the empty manifest: ", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, manifestName)); err == nil {
+		t.Error("writing an empty manifest should remove the manifest")
+	}
+
+	err = os.WriteFile(filepath.Join(dir, manifestName), []byte("{"), 0o600)
+	if err != nil {
+		t.Fatal("cannot write the bad manifest: ", err)
+	}
+
+	if _, err := readManifest(dir); err == nil {
+		t.Error("a bad manifest should be an error")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/nickwells/utilities/internal/textdiff"
+)
+
+const (
+	conflictLocalMarker    = "<<<<<<< installed"
+	conflictBaselineMarker = "||||||| baseline"
+	conflictSepMarker      = "======="
+	conflictNewMarker      = ">>>>>>> new"
+)
+
+// lcsMatches returns a map from the index of each line in a to the index of
+// the matching line in b for the lines in the longest common subsequence of
+// a and b.
+func lcsMatches(a, b []string) map[int]int {
+	lcsLen := make([][]int, len(a)+1)
+	for i := range lcsLen {
+		lcsLen[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcsLen[i][j] = lcsLen[i+1][j+1] + 1
+			} else {
+				lcsLen[i][j] = max(lcsLen[i+1][j], lcsLen[i][j+1])
+			}
+		}
+	}
+
+	matches := map[int]int{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			matches[i] = j
+			i++
+			j++
+		case lcsLen[i+1][j] >= lcsLen[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return matches
+}
+
+// merge3 performs a three-way merge of the lines of the installed and new
+// versions of a snippet, both of which are derived from the baseline. Any
+// change made in only one of the versions is applied. Where the two
+// versions make different changes to the same part of the baseline the
+// merged text will include both sets of changes surrounded by conflict
+// markers. It returns the merged text and the number of conflicts.
+func merge3(baseline, installed, newVsn string) (string, int) {
+	base := textdiff.SplitLines(baseline)
+	local := textdiff.SplitLines(installed)
+	upstream := textdiff.SplitLines(newVsn)
+
+	localMatches := lcsMatches(base, local)
+	upstreamMatches := lcsMatches(base, upstream)
+
+	var merged strings.Builder
+
+	conflicts := 0
+
+	i, j, k := 0, 0, 0
+	for i < len(base) || j < len(local) || k < len(upstream) {
+		if i < len(base) {
+			lj, lok := localMatches[i]
+			uk, uok := upstreamMatches[i]
+
+			if lok && uok && lj == j && uk == k {
+				merged.WriteString(base[i])
+
+				i, j, k = i+1, j+1, k+1
+
+				continue
+			}
+		}
+
+		o, jEnd, kEnd := nextStableLine(i, base, localMatches, upstreamMatches)
+		if o == len(base) {
+			jEnd, kEnd = len(local), len(upstream)
+		}
+
+		baseChunk := base[i:o]
+		localChunk := local[j:jEnd]
+		upstreamChunk := upstream[k:kEnd]
+
+		switch {
+		case slices.Equal(localChunk, baseChunk):
+			writeLines(&merged, upstreamChunk)
+		case slices.Equal(upstreamChunk, baseChunk),
+			slices.Equal(localChunk, upstreamChunk):
+			writeLines(&merged, localChunk)
+		default:
+			conflicts++
+
+			writeConflict(&merged, baseChunk, localChunk, upstreamChunk)
+		}
+
+		i, j, k = o, jEnd, kEnd
+	}
+
+	return merged.String(), conflicts
+}
+
+// nextStableLine returns the index of the next line in the baseline, at or
+// after i, which is matched in both the other versions together with the
+// indexes of the matching lines. If there is no such line it returns the
+// length of the baseline.
+func nextStableLine(i int, base []string,
+	localMatches, upstreamMatches map[int]int,
+) (int, int, int) {
+	for o := i; o < len(base); o++ {
+		lj, lok := localMatches[o]
+		uk, uok := upstreamMatches[o]
+
+		if lok && uok {
+			return o, lj, uk
+		}
+	}
+
+	return len(base), 0, 0
+}
+
+// writeLines writes the lines to the builder
+func writeLines(b *strings.Builder, lines []string) {
+	for _, l := range lines {
+		b.WriteString(l)
+	}
+}
+
+// writeConflict writes the conflicting chunks to the builder surrounded by
+// conflict markers. The lines are terminated with a newline so that each
+// marker starts on a new line.
+func writeConflict(b *strings.Builder, base, local, upstream []string) {
+	writeSection := func(marker string, lines []string) {
+		b.WriteString(marker + "\n")
+
+		for _, l := range lines {
+			b.WriteString(l)
+
+			if !strings.HasSuffix(l, "\n") {
+				b.WriteString("\n")
+			}
+		}
+	}
+
+	writeSection(conflictLocalMarker, local)
+	writeSection(conflictBaselineMarker, base)
+	writeSection(conflictSepMarker, upstream)
+	b.WriteString(conflictNewMarker + "\n")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestMerge3(t *testing.T) {
+	const base = "a\nb\nc\nd\ne\n"
+
+	testCases := []struct {
+		testhelper.ID
+		installed    string
+		newVsn       string
+		expMerged    string
+		expConflicts int
+	}{
+		{
+			ID:        testhelper.MkID("no changes"),
+			installed: base,
+			newVsn:    base,
+			expMerged: base,
+		},
+		{
+			ID:        testhelper.MkID("only local changes"),
+			installed: "a\nB\nc\nd\ne\n",
+			newVsn:    base,
+			expMerged: "a\nB\nc\nd\ne\n",
+		},
+		{
+			ID:        testhelper.MkID("only new changes"),
+			installed: base,
+			newVsn:    "a\nb\nc\nd\ne\nf\n",
+			expMerged: "a\nb\nc\nd\ne\nf\n",
+		},
+		{
+			ID:        testhelper.MkID("separate changes"),
+			installed: "x\na\nB\nc\nd\ne\n",
+			newVsn:    "a\nb\nc\ne\nf\n",
+			expMerged: "x\na\nB\nc\ne\nf\n",
+		},
+		{
+			ID:        testhelper.MkID("same change"),
+			installed: "a\nb\nC\nd\ne\n",
+			newVsn:    "a\nb\nC\nd\ne\n",
+			expMerged: "a\nb\nC\nd\ne\n",
+		},
+		{
+			ID:        testhelper.MkID("conflicting changes"),
+			installed: "a\nb\nL\nd\ne\n",
+			newVsn:    "a\nb\nN\nd\ne",
+			expMerged: "a\nb\n" +
+				conflictLocalMarker + "\n" +
+				"L\n" +
+				conflictBaselineMarker + "\n" +
+				"c\n" +
+				conflictSepMarker + "\n" +
+				"N\n" +
+				conflictNewMarker + "\n" +
+				"d\ne",
+			expConflicts: 1,
+		},
+		{
+			ID:        testhelper.MkID("conflict at the end"),
+			installed: "a\nb\nc\nd\ne\nL",
+			newVsn:    "a\nb\nc\nd\ne\nN",
+			expMerged: "a\nb\nc\nd\ne\n" +
+				conflictLocalMarker + "\n" +
+				"L\n" +
+				conflictBaselineMarker + "\n" +
+				conflictSepMarker + "\n" +
+				"N\n" +
+				conflictNewMarker + "\n",
+			expConflicts: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		merged, conflicts := merge3(base, tc.installed, tc.newVsn)
+		testhelper.DiffString(t, tc.IDStr(), "merged text",
+			merged, tc.expMerged)
+		testhelper.DiffInt(t, tc.IDStr(), "conflicts",
+			conflicts, tc.expConflicts)
+	}
+}
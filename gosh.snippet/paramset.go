@@ -20,10 +20,46 @@ func makeParamSet(prog *prog) *param.PSet {
 		param.SetProgramDescription(
 			"This can install the standard collection of useful snippets."+
 				" It can also be used to install snippets from a"+
-				" directory or to compare two collections of snippets."+
+				" directory or an archive (a gzipped tar file, a zip"+
+				" file or a git bundle) or to compare two collections"+
+				" of snippets."+
 				"\n\n"+
 				"The default behaviour is to compare the"+
 				" standard collection of snippets with those"+
-				" in the given target directory."),
+				" in the given target directory."+
+				"\n\n"+
+				"When snippets are installed a manifest is written into"+
+				" the target directory recording the installed version"+
+				" of each snippet. When a new version of a snippet is"+
+				" installed this is used to find any local changes"+
+				" made to the installed snippet. Local changes are"+
+				" merged with the changes in the new version and any"+
+				" conflicting changes are marked and reported."+
+				"\n\n"+
+				"The manifest is also used to show whether snippets"+
+				" have changed locally or in the source when comparing"+
+				" snippets and to remove only those snippets which"+
+				" were installed by this program when uninstalling."+
+				"\n\n"+
+				"The snippets to be compared or installed can be"+
+				" chosen by name, by sub-directory or by the tags in"+
+				" their snippet comments. An installation can be"+
+				" tried out first, showing what would be done"+
+				" without changing anything."+
+				"\n\n"+
+				"The old copies of snippets, kept when installed"+
+				" snippets are replaced, can be tidied up, removing"+
+				" all but the newest copies of each snippet."+
+				"\n\n"+
+				"A Markdown catalogue of the snippets can be written,"+
+				" giving an overview of the available snippets."+
+				"\n\n"+
+				"The snippets can be checked, this will report any"+
+				" problems with the snippet comments, any imported"+
+				" packages which cannot be found, any snippets which"+
+				" are expected or followed but are missing and any"+
+				" errors in the code. The snippets can also be checked"+
+				" before they are installed, in which case none are"+
+				" installed if any have problems."),
 	)
 }
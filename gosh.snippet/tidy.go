@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// The values of the tidy-mode parameter
+const (
+	tidyQuery  = "query"
+	tidyDelete = "delete"
+	tidyList   = "list"
+)
+
+const (
+	dfltTidyKeep = 1
+	hoursPerDay  = 24
+)
+
+// backupCopy records a timestamped copy of a snippet
+type backupCopy struct {
+	name    string
+	snippet string
+	made    time.Time
+	remove  bool
+}
+
+// backupCopyOf returns the name of the snippet that the file is a
+// timestamped copy of and the time the copy was made. If the file is not a
+// timestamped copy then false is returned.
+func backupCopyOf(name string) (string, time.Time, bool) {
+	if len(name) <= timestampLen+len(backupSuffix) {
+		return "", time.Time{}, false
+	}
+
+	ts := name[len(name)-timestampLen:]
+
+	made, err := time.ParseInLocation(timestampFormat, ts, time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	snippetName, ok := strings.CutSuffix(name[:len(name)-timestampLen],
+		backupSuffix)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	return snippetName, made, true
+}
+
+// findBackupCopies returns the timestamped copies of snippets in the file
+// system
+func findBackupCopies(fsys fs.FS) ([]backupCopy, error) {
+	copies := []backupCopy{}
+
+	err := fs.WalkDir(fsys, ".",
+		func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if de.IsDir() {
+				return nil
+			}
+
+			if snippetName, made, ok := backupCopyOf(path); ok {
+				copies = append(copies, backupCopy{
+					name:    filepath.FromSlash(path),
+					snippet: filepath.FromSlash(snippetName),
+					made:    made,
+				})
+			}
+
+			return nil
+		})
+
+	return copies, err
+}
+
+// planTidy marks the copies to be removed. The copies of each snippet are
+// sorted, newest first, and all but the newest keep copies are removed. If
+// maxAge is greater than zero then only those copies older than that are
+// removed. The copies are returned sorted by snippet name.
+func planTidy(copies []backupCopy, keep int, maxAge time.Duration,
+	now time.Time,
+) []backupCopy {
+	slices.SortFunc(copies, func(a, b backupCopy) int {
+		if c := strings.Compare(a.snippet, b.snippet); c != 0 {
+			return c
+		}
+
+		return b.made.Compare(a.made)
+	})
+
+	count := 0
+
+	for i := range copies {
+		if i == 0 || copies[i].snippet != copies[i-1].snippet {
+			count = 0
+		}
+
+		count++
+
+		copies[i].remove = count > keep &&
+			(maxAge <= 0 || now.Sub(copies[i].made) > maxAge)
+	}
+
+	return copies
+}
+
+// tidyBackups finds the timestamped copies of the snippets in the target
+// directory and removes the older ones. The copies are listed, grouped by
+// snippet, showing which are to be removed; depending on the tidy mode the
+// user is asked before they are removed.
+func (prog *prog) tidyBackups() {
+	verbose.Println("Tidying the copies of snippets in ", prog.toDir)
+
+	copies, err := findBackupCopies(prog.targetFS)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Can't find the copies of the snippets: %v\n",
+			err)
+		os.Exit(1)
+	}
+
+	copies = planTidy(copies, int(prog.tidyKeep),
+		time.Duration(prog.tidyMaxAgeDays)*hoursPerDay*time.Hour,
+		time.Now())
+
+	toRemove := []string{}
+
+	for _, bc := range copies {
+		if bc.remove {
+			toRemove = append(toRemove, bc.name)
+		}
+	}
+
+	reportBackupCopies(prog.toDir, copies, len(toRemove))
+
+	if len(toRemove) == 0 {
+		return
+	}
+
+	switch prog.tidyMode {
+	case tidyList:
+		return
+	case tidyQuery:
+		response := prog.tidyR.GetResponseOrDie()
+
+		fmt.Println()
+
+		if response != 'y' {
+			return
+		}
+	}
+
+	removed := 0
+
+	for _, name := range toRemove {
+		verbose.Println("\tremoving ", name)
+
+		err := os.Remove(filepath.Join(prog.toDir, name))
+		if prog.status.handleErr(err, "Remove failure", name) {
+			continue
+		}
+
+		removed++
+	}
+
+	fmt.Println(removed, english.Plural("copy", removed), "removed")
+	prog.status.reportErrors("removed", "Tidying the copies of snippets")
+}
+
+// reportBackupCopies lists the copies, grouped by snippet, showing whether
+// each copy is to be kept or removed
+func reportBackupCopies(dir string, copies []backupCopy, removeCount int) {
+	fmt.Println(len(copies), english.Plural("timestamped copy", len(copies)),
+		"found in", dir)
+
+	for i, bc := range copies {
+		if i == 0 || bc.snippet != copies[i-1].snippet {
+			fmt.Println(bc.snippet)
+		}
+
+		action := "keep"
+		if bc.remove {
+			action = "remove"
+		}
+
+		fmt.Printf("%*s%-6s %s\n",
+			listItemIndent, "", action, filepath.Base(bc.name))
+	}
+
+	fmt.Println(removeCount, english.Plural("copy", removeCount),
+		"to be removed")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestBackupCopyOf(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		name       string
+		expOK      bool
+		expSnippet string
+		expMade    time.Time
+	}{
+		{
+			ID:         testhelper.MkID("timestamped copy"),
+			name:       "timer/1-init.orig.20260102-030405.678",
+			expOK:      true,
+			expSnippet: "timer/1-init",
+			expMade: time.Date(2026, 1, 2, 3, 4, 5, 678*int(time.Millisecond),
+				time.Local),
+		},
+		{
+			ID:   testhelper.MkID("copy without a timestamp"),
+			name: "timer/1-init.orig",
+		},
+		{
+			ID:   testhelper.MkID("timestamp without the backup suffix"),
+			name: "timer/1-init.20260102-030405.678",
+		},
+		{
+			ID:   testhelper.MkID("bad timestamp"),
+			name: "abort.orig.20261302-030405.678",
+		},
+		{
+			ID:   testhelper.MkID("snippet"),
+			name: "abort",
+		},
+	}
+
+	for _, tc := range testCases {
+		snippetName, made, ok := backupCopyOf(tc.name)
+		if ok != tc.expOK {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: expected ok: %t, got: %t", tc.expOK, ok)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "snippet name",
+			snippetName, tc.expSnippet)
+
+		if !made.Equal(tc.expMade) {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: expected time: %s, got: %s", tc.expMade, made)
+		}
+	}
+}
+
+func TestPlanTidy(t *testing.T) {
+	now := time.Date(2026, 6, 30, 12, 0, 0, 0, time.Local)
+	fsys := fstest.MapFS{}
+
+	for _, name := range []string{
+		"a.orig.20260629-120000.000",
+		"a.orig.20260601-120000.000",
+		"a.orig.20260501-120000.000",
+		"a.orig",
+		"a",
+		"dir/b.orig.20260401-120000.000",
+		"dir/b.orig.20260620-120000.000",
+		".gosh.snippet.manifest",
+	} {
+		fsys[name] = &fstest.MapFile{}
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		keep      int
+		maxAge    time.Duration
+		expRemove []string
+	}{
+		{
+			ID:   testhelper.MkID("keep 1"),
+			keep: 1,
+			expRemove: []string{
+				"a.orig.20260601-120000.000",
+				"a.orig.20260501-120000.000",
+				"dir/b.orig.20260401-120000.000",
+			},
+		},
+		{
+			ID:        testhelper.MkID("keep 2"),
+			keep:      2,
+			expRemove: []string{"a.orig.20260501-120000.000"},
+		},
+		{
+			ID:     testhelper.MkID("keep 0, older than 35 days"),
+			maxAge: 35 * hoursPerDay * time.Hour,
+			expRemove: []string{
+				"a.orig.20260501-120000.000",
+				"dir/b.orig.20260401-120000.000",
+			},
+		},
+		{
+			ID:     testhelper.MkID("keep 1, older than 40 days"),
+			keep:   1,
+			maxAge: 40 * hoursPerDay * time.Hour,
+			expRemove: []string{
+				"a.orig.20260501-120000.000",
+				"dir/b.orig.20260401-120000.000",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		copies, err := findBackupCopies(fsys)
+		if err != nil {
+			t.Fatal("cannot find the copies: ", err)
+		}
+
+		testhelper.DiffInt(t, tc.IDStr(), "copies found", len(copies), 5)
+
+		remove := []string{}
+
+		for _, bc := range planTidy(copies, tc.keep, tc.maxAge, now) {
+			if bc.remove {
+				remove = append(remove, bc.name)
+			}
+		}
+
+		testhelper.DiffStringSlice(t, tc.IDStr(), "copies to remove",
+			remove, tc.expRemove)
+	}
+}
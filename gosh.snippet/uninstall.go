@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/nickwells/english.mod/english"
+	"github.com/nickwells/twrap.mod/twrap"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// uninstallSnippets removes the snippets recorded in the manifest from the
+// target directory. Snippets which have been changed since they were
+// installed are kept, as are any snippets which were not installed by
+// gosh.snippet. Any sub-directories left empty are also removed.
+func (prog *prog) uninstallSnippets() {
+	verbose.Println("Uninstalling snippets from ", prog.toDir)
+
+	if prog.manifest.isEmpty() {
+		fmt.Fprintln(os.Stderr,
+			"There is no record of any snippets installed in", prog.toDir)
+		os.Exit(1)
+	}
+
+	removed := []string{}
+	kept := []string{}
+	dirs := map[string]bool{}
+
+	for _, name := range slices.Sorted(maps.Keys(prog.manifest.Snippets)) {
+		toS, ok := prog.targetSnippets.files[name]
+		if !ok {
+			verbose.Println("\talready removed ", name)
+			prog.manifest.remove(name)
+			addParentDirs(dirs, name)
+
+			continue
+		}
+
+		if contentHash(toS.content) != prog.manifest.Snippets[name].Hash {
+			verbose.Println("\tchanged locally, keeping ", name)
+
+			kept = append(kept, name)
+
+			continue
+		}
+
+		verbose.Println("\tremoving ", name)
+
+		err := os.Remove(filepath.Join(prog.toDir, name))
+		if prog.status.handleErr(err, "Remove failure", name) {
+			continue
+		}
+
+		removed = append(removed, name)
+		prog.manifest.remove(name)
+		addParentDirs(dirs, name)
+	}
+
+	prog.removeEmptyDirs(dirs)
+
+	err := prog.manifest.write(prog.toDir)
+	if err != nil {
+		prog.status.errs.AddError("Manifest write failure", err)
+	}
+
+	reportUninstall(prog.toDir, removed, kept)
+	prog.status.reportErrors("uninstalled", "Uninstalling snippets")
+}
+
+// addParentDirs adds the directories containing the named snippet to the
+// set of directories
+func addParentDirs(dirs map[string]bool, name string) {
+	for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+		dirs[dir] = true
+	}
+}
+
+// removeEmptyDirs removes those of the given sub-directories of the target
+// directory which are empty. The deepest directories are removed first so
+// that a directory which only held empty directories is also removed.
+func (prog *prog) removeEmptyDirs(dirs map[string]bool) {
+	names := slices.Collect(maps.Keys(dirs))
+	slices.SortFunc(names, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	for _, dir := range names {
+		path := filepath.Join(prog.toDir, dir)
+
+		entries, err := os.ReadDir(path)
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && len(entries) > 0) {
+			continue
+		}
+
+		if err == nil {
+			verbose.Println("\tremoving the empty directory ", dir)
+			err = os.Remove(path)
+		}
+
+		if err != nil {
+			prog.status.errs.AddError("Directory removal failure", err)
+		}
+	}
+}
+
+// reportUninstall reports the snippets which were removed and those which
+// were kept because they had been changed
+func reportUninstall(dir string, removed, kept []string) {
+	twc := twrap.NewTWConfOrPanic()
+
+	fmt.Println(len(removed),
+		english.Plural("snippet", len(removed)),
+		"removed")
+
+	if verbose.IsOn() && len(removed) > 0 {
+		fmt.Println("from", dir)
+		twc.List(removed, listItemIndent)
+	}
+
+	if len(kept) > 0 {
+		fmt.Println()
+		twc.Wrap("The following snippets were installed by gosh.snippet"+
+			" but have been changed since then and so have been kept."+
+			" You should check whether you still need them and remove"+
+			" them if not.", 0)
+		fmt.Println()
+		fmt.Println(len(kept),
+			english.Plural("snippet", len(kept)),
+			"kept")
+		fmt.Println("in", dir)
+		twc.List(kept, listItemIndent)
+	}
+}
@@ -114,5 +114,59 @@ func addExamples(ps *param.PSet) error {
 		` -snippet-list-constraint iferr`,
 		"This will list just the text of the iferr snippet.")
 
+	ps.AddExample(`gosh`+
+		` -e 'fmt.Println(strings.ToUpper(_l.Text()))'`+
+		` -n -dont-exec`+
+		` -snippet-save upper`+
+		` -snippet-save-doc 'print each line in upper case'`,
+		"This will save the code given for the exec section as a"+
+			" snippet called 'upper' in the first snippets directory."+
+			" The snippet will import the 'fmt' and 'strings' packages.")
+
+	ps.AddExample("gosh -profile csv -profile sum -- data.csv",
+		"This applies the parameters in the 'csv' profile and then"+
+			" those in the 'sum' profile before running the program"+
+			" over the file 'data.csv'. The 'csv' profile might set"+
+			" up a readloop splitting lines on commas and the 'sum'"+
+			" profile might add code to total a column.")
+
+	ps.AddExample(`gosh -n -g 'var n int' -e 'n++'`+
+		` -e-tmpl '{{._fl}}: {{._line}}{{"\n"}}'`+
+		` -a-tmpl 'lines: {{.n}}{{"\n"}}' -template-var n`+
+		` -- file.txt`,
+		"This prints each line of the file preceded by its line"+
+			" number and then prints the number of lines. The"+
+			" templates are applied at the end of the exec and"+
+			" after sections and the global variable 'n' is made"+
+			" available to them.")
+
+	ps.AddExample(`gosh -n -fb-pf '"==> %s <==\n", _fn'`+
+		` -fe-pf '"%d lines\n", _fl' -pln '_l.Text()' -- a.txt b.txt`,
+		"This prints each file with a header giving the file name"+
+			" and a footer giving the number of lines in the file.")
+
+	ps.AddExample(`cat prog.go data.txt | gosh -n -exec-stdin -stdin-data`,
+		"This reads the code from standard input up to a line holding"+
+			" just '"+dfltStdinDataMarker+"' (which should be the"+
+			" last line of 'prog.go') and then runs the code in a"+
+			" readloop over the rest of standard input, the contents"+
+			" of 'data.txt'.")
+
+	ps.AddExample(`gosh -history-search 'strings\.Split'`,
+		"This lists the previous invocations of gosh which used"+
+			" strings.Split. Each entry is shown with its index"+
+			" which can be given to the "+paramNameHistoryRun+
+			" or "+paramNameHistoryEdit+" parameters to run it again.")
+
+	ps.AddExample(`gosh -history-edit -1`,
+		"This runs the most recent invocation of gosh again but lets"+
+			" you edit the program before it is run.")
+
+	ps.AddExample(`gosh -pre-check-json |`+
+		` jq -r '.results[] | select(.ok | not) | .name'`,
+		"This checks that gosh is correctly installed and lists the"+
+			" names of any checks which failed. The exit status will"+
+			" be non-zero if any check failed.")
+
 	return nil
 }
@@ -203,7 +203,9 @@ func addNotes(ps *param.PSet) error {
 			globalSect+"       - code at global scope, outside of main\n"+
 			beforeSect+"       - code at the start of the program\n"+
 			beforeInnerSect+" - code before any inner loop\n"+
+			fileBeginSect+"   - code at the start of each file\n"+
 			execSect+"         - code, maybe in a readloop/web handler\n"+
+			fileEndSect+"     - code at the end of each file\n"+
 			afterInnerSect+"  - code after any inner loop\n"+
 			afterSect+"        - code at the end of the program"+
 			"\n\n"+
@@ -212,7 +214,15 @@ func addNotes(ps *param.PSet) error {
 			" reading each one. Otherwise they just appear immediately"+
 			" before or after their corresponding sections. "+
 			beforeInnerSect+" appears after "+beforeSect+
-			" and "+afterInnerSect+" appears before "+afterSect)
+			" and "+afterInnerSect+" appears before "+afterSect+
+			"\n\n"+
+			"The "+fileBeginSect+" and "+fileEndSect+" sections are"+
+			" only allowed in a readloop. They are run once for each"+
+			" file read (or once for standard input) with '_fn' set"+
+			" to the file name. The "+fileBeginSect+" section is run"+
+			" before the first line is read and the "+fileEndSect+
+			" section after the last line, when '_fl' holds the"+
+			" number of lines in the file.")
 
 	ps.AddNote(noteShebangScripts,
 		"You can use gosh in shebang scripts (executable files"+
@@ -332,7 +342,11 @@ func addNotes(ps *param.PSet) error {
 			" that some other gosh stage has failed"+
 			"\n"+
 			"- "+strconv.Itoa(goshExitStatusRunFail)+": indicates"+
-			" that the built executable could not be run")
+			" that the built executable could not be run"+
+			"\n"+
+			"- "+strconv.Itoa(goshExitStatusVetFail)+": indicates"+
+			" that 'go vet' (run when gosh is passed"+
+			" the '"+paramNameVet+"' parameter) has reported problems")
 
 	return nil
 }
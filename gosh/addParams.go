@@ -40,6 +40,7 @@ const (
 	paramNameWorkspaceUse        = "workspace-use"
 	paramNameIgnoreGoModTidyErrs = "go-mod-tidy-ignore-errors"
 	paramNameDontRunGoModTidy    = "go-mod-tidy-dont-run"
+	paramNameOffline             = "offline"
 
 	paramNameFormat        = "format"
 	paramNameFormatter     = "formatter"
@@ -57,12 +58,20 @@ const (
 	envVisual             = "VISUAL"
 	envEditor             = "EDITOR"
 
-	paramNameInPlaceEdit  = "in-place-edit"
-	paramNameReadloop     = "run-in-readloop"
-	paramNameSplitLine    = "split-line"
-	paramNameSplitPattern = "split-pattern"
+	paramNameInPlaceEdit    = "in-place-edit"
+	paramNameInPlaceEditTxn = "in-place-edit-all-or-nothing"
+	paramNameReadloop       = "run-in-readloop"
+	paramNameSplitLine      = "split-line"
+	paramNameSplitPattern   = "split-pattern"
 
-	paramNamePreCheck = "pre-check"
+	paramNameBaseTempDir = "base-temp-dir"
+
+	paramNamePreCheck        = "pre-check"
+	paramNamePreCheckJSON    = "pre-check-json"
+	paramNamePreCheckJSONAlt = "pre-check-as-json"
+
+	paramNameVet     = "vet"
+	paramNameVetArgs = "vet-arg"
 
 	paramNameShowFilename = "show-filename"
 
@@ -80,6 +89,8 @@ const (
 	paramNameExecStdin        = "exec-stdin"
 	paramNameAfterInnerStdin  = "after-inner-stdin"
 	paramNameAfterStdin       = "after-stdin"
+	paramNameStdinData        = "stdin-data"
+	paramNameStdinDataMarker  = "stdin-data-marker"
 )
 
 var stdinParamNames = []string{
@@ -89,6 +100,8 @@ var stdinParamNames = []string{
 	paramNameExecStdin,
 	paramNameAfterInnerStdin,
 	paramNameAfterStdin,
+	paramNameStdinData,
+	paramNameStdinDataMarker,
 }
 
 var readloopParamNames = []string{
@@ -96,6 +109,7 @@ var readloopParamNames = []string{
 	paramNameSplitLine,
 	paramNameSplitPattern,
 	paramNameInPlaceEdit,
+	paramNameInPlaceEditTxn,
 }
 
 var fileParamNames = []string{
@@ -183,13 +197,13 @@ func makeShebangFileHelpText(sect string) string {
 // PAF is being generated not at the point where the parameter value is
 // given.
 func snippetPAF(g *gosh, sName *string, scriptName string) param.ActionFunc {
-	return func(_ location.L, _ *param.BaseParam, _ []string) error {
+	return func(loc location.L, _ *param.BaseParam, _ []string) error {
 		err := g.CacheSnippet(*sName)
 		if err != nil {
 			return err
 		}
 
-		g.AddScriptEntry(scriptName, *sName, snippetExpand)
+		g.AddScriptEntry(scriptName, *sName, snippetExpand, loc.String())
 
 		return nil
 	}
@@ -203,8 +217,8 @@ func snippetPAF(g *gosh, sName *string, scriptName string) param.ActionFunc {
 // the PAF is being generated not at the point where the parameter value is
 // given.
 func scriptPAF(g *gosh, text *string, scriptName string) param.ActionFunc {
-	return func(_ location.L, _ *param.BaseParam, _ []string) error {
-		g.AddScriptEntry(scriptName, *text, verbatim)
+	return func(loc location.L, _ *param.BaseParam, _ []string) error {
+		g.AddScriptEntry(scriptName, *text, verbatim, loc.String())
 		return nil
 	}
 }
@@ -212,8 +226,8 @@ func scriptPAF(g *gosh, text *string, scriptName string) param.ActionFunc {
 // stdinPAF generates the Post-Action func (PAF) that reads from os.Stdin and
 // adds the resulting text into the named script.
 func stdinPAF(g *gosh, scriptName string) param.ActionFunc {
-	return func(_ location.L, _ *param.BaseParam, _ []string) error {
-		g.AddScriptEntry(scriptName, "", readFromStdin)
+	return func(loc location.L, _ *param.BaseParam, _ []string) error {
+		g.AddScriptEntry(scriptName, "", readFromStdin, loc.String())
 		return nil
 	}
 }
@@ -255,7 +269,7 @@ func shebangFilePAF(g *gosh, text *string, scriptName string) param.ActionFunc {
 			return err
 		}
 
-		g.AddScriptEntry(scriptName, string(script), verbatim)
+		g.AddScriptEntry(scriptName, string(script), verbatim, loc.String())
 
 		if len(config) != 0 {
 			return parseShebangConfig(loc, p, config)
@@ -274,13 +288,13 @@ func shebangFilePAF(g *gosh, text *string, scriptName string) param.ActionFunc {
 // the PAF is being generated not at the point where the parameter value is
 // given.
 func packageFilePAF(g *gosh, text *string, scriptName string) param.ActionFunc {
-	return func(_ location.L, _ *param.BaseParam, _ []string) error {
+	return func(loc location.L, _ *param.BaseParam, _ []string) error {
 		contents, err := packageFileContents(*text)
 		if err != nil {
 			return err
 		}
 
-		g.AddScriptEntry(scriptName, contents, verbatim)
+		g.AddScriptEntry(scriptName, contents, verbatim, loc.String())
 
 		return nil
 	}
@@ -418,6 +432,28 @@ func addSnippetParams(g *gosh) func(ps *param.PSet) error {
 			param.PostAction(snippetPAF(g, &snippetName, afterInnerSect)),
 		)
 
+		ps.Add("file-begin-snippet",
+			psetter.String[string]{
+				Value:  &snippetName,
+				Checks: []check.String{checkStringNotEmpty},
+			},
+			makeSnippetHelpText(fileBeginSect),
+			param.AltNames("fb-s", "fbs"),
+			param.ValueName("filename"),
+			param.PostAction(snippetPAF(g, &snippetName, fileBeginSect)),
+		)
+
+		ps.Add("file-end-snippet",
+			psetter.String[string]{
+				Value:  &snippetName,
+				Checks: []check.String{checkStringNotEmpty},
+			},
+			makeSnippetHelpText(fileEndSect),
+			param.AltNames("fe-s", "fes"),
+			param.ValueName("filename"),
+			param.PostAction(snippetPAF(g, &snippetName, fileEndSect)),
+		)
+
 		ps.Add("after-snippet",
 			psetter.String[string]{
 				Value:  &snippetName,
@@ -630,6 +666,32 @@ func addReadloopParams(g *gosh) func(ps *param.PSet) error {
 			),
 		)
 
+		g.runInReadloopSetters = append(g.runInReadloopSetters,
+			ps.Add(paramNameInPlaceEditTxn,
+				psetter.Bool{Value: &g.inPlaceEditTxn},
+				"edit the files in place as for the '"+
+					paramNameInPlaceEdit+"' parameter but only replace"+
+					" the files if every file has been processed"+
+					" successfully. Each new file is written to a"+
+					" temporary file and only when all the files have"+
+					" been read are they renamed into place. If any"+
+					" file cannot be read or written then no file is"+
+					" changed. If any of the renames fail then the"+
+					" files already replaced are restored from their"+
+					" '"+origExt+"' copies. In either case the program"+
+					" reports what has happened and exits with a"+
+					" non-zero status."+
+					"\n\n"+
+					"Your code can abandon the edit by setting"+
+					" '_ipeFailed' to true.",
+				param.AltNames("in-place-edit-txn", "i-all"),
+				param.PostAction(paction.SetVal(&g.runInReadLoop, true)),
+				param.PostAction(paction.SetVal(&g.inPlaceEdit, true)),
+				param.GroupName(paramGroupNameReadloop),
+				param.SeeAlso(paramNameInPlaceEdit, paramNameWPrint),
+			),
+		)
+
 		writeToIPEFile := ps.Add(paramNameWPrint,
 			psetter.String[string]{
 				Value: &codeVal,
@@ -657,6 +719,16 @@ func addReadloopParams(g *gosh) func(ps *param.PSet) error {
 					"-"+paramNameInPlaceEdit, ps.TerminalParam())
 			}
 
+			for _, sect := range []string{fileBeginSect, fileEndSect} {
+				if len(g.scripts[sect]) > 0 && !g.runInReadLoop {
+					return fmt.Errorf(
+						"code has been given for the %q section but"+
+							" this is only run in a readloop (give the"+
+							" %q parameter)",
+						sect, "-"+paramNameReadloop)
+				}
+			}
+
 			if writeToIPEFile.HasBeenSet() && !g.inPlaceEdit {
 				return fmt.Errorf(
 					"you are writing to the file used when in-place editing"+
@@ -738,6 +810,42 @@ func addStdinParams(g *gosh) func(ps *param.PSet) error {
 			)
 		}
 
+		const stdinDataNote = "\n\n" +
+			"The code is read from standard input up to the marker" +
+			" line and the rest of standard input is passed to the" +
+			" generated program. This lets a pipeline send both the" +
+			" program and the data to be read by a readloop. If the" +
+			" program runs in a readloop reading from standard input" +
+			" then it is an error if the marker is not found or if" +
+			" there is no data after it."
+
+		ps.Add(paramNameStdinData,
+			psetter.Nil{},
+			"the code read from standard input (by one of the"+
+				" ...-stdin parameters) ends at a line holding just"+
+				" '"+dfltStdinDataMarker+"'."+stdinDataNote,
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(stdinParamNames...),
+			param.PostAction(
+				paction.SetVal(&g.stdinDataMarker, dfltStdinDataMarker)),
+		)
+
+		ps.Add(paramNameStdinDataMarker,
+			psetter.String[string]{
+				Value: &g.stdinDataMarker,
+				Checks: []check.String{
+					check.StringLength[string](check.ValGT(0)),
+				},
+			},
+			"the code read from standard input (by one of the"+
+				" ...-stdin parameters) ends at a line holding just"+
+				" the given marker."+stdinDataNote,
+			param.AltNames("stdin-delimiter", "stdin-delim"),
+			param.ValueName("marker"),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(stdinParamNames...),
+		)
+
 		ps.AddFinalCheck(func() error {
 			if stdinCount.Total() > 1 {
 				return fmt.Errorf(
@@ -745,6 +853,13 @@ func addStdinParams(g *gosh) func(ps *param.PSet) error {
 					stdinCount.SetBy())
 			}
 
+			if g.stdinDataMarker != "" && stdinCount.Total() == 0 {
+				return errors.New(
+					"a marker line separating the code from the data on" +
+						" standard input has been given but no code is" +
+						" being read from standard input")
+			}
+
 			return nil
 		})
 
@@ -929,6 +1044,60 @@ func addParams(g *gosh) func(ps *param.PSet) error {
 			param.PostAction(paction.AppendStrings(&g.imports, "fmt")),
 		)
 
+		// File-Begin and File-End section params
+
+		for _, fs := range []struct {
+			sect   string
+			abbrev string
+			desc   string
+		}{
+			{
+				sect:   fileBeginSect,
+				abbrev: "fb",
+				desc: " This section is run in the readloop at the start" +
+					" of each file, after it has been opened and" +
+					" before any lines have been read. It can be used" +
+					" to write a per-file header.",
+			},
+			{
+				sect:   fileEndSect,
+				abbrev: "fe",
+				desc: " This section is run in the readloop at the end" +
+					" of each file, after all its lines have been" +
+					" read and before it is closed. The '_fl'" +
+					" variable holds the number of lines in the file" +
+					" and so it can be used to write a per-file" +
+					" summary.",
+			},
+		} {
+			ps.Add(fs.sect, psetter.String[string]{Value: &codeVal},
+				"follow this with Go code."+
+					makeCodeSectionHelpText("", fs.sect)+fs.desc,
+				param.AltNames(fs.abbrev),
+				param.PostAction(scriptPAF(g, &codeVal, fs.sect)),
+				param.ValueName("Go-code"),
+				param.SeeAlso(paramNameReadloop),
+			)
+
+			ps.Add(fs.sect+"-print",
+				psetter.String[string]{
+					Value: &codeVal,
+					Editor: addPrint{
+						prefixes:    []string{fs.sect + "-", fs.abbrev + "-"},
+						paramToCall: stdPrintMap,
+						needsVal:    needsValMap,
+					},
+				},
+				makePrintHelpText(fs.sect)+fs.desc,
+				param.AltNames(
+					fs.sect+"-printf", fs.sect+"-println",
+					fs.abbrev+"-p", fs.abbrev+"-pf", fs.abbrev+"-pln"),
+				param.PostAction(scriptPAF(g, &codeVal, fs.sect)),
+				param.PostAction(paction.AppendStrings(&g.imports, "fmt")),
+				param.SeeAlso(paramNameReadloop),
+			)
+		}
+
 		// Inner-After section params
 
 		ps.Add("after", psetter.String[string]{Value: &codeVal},
@@ -1059,6 +1228,7 @@ func addParams(g *gosh) func(ps *param.PSet) error {
 				Checks: []check.String{
 					checkStringNotEmpty,
 					checkImports,
+					checkImportVersion,
 				},
 			},
 			"provide any explicit imports."+
@@ -1066,7 +1236,14 @@ func addParams(g *gosh) func(ps *param.PSet) error {
 				"Note that the import path can be given with"+
 				" a leading ...= in which case the part before"+
 				" the '=' must be a '.' or a valid Go identifier"+
-				" and is used as an alias for the package name.",
+				" and is used as an alias for the package name."+
+				"\n\n"+
+				"The import path can also be given with a trailing"+
+				" @version in which case gosh will run 'go get"+
+				" path@version' before the module is tidied. This"+
+				" lets you pin the version of the module used which"+
+				" is useful for making shebang scripts reproducible.",
+			param.SeeAlso(paramNameOffline),
 			param.AltNames("imports", "I"),
 			param.ValueName("package"),
 		)
@@ -1183,6 +1360,8 @@ func addParams(g *gosh) func(ps *param.PSet) error {
 
 		// Final checks
 
+		ps.AddFinalCheck(g.setImportVersions)
+
 		ps.AddFinalCheck(func() error {
 			if g.runAsWebserver && g.runInReadLoop {
 				var errStr strings.Builder
@@ -1294,6 +1473,30 @@ func addGoshParams(g *gosh) func(ps *param.PSet) error {
 			param.GroupName(paramGroupNameGosh),
 		)
 
+		ps.Add(paramNameGoVersion,
+			psetter.String[string]{
+				Value:  &g.goVersion,
+				Checks: []check.String{checkGoVersion},
+			},
+			"the version of Go that the program requires. This is"+
+				" written as the go directive in the generated go.mod"+
+				" file. The Go command is used if it supports this"+
+				" version, otherwise gosh will look for a suitable"+
+				" toolchain in the module cache or in the SDK"+
+				" directory where the golang.org/dl commands install"+
+				" them. If no suitable toolchain is installed locally"+
+				" gosh will report the toolchains it has found and"+
+				" exit; it will never download a toolchain."+
+				"\n\n"+
+				"This can be given in a shebang script so that the"+
+				" script records the version of Go it needs.",
+			param.AltNames("go-directive", "toolchain"),
+			param.ValueName("version"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.GroupName(paramGroupNameGosh),
+			param.SeeAlso(paramNameSetGoCmd),
+		)
+
 		// Import-populator params
 
 		ps.Add(paramNameImporter,
@@ -1453,6 +1656,22 @@ func addGoshParams(g *gosh) func(ps *param.PSet) error {
 			param.GroupName(paramGroupNameGosh),
 		)
 
+		ps.Add(paramNameOffline,
+			psetter.Bool{
+				Value: &g.offline,
+			},
+			"only use modules from the local module cache. The go"+
+				" commands that gosh runs will be run with GOPROXY=off"+
+				" and with '-mod=mod' added to GOFLAGS. If a module"+
+				" (or a version of a module given with the "+
+				paramNameImport+" parameter) is not available"+
+				" locally then gosh will fail and report this.",
+			param.AltNames("off-line", "no-network"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(paramNameImport),
+			param.GroupName(paramGroupNameGosh),
+		)
+
 		// Miscellaneous params
 
 		ps.Add("build-arg",
@@ -1463,6 +1682,29 @@ func addGoshParams(g *gosh) func(ps *param.PSet) error {
 			param.GroupName(paramGroupNameGosh),
 		)
 
+		ps.Add(paramNameVet, psetter.Bool{Value: &g.vet},
+			"run 'go vet' over the generated program before building"+
+				" it. Any problems found are reported against the"+
+				" parameter, section or snippet which supplied the code"+
+				" rather than against the generated program. If go vet"+
+				" reports any problems the program is not run and the"+
+				" generated code is kept.",
+			param.AltNames("go-vet"),
+			param.Attrs(param.DontShowInStdUsage),
+			param.GroupName(paramGroupNameGosh),
+			param.SeeAlso(paramNameVetArgs),
+		)
+
+		ps.Add(paramNameVetArgs,
+			psetter.StrListAppender[string]{Value: &g.vetArgs},
+			"add an argument to pass to the go vet command.",
+			param.AltNames("vet-args", "args-vet"),
+			param.PostAction(paction.SetVal(&g.vet, true)),
+			param.Attrs(param.DontShowInStdUsage),
+			param.GroupName(paramGroupNameGosh),
+			param.SeeAlso(paramNameVet),
+		)
+
 		ps.Add("add-comments", psetter.Bool{Value: &g.addComments},
 			"add end-of-line comments to show the lines of code"+
 				" generated by gosh.",
@@ -1473,7 +1715,7 @@ func addGoshParams(g *gosh) func(ps *param.PSet) error {
 			param.GroupName(paramGroupNameGosh),
 		)
 
-		ps.Add("base-temp-dir",
+		ps.Add(paramNameBaseTempDir,
 			psetter.Pathname{
 				Value:       &g.baseTempDir,
 				Expectation: filecheck.DirExists(),
@@ -1493,6 +1735,23 @@ func addGoshParams(g *gosh) func(ps *param.PSet) error {
 				" should be made",
 			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
 			param.GroupName(paramGroupNameGosh),
+			param.SeeAlso(paramNamePreCheckJSON),
+		)
+
+		ps.Add(paramNamePreCheckJSON,
+			psetter.Bool{
+				Value: &g.preCheckJSON,
+			},
+			"run the checks as for the '"+paramNamePreCheck+"' parameter"+
+				" but report the results as JSON rather than as text."+
+				" This is intended for use by setup scripts. The exit"+
+				" status is the same as for the '"+paramNamePreCheck+"'"+
+				" parameter",
+			param.AltNames(paramNamePreCheckJSONAlt),
+			param.PostAction(paction.SetVal(&g.preCheck, true)),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.GroupName(paramGroupNameGosh),
+			param.SeeAlso(paramNamePreCheck),
 		)
 
 		// Final checks
@@ -49,6 +49,7 @@ func cmpGoshStruct(iVal, iExpVal any) error {
 		[]string{"snippetDirs"},          // ... and the snippet dir list
 		[]string{"runInReadloopSetters"}, // ... and the lists of ByName param
 		[]string{"runAsWebserverSetters"},
+		[]string{"scriptSources"}, // ... and where the script entries came from
 	)
 }
 
@@ -467,6 +468,18 @@ func TestParseParamsCmdReadloop(t *testing.T) {
 				p, "--", testDataFile1, testDataFile2))
 	}
 
+	testCases = append(testCases,
+		mkTestParser(nil,
+			testhelper.MkID("in-place edit, all or nothing"),
+			func(g *gosh) {
+				g.runInReadLoop = true
+				g.inPlaceEdit = true
+				g.inPlaceEditTxn = true
+				g.filesToRead = true
+				g.args = []string{testDataFile1, testDataFile2}
+			},
+			"-"+paramNameInPlaceEditTxn, "--", testDataFile1, testDataFile2))
+
 	for _, p := range []string{
 		"-" + paramNameReadloop,
 		"-n",
@@ -768,6 +781,53 @@ func TestParseParamsPrinting(t *testing.T) {
 				}, p.param, printVal[p.idx]))
 	}
 
+	for _, p := range []struct {
+		param      string
+		idx        int
+		scriptPart string
+	}{
+		{"-file-begin-print", printTypeP, fileBeginSect},
+		{"-fb-p", printTypeP, fileBeginSect},
+		{"-file-begin-println", printTypePln, fileBeginSect},
+		{"-fb-pln", printTypePln, fileBeginSect},
+		{"-file-begin-printf", printTypePf, fileBeginSect},
+		{"-fb-pf", printTypePf, fileBeginSect},
+
+		{"-file-end-print", printTypeP, fileEndSect},
+		{"-fe-p", printTypeP, fileEndSect},
+		{"-file-end-println", printTypePln, fileEndSect},
+		{"-fe-pln", printTypePln, fileEndSect},
+		{"-file-end-printf", printTypePf, fileEndSect},
+		{"-fe-pf", printTypePf, fileEndSect},
+	} {
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID(""),
+				func(g *gosh) {
+					g.runInReadLoop = true
+					g.imports = []string{"fmt"}
+					g.scripts[p.scriptPart] = []scriptEntry{printValSE[p.idx]}
+				}, "-n", p.param, printVal[p.idx]))
+	}
+
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			"Final Checks",
+			errors.New(`code has been given for the "file-end" section`+
+				` but this is only run in a readloop`+
+				` (give the "-run-in-readloop" parameter)`))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs,
+				testhelper.MkID("file-end section, no readloop"),
+				func(g *gosh) {
+					g.imports = []string{"fmt"}
+					g.scripts[fileEndSect] = []scriptEntry{
+						printValSE[printTypePln],
+					}
+				}, "-fe-pln", printVal[printTypePln]))
+	}
+
 	for _, tc := range testCases {
 		_ = tc.Test(t)
 	}
@@ -821,6 +881,34 @@ func TestParseParamsSnippets(t *testing.T) {
 				p.param, snippets[1]))
 	}
 
+	for _, p := range []struct {
+		param      string
+		scriptPart string
+	}{
+		{"-file-begin-snippet", fileBeginSect},
+		{"-fb-s", fileBeginSect},
+		{"-fbs", fileBeginSect},
+
+		{"-file-end-snippet", fileEndSect},
+		{"-fe-s", fileEndSect},
+		{"-fes", fileEndSect},
+	} {
+		testCases = append(testCases,
+			mkTestParser(nil, testhelper.MkID(""),
+				func(g *gosh) {
+					g.runInReadLoop = true
+					g.scripts[p.scriptPart] = []scriptEntry{
+						snippetsSE[0],
+						snippetsSE[1],
+					}
+					g.snippetDirs = append([]string{sdPath}, g.snippetDirs...)
+				},
+				"-n",
+				"-snippet-dir", filepath.Join("testdata", snippetsDir),
+				p.param, snippets[0],
+				p.param, snippets[1]))
+	}
+
 	for _, tc := range testCases {
 		_ = tc.Test(t)
 	}
@@ -894,6 +982,69 @@ func TestParseParamsCmd(t *testing.T) {
 			"-import", "c/d",
 			"-I", "e/f"))
 
+	testCases = append(testCases,
+		mkTestParser(nil, testhelper.MkID(""), func(g *gosh) {
+			g.goVersion = "1.22"
+		}, "-go-version", "1.22"))
+
+	testCases = append(testCases,
+		mkTestParser(nil, testhelper.MkID(""), func(g *gosh) {
+			g.imports = []string{"a/b", "x=c/d", "e/f"}
+			g.importVersions = map[string]string{
+				"a/b": "v1.2.3",
+				"c/d": "latest",
+			}
+		}, "-import", "a/b@v1.2.3",
+			"-import", "x=c/d@latest",
+			"-import", "e/f"))
+
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			"Final Checks",
+			errors.New(`the import "a/b" is given with different versions:`+
+				` "v1.2.3" and "v1.2.4"`))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs,
+				testhelper.MkID("import, conflicting versions"),
+				func(g *gosh) {
+					g.imports = []string{"a/b@v1.2.3", "a/b@v1.2.4"}
+				}, "-import", "a/b@v1.2.3",
+				"-import", "a/b@v1.2.4"))
+	}
+
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			"Final Checks",
+			errors.New("template variables have been given"+
+				" but there are no templates to use them"))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs,
+				testhelper.MkID("template variables, no templates"),
+				func(g *gosh) {
+					g.templateVars = []string{"total"}
+				}, "-template-var", "total"))
+	}
+
+	{
+		parseErrs := errutil.ErrMap{}
+		parseErrs.AddError(
+			"Final Checks",
+			errors.New("a marker line separating the code from the data on"+
+				" standard input has been given but no code is"+
+				" being read from standard input"))
+
+		testCases = append(testCases,
+			mkTestParser(parseErrs,
+				testhelper.MkID("stdin data marker, no stdin code"),
+				func(g *gosh) {
+					g.stdinDataMarker = "END"
+				}, "-stdin-data-marker", "END"))
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		t.Fatal("Cannot find the current working directory:", err)
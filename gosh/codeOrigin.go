@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/snippet.mod/snippet"
+)
+
+const (
+	entryMarkerIntro      = "//" + goshCommentIntro + "entry : "
+	entriesEndMarkerIntro = "//" + goshCommentIntro + "entries end : "
+)
+
+// goshPosRE matches the start of a message from the Go tools which refers
+// to a position in the generated program. The submatches are the line
+// number and the remainder of the message (including any column number).
+var goshPosRE = regexp.MustCompile(
+	`^(?:\./)?` + regexp.QuoteMeta(goshFilename) + `:([0-9]+):(.*)$`)
+
+// entryMarker returns the comment which is written before the code for the
+// i'th entry in the named section
+func entryMarker(sect string, i int) string {
+	return entryMarkerIntro + sect + " : " + strconv.Itoa(i)
+}
+
+// entriesEndMarker returns the comment which is written after the code for
+// the last entry in the named section
+func entriesEndMarker(sect string) string {
+	return entriesEndMarkerIntro + sect
+}
+
+// codeOrigin records where a line in the generated program came from. If
+// the section is empty then the line was generated by gosh.
+type codeOrigin struct {
+	section   string
+	entry     int
+	entryLine int
+
+	snippetName string
+	snippetPath string
+	snippetLine int
+}
+
+// findCodeOrigins returns a codeOrigin for each of the lines, using the
+// entry markers and the snippet BEGIN and END comments to find the source of
+// each line. Lines which are outside of any entry are taken to have been
+// generated by gosh.
+func findCodeOrigins(lines []string) []codeOrigin {
+	snippetIntro := "//" + goshCommentIntro + "snippet : "
+	origins := make([]codeOrigin, len(lines))
+	cur := codeOrigin{}
+	inSnippetHdr := false
+
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+
+		switch {
+		case strings.HasPrefix(l, entryMarkerIntro):
+			sect, idx, _ := strings.Cut(
+				strings.TrimPrefix(l, entryMarkerIntro), " : ")
+			cur = codeOrigin{}
+
+			if n, err := strconv.Atoi(idx); err == nil {
+				cur.section = sect
+				cur.entry = n
+			}
+
+			continue
+		case strings.HasPrefix(l, entriesEndMarkerIntro):
+			cur = codeOrigin{}
+			continue
+		case cur.section == "":
+			continue
+		case strings.HasPrefix(l, snippetIntro+"BEGIN "):
+			cur.snippetName = strings.TrimPrefix(l, snippetIntro+"BEGIN ")
+			cur.snippetLine = 0
+			inSnippetHdr = true
+
+			continue
+		case strings.HasPrefix(l, snippetIntro+"END"):
+			cur.snippetName = ""
+			cur.snippetPath = ""
+			cur.snippetLine = 0
+
+			continue
+		case inSnippetHdr:
+			cur.snippetPath = strings.TrimPrefix(l, "// ")
+			inSnippetHdr = false
+
+			continue
+		}
+
+		if cur.snippetName != "" {
+			cur.snippetLine++
+		} else {
+			cur.entryLine++
+		}
+
+		origins[i] = cur
+	}
+
+	return origins
+}
+
+// snippetFileLine returns the line number in the snippet file of the n'th
+// line of the snippet text. Lines starting with the snippet comment string
+// are not part of the snippet text and so are skipped when counting. If the
+// file cannot be read the snippet text line number is returned.
+func snippetFileLine(path string, n int) int {
+	f, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return n
+	}
+	defer f.Close()
+
+	hdrIntro := "// " + snippet.CommentStr
+	textLines := 0
+	fileLine := 0
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fileLine++
+
+		if strings.HasPrefix(s.Text(), hdrIntro) {
+			continue
+		}
+
+		textLines++
+		if textLines == n {
+			return fileLine
+		}
+	}
+
+	return n
+}
+
+// positionMapper describes lines in the generated program in terms of the
+// user-supplied code which produced them
+type positionMapper interface {
+	// describe returns a description of the origin of the line
+	describe(line int) string
+	// context returns the user-supplied code surrounding the line
+	context(line int) []string
+}
+
+// goshCode holds the lines of the generated program together with their
+// origins. It implements the positionMapper interface.
+type goshCode struct {
+	sources map[string][]string
+	lines   []string
+	origins []codeOrigin
+}
+
+// readGoshCode reads the generated program and finds the origin of each
+// line. If the program cannot be read the resulting goshCode will just
+// describe lines by the program name and line number.
+func (g *gosh) readGoshCode() *goshCode {
+	gc := &goshCode{sources: g.scriptSources}
+
+	content, err := os.ReadFile(filepath.Join(g.goshDir, goshFilename))
+	if err != nil {
+		return gc
+	}
+
+	gc.lines = strings.Split(string(content), "\n")
+	gc.origins = findCodeOrigins(gc.lines)
+
+	return gc
+}
+
+// originOf returns the origin of the given line and true if the line is in
+// the generated program or false otherwise
+func (gc *goshCode) originOf(line int) (codeOrigin, bool) {
+	if line < 1 || line > len(gc.origins) {
+		return codeOrigin{}, false
+	}
+
+	return gc.origins[line-1], true
+}
+
+// describe returns a description of where the line of the generated
+// program came from.
+func (gc *goshCode) describe(line int) string {
+	o, ok := gc.originOf(line)
+	if !ok {
+		return fmt.Sprintf("%s:%d", goshFilename, line)
+	}
+
+	if o.section == "" {
+		return fmt.Sprintf("%s:%d (generated by gosh)", goshFilename, line)
+	}
+
+	source := "an unknown parameter"
+	if srcs := gc.sources[o.section]; o.entry < len(srcs) {
+		source = srcs[o.entry]
+	}
+
+	if o.snippetName != "" {
+		return fmt.Sprintf("snippet %q (%s:%d) in the %q section, from %s",
+			o.snippetName,
+			o.snippetPath, snippetFileLine(o.snippetPath, o.snippetLine),
+			o.section,
+			source)
+	}
+
+	return fmt.Sprintf("line %d of the %q section code, from %s",
+		o.entryLine, o.section, source)
+}
+
+// context returns the lines of user-supplied code around the given line,
+// each prefixed with its line number in the parameter value or snippet
+// file. The given line is marked with a '>'. Only lines from the same
+// parameter value or snippet are given; if the line was generated by gosh
+// nothing is returned.
+func (gc *goshCode) context(line int) []string {
+	const contextLines = 2
+
+	o, ok := gc.originOf(line)
+	if !ok || o.section == "" {
+		return nil
+	}
+
+	ctx := []string{}
+
+	for l := line - contextLines; l <= line+contextLines; l++ {
+		lo, ok := gc.originOf(l)
+		if !ok ||
+			lo.section != o.section ||
+			lo.entry != o.entry ||
+			lo.snippetName != o.snippetName {
+			continue
+		}
+
+		n := lo.entryLine
+		if lo.snippetName != "" {
+			n = snippetFileLine(lo.snippetPath, lo.snippetLine)
+		}
+
+		mark := " "
+		if l == line {
+			mark = ">"
+		}
+
+		ctx = append(ctx, fmt.Sprintf("%s%4d: %s",
+			mark, n, strings.TrimLeft(gc.lines[l-1], "\t")))
+	}
+
+	return ctx
+}
+
+// mapGoshPositions returns the output with every message which refers to a
+// line in the generated program rewritten to refer instead to the origin of
+// that line. Each such message is followed by the surrounding user code.
+func mapGoshPositions(output string, pm positionMapper) string {
+	var mapped strings.Builder
+
+	for l := range strings.SplitSeq(strings.TrimRight(output, "\n"), "\n") {
+		parts := goshPosRE.FindStringSubmatch(l)
+		if parts == nil {
+			mapped.WriteString(l + "\n")
+			continue
+		}
+
+		line, err := strconv.Atoi(parts[1])
+		if err != nil {
+			mapped.WriteString(l + "\n")
+			continue
+		}
+
+		msg := parts[2]
+		if col, rest, ok := strings.Cut(msg, ":"); ok && isAllDigits(col) {
+			msg = rest
+		}
+
+		mapped.WriteString(pm.describe(line) + ":" + msg + "\n")
+
+		for _, c := range pm.context(line) {
+			mapped.WriteString("\t" + c + "\n")
+		}
+	}
+
+	return mapped.String()
+}
+
+// isAllDigits returns true if the string is non-empty and consists only of
+// decimal digits
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestFindCodeOrigins(t *testing.T) {
+	lines := []string{
+		"package main",
+		"func main() {",
+		"\t" + entryMarker(execSect, 0),
+		"\ta := 1",
+		"\tb := 2",
+		"\t" + entryMarker(execSect, 1),
+		"\t//" + goshCommentIntro + "snippet : BEGIN s0",
+		"\t// /path/to/s0",
+		"\tc := 3",
+		"\t//" + goshCommentIntro + "snippet : END",
+		"\td := 4",
+		"\t" + entriesEndMarker(execSect),
+		"}",
+	}
+	expOrigins := []codeOrigin{
+		{},
+		{},
+		{},
+		{section: execSect, entry: 0, entryLine: 1},
+		{section: execSect, entry: 0, entryLine: 2},
+		{},
+		{},
+		{},
+		{
+			section:     execSect,
+			entry:       1,
+			snippetName: "s0",
+			snippetPath: "/path/to/s0",
+			snippetLine: 1,
+		},
+		{},
+		{section: execSect, entry: 1, entryLine: 1},
+		{},
+		{},
+	}
+
+	origins := findCodeOrigins(lines)
+	if testhelper.DiffInt(t, "findCodeOrigins", "number of origins",
+		len(origins), len(expOrigins)) {
+		return
+	}
+
+	for i, o := range origins {
+		id := fmt.Sprintf("line %d", i+1)
+		testhelper.DiffString(t, id, "section",
+			o.section, expOrigins[i].section)
+		testhelper.DiffInt(t, id, "entry",
+			o.entry, expOrigins[i].entry)
+		testhelper.DiffInt(t, id, "entry line",
+			o.entryLine, expOrigins[i].entryLine)
+		testhelper.DiffString(t, id, "snippet name",
+			o.snippetName, expOrigins[i].snippetName)
+		testhelper.DiffString(t, id, "snippet path",
+			o.snippetPath, expOrigins[i].snippetPath)
+		testhelper.DiffInt(t, id, "snippet line",
+			o.snippetLine, expOrigins[i].snippetLine)
+	}
+}
+
+// testPosMapper is a positionMapper for testing mapGoshPositions
+type testPosMapper struct{}
+
+func (testPosMapper) describe(line int) string {
+	return fmt.Sprintf("LINE-%d", line)
+}
+
+func (testPosMapper) context(line int) []string {
+	if line != 12 {
+		return nil
+	}
+
+	return []string{"> 1: a := 1"}
+}
+
+func TestMapGoshPositions(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		output    string
+		expOutput string
+	}{
+		{
+			ID:        testhelper.MkID("no positions"),
+			output:    "# G\nsome message\n",
+			expOutput: "# G\nsome message\n",
+		},
+		{
+			ID:     testhelper.MkID("with column, with context"),
+			output: "# G\n./gosh.go:12:2: declared and not used: a\n",
+			expOutput: "# G\n" +
+				"LINE-12: declared and not used: a\n" +
+				"\t> 1: a := 1\n",
+		},
+		{
+			ID:        testhelper.MkID("without column"),
+			output:    "gosh.go:7: bad thing",
+			expOutput: "LINE-7: bad thing\n",
+		},
+		{
+			ID:        testhelper.MkID("other file"),
+			output:    "./goshCopy00x.go:7:1: bad thing\n",
+			expOutput: "./goshCopy00x.go:7:1: bad thing\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "output",
+			mapGoshPositions(tc.output, testPosMapper{}), tc.expOutput)
+	}
+}
+
+func TestGoshCodeContext(t *testing.T) {
+	lines := []string{
+		"func main() {",
+		"\t" + entryMarker(execSect, 0),
+		"\ta := 1",
+		"\t" + entryMarker(execSect, 1),
+		"\tb := 2",
+		"\tc := 3",
+		"\td := 4",
+		"\te := 5",
+		"\t" + entriesEndMarker(execSect),
+		"}",
+	}
+	gc := &goshCode{
+		sources: map[string][]string{
+			execSect: {"param-0", "param-1"},
+		},
+		lines:   lines,
+		origins: findCodeOrigins(lines),
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		line       int
+		expDesc    string
+		expContext []string
+	}{
+		{
+			ID:      testhelper.MkID("generated"),
+			line:    1,
+			expDesc: "gosh.go:1 (generated by gosh)",
+		},
+		{
+			ID:      testhelper.MkID("out of range"),
+			line:    99,
+			expDesc: "gosh.go:99",
+		},
+		{
+			ID:         testhelper.MkID("single line entry"),
+			line:       3,
+			expDesc:    `line 1 of the "exec" section code, from param-0`,
+			expContext: []string{">   1: a := 1"},
+		},
+		{
+			ID:      testhelper.MkID("multi-line entry"),
+			line:    6,
+			expDesc: `line 2 of the "exec" section code, from param-1`,
+			expContext: []string{
+				"    1: b := 2",
+				">   2: c := 3",
+				"    3: d := 4",
+				"    4: e := 5",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "description",
+			gc.describe(tc.line), tc.expDesc)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "context",
+			gc.context(tc.line), tc.expContext)
+	}
+}
+
+func TestPlainBuildErrorMapping(t *testing.T) {
+	const (
+		bSource = `[command line]: Supplied Parameter:2: "-b" "y := 2"`
+		eSource = `[command line]: Supplied Parameter:4: "-e" "x := 1"`
+	)
+
+	g := newGosh()
+	g.goshDir = t.TempDir()
+	g.AddScriptEntry(beforeSect, "y := 2", verbatim, bSource)
+	g.AddScriptEntry(execSect, "x := 1", verbatim, eSource)
+
+	f, err := os.Create(filepath.Join(g.goshDir, goshFilename))
+	if err != nil {
+		t.Fatal("can't create the program file: ", err)
+	}
+
+	g.w = f
+	g.writeScript(beforeSect)
+	g.writeScript(execSect)
+
+	if err := f.Close(); err != nil {
+		t.Fatal("can't close the program file: ", err)
+	}
+
+	output := "./gosh.go:2:1: declared and not used: y\n" +
+		"./gosh.go:5:1: declared and not used: x\n"
+	expOutput := "line 1 of the \"before\" section code, from " + bSource +
+		": declared and not used: y\n" +
+		"\t>   1: y := 2\n" +
+		"line 1 of the \"exec\" section code, from " + eSource +
+		": declared and not used: x\n" +
+		"\t>   1: x := 1\n"
+
+	testhelper.DiffString(t, "plain build", "output",
+		mapGoshPositions(output, g.readGoshCode()), expOutput)
+}
@@ -18,8 +18,13 @@ var origFileProvisos = filecheck.IsNew()
 // HandleRemainder processes the trailing parameters. If gosh has the
 // 'runInReadLoop' flag set then they are treated as files and added to the
 // filesToRead. Otherwise they are added to the list of args and that is
-// looped over instead.
+// looped over instead. If any program parameters have been declared then
+// any values for them are first removed.
 func (g *gosh) HandleRemainder(rem []string) {
+	if len(g.progParams) > 0 {
+		g.progParamArgs, rem = splitProgParamArgs(rem)
+	}
+
 	if g.runInReadLoop && !g.skipArgLoop {
 		g.populateFilesToRead(rem)
 	} else {
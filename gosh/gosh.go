@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -29,7 +32,9 @@ const (
 	globalSect      = "global"
 	beforeSect      = "before"
 	beforeInnerSect = "before-inner"
+	fileBeginSect   = "file-begin"
 	execSect        = "exec"
+	fileEndSect     = "file-end"
 	afterInnerSect  = "after-inner"
 	afterSect       = "after"
 
@@ -41,6 +46,7 @@ const (
 	goshExitStatusBuildFail
 	goshExitStatusMisc
 	goshExitStatusRunFail
+	goshExitStatusVetFail
 )
 
 type expandFunc func(*gosh, string) ([]string, error)
@@ -56,7 +62,8 @@ type scriptEntry struct {
 
 // gosh records all the details needed to build a gosh program
 type gosh struct {
-	preCheck bool
+	preCheck     bool
+	preCheckJSON bool
 
 	w           *os.File
 	indent      int
@@ -64,13 +71,16 @@ type gosh struct {
 
 	imports []string
 
-	scripts     map[string][]scriptEntry
-	copyGoFiles []string
+	scripts       map[string][]scriptEntry
+	scriptSources map[string][]string
+	sectionText   map[string][]string
+	copyGoFiles   []string
 
-	runInReadLoop bool
-	inPlaceEdit   bool
-	splitLine     bool
-	splitPattern  string
+	runInReadLoop  bool
+	inPlaceEdit    bool
+	inPlaceEditTxn bool
+	splitLine      bool
+	splitPattern   string
 
 	runAsWebserver bool
 	httpHandler    string
@@ -92,13 +102,46 @@ type gosh struct {
 
 	args        []string
 	skipArgLoop bool
+
+	progParams    []progParam
+	progParamArgs []string
+
+	templates    map[string]string
+	templateVars []string
+
 	filesToRead bool
 	errMap      *errutil.ErrMap
 
+	stdinDataMarker string
+	stdinData       *bufio.Reader
+
 	snippetDirs []string
 	snippetUsed map[string]bool
 	snippets    *snippet.Cache
 
+	profileDirs   []string
+	profilesInUse []string
+	profileList   bool
+	profileShow   []string
+
+	historyFile       string
+	historyDontRecord bool
+	historyList       bool
+	historySearch     *regexp.Regexp
+	historyRun        int64
+	historyEdit       int64
+
+	snippetSaveName      string
+	snippetSaveSect      string
+	snippetSaveDocs      []string
+	snippetSaveOverwrite bool
+	snippetSaved         bool
+
+	importVersions map[string]string
+	offline        bool
+
+	goVersion string
+
 	localModules        map[string]string
 	workspace           []string
 	ignoreGoModTidyErrs bool
@@ -122,6 +165,14 @@ type gosh struct {
 
 	buildArgs []string
 
+	vet     bool
+	vetArgs []string
+
+	buildWithRace bool
+	cpuProfile    string
+	memProfile    string
+	traceFile     string
+
 	env      []string
 	clearEnv bool
 
@@ -192,10 +243,15 @@ func newGosh() *gosh {
 			globalSect:      {},
 			beforeSect:      {},
 			beforeInnerSect: {},
+			fileBeginSect:   {},
 			execSect:        {},
+			fileEndSect:     {},
 			afterInnerSect:  {},
 			afterSect:       {},
 		},
+		scriptSources: map[string][]string{},
+		templates:     map[string]string{},
+		sectionText:   map[string][]string{},
 
 		splitPattern: dfltSplitPattern,
 
@@ -209,13 +265,16 @@ func newGosh() *gosh {
 
 		runDir: cwd,
 
-		snippetUsed: map[string]bool{},
-		snippets:    &snippet.Cache{},
+		snippetUsed:     map[string]bool{},
+		snippets:        &snippet.Cache{},
+		snippetSaveSect: execSect,
 
 		dbgStack: &verbose.Stack{},
 	}
 
 	g.setDfltSnippetPath()
+	g.setDfltProfilePath()
+	g.setDfltHistoryFile()
 
 	return g
 }
@@ -247,9 +306,11 @@ func verbatim(_ *gosh, s string) ([]string, error) {
 	return []string{s}, nil
 }
 
-// AddScriptEntry adds the script entry to the named script. It panics if the
-// script name is invalid or the expandFunc is nil.
-func (g *gosh) AddScriptEntry(sName, v string, ef expandFunc) {
+// AddScriptEntry adds the script entry to the named script. The source
+// describes where the entry came from and is used when reporting problems
+// with the generated code. It panics if the script name is invalid or the
+// expandFunc is nil.
+func (g *gosh) AddScriptEntry(sName, v string, ef expandFunc, source string) {
 	s, ok := g.scripts[sName]
 	if !ok {
 		panic(fmt.Errorf("the script name is invalid: %q", sName))
@@ -260,6 +321,36 @@ func (g *gosh) AddScriptEntry(sName, v string, ef expandFunc) {
 	}
 
 	g.scripts[sName] = append(s, scriptEntry{expand: ef, value: v})
+	g.scriptSources[sName] = append(g.scriptSources[sName], source)
+}
+
+// goshSources holds the sources of the script entries which gosh adds
+// itself rather than taking from a parameter
+var goshSources = []string{profilingSource, progParamSource, templateSource}
+
+// isUserEntry returns true if the i'th entry in the named script was
+// supplied by the user rather than added by gosh itself.
+func (g *gosh) isUserEntry(sName string, i int) bool {
+	sources := g.scriptSources[sName]
+	if i >= len(sources) {
+		return true
+	}
+
+	return !slices.Contains(goshSources, sources[i])
+}
+
+// userEntryCount returns the number of entries in the named script which
+// were supplied by the user.
+func (g *gosh) userEntryCount(sName string) int {
+	count := 0
+
+	for i := range g.scripts[sName] {
+		if g.isUserEntry(sName, i) {
+			count++
+		}
+	}
+
+	return count
 }
 
 // addError adds the error to the named error map entry
@@ -370,6 +461,18 @@ var knownVarMap = varMap{
 		typeName: "[]string",
 		desc:     "the parts of the line (when split)",
 	},
+	"_ipeFiles": {
+		typeName: "[]string",
+		desc:     "the files edited in place (when all-or-nothing)",
+	},
+	"_ipeTemps": {
+		typeName: "[]string",
+		desc:     "the new files for those edited in place",
+	},
+	"_ipeFailed": {
+		typeName: "bool",
+		desc:     "set to true to abandon an all-or-nothing in-place edit",
+	},
 }
 
 // nameType looks up the name in knownVarMap and if it is found it will
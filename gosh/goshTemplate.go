@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/nickwells/filecheck.mod/filecheck"
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+)
+
+const (
+	paramNameBeforeTemplate     = "before-template"
+	paramNameBeforeTemplateFile = "before-template-file"
+	paramNameExecTemplate       = "exec-template"
+	paramNameExecTemplateFile   = "exec-template-file"
+	paramNameAfterTemplate      = "after-template"
+	paramNameAfterTemplateFile  = "after-template-file"
+	paramNameTemplateVar        = "template-var"
+
+	templateParamGroup = "cmd-template"
+
+	templateSource = "the template parameters"
+)
+
+var templateParamNames = []string{
+	paramNameBeforeTemplate,
+	paramNameBeforeTemplateFile,
+	paramNameExecTemplate,
+	paramNameExecTemplateFile,
+	paramNameAfterTemplate,
+	paramNameAfterTemplateFile,
+	paramNameTemplateVar,
+}
+
+// templateSections gives the sections which can have a template in the
+// order in which the templates are declared in the generated program
+var templateSections = []string{beforeSect, execSect, afterSect}
+
+// addTemplateParams returns a func that will add the parameters for
+// giving templates which format the output of the generated program
+func addTemplateParams(g *gosh) func(ps *param.PSet) error {
+	return func(ps *param.PSet) error {
+		ps.AddGroup(templateParamGroup,
+			"parameters relating to the templates used to format"+
+				" the output of the generated program.")
+
+		const templateNote = "\n\n" +
+			"The template is written using the syntax of the Go" +
+			" text/template package. If the program is run as a" +
+			" webserver then the html/template package is used" +
+			" instead so that the values are safely escaped." +
+			"\n\n" +
+			"The template is given a map holding the gosh variables" +
+			" which are available at that point in the program:" +
+			" '_fn' (the file name) and '_fl' (the file line number)" +
+			" in a readloop, '_line' (the text of the line) and" +
+			" '_lp' (the split line parts) while reading each line" +
+			" and '_req' (the HTTP request) in a webserver. Any" +
+			" variables named with the " + paramNameTemplateVar +
+			" parameter are also added. These are referred to in" +
+			" the template by name, for instance: {{._fn}}." +
+			"\n\n" +
+			"Only one template may be given for each section."
+
+		var (
+			tmplText string
+			fileName string
+		)
+
+		tmplParams := []struct {
+			name     string
+			fileName string
+			altNames []string
+			sName    string
+			desc     string
+		}{
+			{
+				name:     paramNameBeforeTemplate,
+				fileName: paramNameBeforeTemplateFile,
+				altNames: []string{"b-tmpl"},
+				sName:    beforeSect,
+				desc: "once at the end of the '" + beforeSect + "'" +
+					" section",
+			},
+			{
+				name:     paramNameExecTemplate,
+				fileName: paramNameExecTemplateFile,
+				altNames: []string{"e-tmpl", "template", "tmpl"},
+				sName:    execSect,
+				desc: "at the end of the '" + execSect + "'" +
+					" section. In a readloop this will be run for" +
+					" each line that is read and in a webserver" +
+					" for each request; the output is written to the" +
+					" file being edited or to the HTTP response as" +
+					" appropriate",
+			},
+			{
+				name:     paramNameAfterTemplate,
+				fileName: paramNameAfterTemplateFile,
+				altNames: []string{"a-tmpl"},
+				sName:    afterSect,
+				desc: "once at the end of the '" + afterSect + "'" +
+					" section. This can be used to report values" +
+					" accumulated while the program runs",
+			},
+		}
+
+		for _, tp := range tmplParams {
+			fileAltNames := []string{}
+			for _, an := range tp.altNames {
+				fileAltNames = append(fileAltNames, an+"-file")
+			}
+
+			ps.Add(tp.name, psetter.String[string]{Value: &tmplText},
+				"follow this with a template which will be applied "+
+					tp.desc+"."+templateNote,
+				param.AltNames(tp.altNames...),
+				param.ValueName("template"),
+				param.PostAction(templatePAF(g, &tmplText, tp.sName)),
+				param.GroupName(templateParamGroup),
+				param.Attrs(param.DontShowInStdUsage),
+				param.SeeAlso(templateParamNames...),
+			)
+
+			ps.Add(tp.fileName,
+				psetter.Pathname{
+					Value:       &fileName,
+					Expectation: filecheck.FileNonEmpty(),
+				},
+				"follow this with the name of a file holding a template"+
+					" which will be applied "+tp.desc+"."+templateNote,
+				param.AltNames(fileAltNames...),
+				param.PostAction(templateFilePAF(g, &fileName, tp.sName)),
+				param.GroupName(templateParamGroup),
+				param.Attrs(param.DontShowInStdUsage),
+				param.SeeAlso(templateParamNames...),
+			)
+		}
+
+		ps.Add(paramNameTemplateVar,
+			psetter.StrListAppender[string]{Value: &g.templateVars},
+			"follow this with the names of variables which should be"+
+				" made available to the templates. These would"+
+				" typically be global variables declared in the"+
+				" '"+globalSect+"' section but they can be any"+
+				" variables which are in scope at the end of the"+
+				" sections where the templates are applied.",
+			param.AltNames("tmpl-var", "template-vars"),
+			param.ValueName("var,..."),
+			param.GroupName(templateParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(templateParamNames...),
+		)
+
+		ps.AddFinalCheck(func() error {
+			return g.addTemplates()
+		})
+
+		return nil
+	}
+}
+
+// templatePAF generates the Post-Action func (PAF) that records the
+// template for the named section.
+//
+// Note that we pass a pointer to the text rather than the string - this is
+// necessary otherwise we are passing the text value at the point the PAF is
+// being generated not at the point where the parameter value is given.
+func templatePAF(g *gosh, text *string, sName string) param.ActionFunc {
+	return func(_ location.L, _ *param.BaseParam, _ []string) error {
+		return g.setTemplate(sName, *text)
+	}
+}
+
+// templateFilePAF generates the Post-Action func (PAF) that reads the
+// template file and records the template for the named section.
+func templateFilePAF(g *gosh, fileName *string, sName string) param.ActionFunc {
+	return func(_ location.L, _ *param.BaseParam, _ []string) error {
+		content, err := os.ReadFile(*fileName)
+		if err != nil {
+			return fmt.Errorf("could not read the template file: %w", err)
+		}
+
+		return g.setTemplate(sName, string(content))
+	}
+}
+
+// setTemplate checks that the template can be parsed and that no template
+// has already been given for the section and then records it.
+func (g *gosh) setTemplate(sName, text string) error {
+	if _, ok := g.templates[sName]; ok {
+		return fmt.Errorf(
+			"a template has already been given for the %q section", sName)
+	}
+
+	if _, err := template.New(sName).Parse(text); err != nil {
+		return fmt.Errorf("the %q template is invalid: %w", sName, err)
+	}
+
+	g.templates[sName] = text
+
+	return nil
+}
+
+// addTemplates adds the code to parse the templates to the global section
+// and the code to apply each template to the end of its section.
+func (g *gosh) addTemplates() error {
+	if len(g.templates) == 0 {
+		if len(g.templateVars) > 0 {
+			return errors.New("template variables have been given" +
+				" but there are no templates to use them")
+		}
+
+		return nil
+	}
+
+	for _, v := range g.templateVars {
+		if !token.IsIdentifier(v) {
+			return fmt.Errorf(
+				"the template variable %q is not a valid Go identifier", v)
+		}
+	}
+
+	tmplPkg := "text/template"
+	if g.runAsWebserver {
+		tmplPkg = "html/template"
+	}
+
+	g.imports = append(g.imports, "fmt", "io", "os", tmplPkg)
+
+	g.AddScriptEntry(globalSect, templateGlobalCode(g.templates),
+		verbatim, templateSource)
+
+	for _, sName := range templateSections {
+		if _, ok := g.templates[sName]; !ok {
+			continue
+		}
+
+		g.AddScriptEntry(sName,
+			templateExecCode(sName,
+				g.templateWriter(sName), g.templateData(sName)),
+			verbatim, templateSource)
+	}
+
+	return nil
+}
+
+// templateWriter returns the name of the writer that the template for the
+// named section should be written to
+func (g *gosh) templateWriter(sName string) string {
+	if sName == execSect {
+		if g.runAsWebserver {
+			return "_rw"
+		}
+
+		if g.inPlaceEdit {
+			return "_w"
+		}
+	}
+
+	return "os.Stdout"
+}
+
+// templateData returns the names of the variables which are available to
+// the template for the named section. These are the gosh variables which
+// are in scope at the end of the section followed by any template
+// variables the user has given.
+func (g *gosh) templateData(sName string) map[string]string {
+	data := map[string]string{}
+
+	if g.runInReadLoop {
+		data["_fn"] = "_fn"
+		data["_fl"] = "_fl"
+
+		if sName == execSect {
+			data["_line"] = "_l.Text()"
+
+			if g.splitLine {
+				data["_lp"] = "_lp"
+			}
+		}
+	}
+
+	if g.runAsWebserver && sName == execSect {
+		data["_req"] = "_req"
+	}
+
+	for _, v := range g.templateVars {
+		data[v] = v
+	}
+
+	return data
+}
+
+// templateGlobalCode returns the code to parse the templates and the func
+// which will apply them.
+func templateGlobalCode(templates map[string]string) string {
+	var code strings.Builder
+
+	code.WriteString(`
+var goshTemplates = template.New("gosh")
+
+func init() {
+`)
+
+	for _, sName := range templateSections {
+		text, ok := templates[sName]
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(&code,
+			"\ttemplate.Must(goshTemplates.New(%q).Parse(%q))\n",
+			sName, text)
+	}
+
+	code.WriteString(`}
+
+// goshTemplateExec applies the named template to the data, writing the
+// results to w. Any errors are reported on standard error.
+func goshTemplateExec(w io.Writer, name string, data map[string]any) {
+	if err := goshTemplates.ExecuteTemplate(w, name, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Error applying the %q template: %v\n",
+			name, err)
+	}
+}`)
+
+	return code.String()
+}
+
+// templateExecCode returns the code to apply the template for the named
+// section to the data, writing the results to the named writer. The data
+// maps the name used in the template to the expression giving its value.
+func templateExecCode(sName, writer string, data map[string]string) string {
+	var code strings.Builder
+
+	fmt.Fprintf(&code, "goshTemplateExec(%s, %q, map[string]any{\n",
+		writer, sName)
+
+	for _, name := range slices.Sorted(maps.Keys(data)) {
+		fmt.Fprintf(&code, "\t%q: %s,\n", name, data[name])
+	}
+
+	code.WriteString("})")
+
+	return code.String()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSetTemplate(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		existing map[string]string
+		sName    string
+		text     string
+	}{
+		{
+			ID:    testhelper.MkID("good"),
+			sName: execSect,
+			text:  "{{._fn}}:{{._fl}}: {{._line}}\n",
+		},
+		{
+			ID:       testhelper.MkID("other section already set"),
+			existing: map[string]string{afterSect: "done\n"},
+			sName:    execSect,
+			text:     "{{._line}}\n",
+		},
+		{
+			ID:       testhelper.MkID("already set"),
+			existing: map[string]string{execSect: "{{._line}}\n"},
+			sName:    execSect,
+			text:     "{{._line}}\n",
+			ExpErr: testhelper.MkExpErr(
+				`a template has already been given for the "exec" section`),
+		},
+		{
+			ID:    testhelper.MkID("bad template"),
+			sName: execSect,
+			text:  "{{._line}\n",
+			ExpErr: testhelper.MkExpErr(
+				`the "exec" template is invalid`),
+		},
+	}
+
+	for _, tc := range testCases {
+		g := newGosh()
+		for k, v := range tc.existing {
+			g.templates[k] = v
+		}
+
+		err := g.setTemplate(tc.sName, tc.text)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "template",
+				g.templates[tc.sName], tc.text)
+		}
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		setup   func(g *gosh)
+		sName   string
+		expData map[string]string
+	}{
+		{
+			ID:      testhelper.MkID("no loop"),
+			setup:   func(_ *gosh) {},
+			sName:   execSect,
+			expData: map[string]string{},
+		},
+		{
+			ID: testhelper.MkID("readloop, exec, split, vars"),
+			setup: func(g *gosh) {
+				g.runInReadLoop = true
+				g.splitLine = true
+				g.templateVars = []string{"total"}
+			},
+			sName: execSect,
+			expData: map[string]string{
+				"_fn":   "_fn",
+				"_fl":   "_fl",
+				"_line": "_l.Text()",
+				"_lp":   "_lp",
+				"total": "total",
+			},
+		},
+		{
+			ID: testhelper.MkID("readloop, after"),
+			setup: func(g *gosh) {
+				g.runInReadLoop = true
+				g.splitLine = true
+			},
+			sName: afterSect,
+			expData: map[string]string{
+				"_fn": "_fn",
+				"_fl": "_fl",
+			},
+		},
+		{
+			ID: testhelper.MkID("webserver, exec"),
+			setup: func(g *gosh) {
+				g.runAsWebserver = true
+			},
+			sName: execSect,
+			expData: map[string]string{
+				"_req": "_req",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		g := newGosh()
+		tc.setup(g)
+
+		if err := testhelper.DiffVals(
+			g.templateData(tc.sName), tc.expData); err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: template data: %s", err)
+		}
+	}
+}
+
+func TestTemplateCode(t *testing.T) {
+	code := templateGlobalCode(map[string]string{
+		afterSect: "total: {{.total}}\n",
+		execSect:  "{{._fn}}:{{._fl}}: {{._line}}\n",
+	})
+	code += "\n\nfunc main() {\n" +
+		templateExecCode(execSect, "os.Stdout",
+			map[string]string{"_fn": "_fn", "_line": "_l.Text()"}) +
+		"\n}\n"
+
+	_, err := parser.ParseFile(token.NewFileSet(), "",
+		"package main\n"+code, 0)
+	if err != nil {
+		t.Errorf("the generated code doesn't parse: %v\n%s", err, code)
+	}
+
+	for _, s := range []string{
+		`template.Must(goshTemplates.New("exec").Parse(` +
+			`"{{._fn}}:{{._fl}}: {{._line}}\n"))`,
+		`template.Must(goshTemplates.New("after").Parse(` +
+			`"total: {{.total}}\n"))`,
+		`goshTemplateExec(os.Stdout, "exec", map[string]any{`,
+		"\t\"_line\": _l.Text(),\n",
+	} {
+		if !strings.Contains(code, s) {
+			t.Errorf("the generated code should contain %q\n%s", s, code)
+		}
+	}
+
+	if strings.Index(code, `New("exec")`) > strings.Index(code, `New("after")`) {
+		t.Errorf("the templates should be declared in section order\n%s",
+			code)
+	}
+}
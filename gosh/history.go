@@ -0,0 +1,486 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nickwells/check.mod/v2/check"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+const (
+	paramNameHistoryList       = "history-list"
+	paramNameHistorySearch     = "history-search"
+	paramNameHistoryRun        = "history-run"
+	paramNameHistoryEdit       = "history-edit"
+	paramNameHistoryFile       = "history-file"
+	paramNameHistoryDontRecord = "history-dont-record"
+
+	historyParamGroup = "cmd-history"
+
+	historyFileName   = "history"
+	historyMaxEntries = 1000
+	historyLockSfx    = ".lock"
+	historyLockWait   = 5 * time.Second
+	historyLockRetry  = 10 * time.Millisecond
+	historyLockStale  = time.Minute
+	historyDirPerms   = 0o700 // Owner: Read/Write/Search, the rest: none
+	historyFilePerms  = 0o600 // Owner: Read/Write, the rest: none
+
+	xdgStateHomeEnvVar     = "XDG_STATE_HOME"
+	xdgStateHomeEnvVarDflt = "$HOME/.local/state"
+)
+
+var historyParamNames = []string{
+	paramNameHistoryList,
+	paramNameHistorySearch,
+	paramNameHistoryRun,
+	paramNameHistoryEdit,
+	paramNameHistoryFile,
+	paramNameHistoryDontRecord,
+}
+
+// historyEntry records the details of a single invocation of gosh
+type historyEntry struct {
+	Time       time.Time `json:"time"`
+	Dir        string    `json:"dir"`
+	Args       []string  `json:"args"`
+	ExitStatus int       `json:"exitStatus"`
+}
+
+// cmdLine returns the gosh command line for the entry, quoted so that it
+// can be given to the shell
+func (he historyEntry) cmdLine() string {
+	parts := []string{"gosh"}
+	for _, a := range he.Args {
+		parts = append(parts, shellQuote(a))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+var shellSafeRE = regexp.MustCompile(`^[-a-zA-Z0-9_@%+=:,./]+$`)
+
+// shellQuote returns the string quoted so that the shell will treat it as a
+// single word. Strings which need no quoting are returned unchanged.
+func shellQuote(s string) string {
+	if shellSafeRE.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// stateHome returns the XDG state directory. The xdg package does not
+// provide this and so it is found here following the same rules: the value
+// of the XDG_STATE_HOME environment variable or, if that is not set or is
+// not an absolute path, the default value.
+func stateHome() string {
+	dir := os.Getenv(xdgStateHomeEnvVar)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return os.ExpandEnv(xdgStateHomeEnvVarDflt)
+}
+
+// setDfltHistoryFile sets the history file to the default value.
+func (g *gosh) setDfltHistoryFile() {
+	g.historyFile = filepath.Join(stateHome(),
+		"github.com",
+		"nickwells",
+		"utilities",
+		"gosh",
+		historyFileName)
+}
+
+// addHistoryParams returns a func that will add parameters concerned with
+// the history of previous invocations of gosh.
+func addHistoryParams(g *gosh) func(ps *param.PSet) error {
+	return func(ps *param.PSet) error {
+		ps.AddGroup(historyParamGroup,
+			"parameters relating to the history of previous"+
+				" invocations of gosh. Each time gosh successfully"+
+				" builds and runs a program the parameters, the"+
+				" working directory, the time and the exit status"+
+				" are recorded in the history file.")
+
+		const indexNote = "\n\n" +
+			"The entries are numbered from 1, the oldest, as shown" +
+			" by the " + paramNameHistoryList + " parameter. A" +
+			" negative number counts back from the most recent" +
+			" entry so -1 is the last invocation." +
+			"\n\n" +
+			"The entry is run in the directory where it was first" +
+			" run. Note that any code which was read from standard" +
+			" input is not recorded and will be read again."
+
+		ps.Add(paramNameHistoryList,
+			psetter.Bool{Value: &g.historyList},
+			"list the history of previous invocations and exit, no"+
+				" program is run.",
+			param.AltNames("hist-list", "history"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.Add(paramNameHistorySearch,
+			psetter.Regexp{Value: &g.historySearch},
+			"list those previous invocations whose command line or"+
+				" directory matches the regular expression and exit,"+
+				" no program is run.",
+			param.AltNames("hist-search"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.Add(paramNameHistoryRun,
+			psetter.Int[int64]{
+				Value:  &g.historyRun,
+				Checks: []check.Int64{check.ValNE[int64](0)},
+			},
+			"run the numbered entry from the history again."+indexNote,
+			param.AltNames("hist-run", "rerun"),
+			param.ValueName("index"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.Add(paramNameHistoryEdit,
+			psetter.Int[int64]{
+				Value:  &g.historyEdit,
+				Checks: []check.Int64{check.ValNE[int64](0)},
+			},
+			"run the numbered entry from the history again but first"+
+				" edit the generated program, as if the '"+
+				paramNameEditScript+"' parameter had been given."+
+				indexNote,
+			param.AltNames("hist-edit"),
+			param.ValueName("index"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.Add(paramNameHistoryFile,
+			psetter.Pathname{Value: &g.historyFile},
+			"the file in which the history of previous invocations"+
+				" is recorded.",
+			param.AltNames("hist-file"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.Add(paramNameHistoryDontRecord,
+			psetter.Bool{Value: &g.historyDontRecord},
+			"don't record this invocation in the history.",
+			param.AltNames("no-history"),
+			param.GroupName(historyParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(historyParamNames...),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if g.historyRun != 0 && g.historyEdit != 0 {
+				return fmt.Errorf("only one of %q and %q may be given",
+					paramNameHistoryRun, paramNameHistoryEdit)
+			}
+
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// readHistory reads the history entries from the file. A missing file has
+// no entries.
+func readHistory(fileName string) ([]historyEntry, error) {
+	entries := []historyEntry{}
+
+	f, err := os.Open(fileName) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return entries, nil
+		}
+
+		return nil, err
+	}
+	defer f.Close()
+
+	const maxEntrySize = 1024 * 1024
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, maxEntrySize)
+
+	lineNum := 0
+	for s.Scan() {
+		lineNum++
+
+		var he historyEntry
+		if err := json.Unmarshal(s.Bytes(), &he); err != nil {
+			return nil, fmt.Errorf("bad history entry at line %d: %w",
+				lineNum, err)
+		}
+
+		entries = append(entries, he)
+	}
+
+	return entries, s.Err()
+}
+
+// writeHistory writes the history entries to the file, keeping at most
+// historyMaxEntries of the latest entries. The entries are written to a
+// temporary file which then replaces the history file so that the history
+// file is never left partly written.
+func writeHistory(fileName string, entries []historyEntry) error {
+	if len(entries) > historyMaxEntries {
+		entries = entries[len(entries)-historyMaxEntries:]
+	}
+
+	dir := filepath.Dir(fileName)
+	if err := os.MkdirAll(dir, historyDirPerms); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(dir, filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+
+	tmpName := f.Name()
+
+	enc := json.NewEncoder(f)
+	for _, he := range entries {
+		if err = enc.Encode(he); err != nil {
+			break
+		}
+	}
+
+	err = errors.Join(err, f.Close())
+
+	if err == nil {
+		err = os.Chmod(tmpName, historyFilePerms)
+	}
+
+	if err == nil {
+		err = os.Rename(tmpName, fileName)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+
+	return err
+}
+
+// lockHistory takes the lock on the history file, waiting for up to
+// historyLockWait for any other gosh to release it. The lock is a file
+// created alongside the history file; a lock file older than
+// historyLockStale is taken to have been left behind and is removed. It
+// returns a func which releases the lock.
+func lockHistory(fileName string) (func(), error) {
+	lockName := fileName + historyLockSfx
+	deadline := time.Now().Add(historyLockWait)
+
+	for {
+		f, err := os.OpenFile(lockName,
+			os.O_CREATE|os.O_EXCL|os.O_WRONLY, historyFilePerms)
+		if err == nil {
+			if err := f.Close(); err != nil {
+				_ = os.Remove(lockName)
+				return nil, err
+			}
+
+			return func() { _ = os.Remove(lockName) }, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(lockName); err == nil &&
+			time.Since(fi.ModTime()) > historyLockStale {
+			_ = os.Remove(lockName)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf(
+				"the history file is locked, remove %q if no gosh is running",
+				lockName)
+		}
+
+		time.Sleep(historyLockRetry)
+	}
+}
+
+// appendHistory adds the entry to the history file. The history file is
+// locked while it is read and rewritten so that entries added by other
+// instances of gosh running at the same time are not lost.
+func appendHistory(fileName string, he historyEntry) error {
+	if err := os.MkdirAll(filepath.Dir(fileName), historyDirPerms); err != nil {
+		return err
+	}
+
+	unlock, err := lockHistory(fileName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := readHistory(fileName)
+	if err != nil {
+		return err
+	}
+
+	return writeHistory(fileName, append(entries, he))
+}
+
+// historyIndex converts the index given by the user into an offset into
+// the entries. It returns a non-nil error if there is no such entry.
+func historyIndex(idx int64, count int) (int, error) {
+	i := int(idx)
+	if i < 0 {
+		i += count
+	} else {
+		i--
+	}
+
+	if i < 0 || i >= count {
+		return 0, fmt.Errorf(
+			"there is no history entry %d, there are %d entries",
+			idx, count)
+	}
+
+	return i, nil
+}
+
+// recordHistory adds the current invocation to the history file. Failures
+// which come from gosh itself rather than from the generated program are
+// not recorded. Any problems writing the history are reported but are not
+// fatal.
+func (g *gosh) recordHistory() {
+	if g.historyDontRecord || g.historyFile == "" {
+		return
+	}
+
+	switch g.exitStatus {
+	case goshExitStatusBuildFail, goshExitStatusVetFail, goshExitStatusRunFail:
+		return
+	}
+
+	defer g.dbgStack.Start("recordHistory",
+		"Recording the invocation in the history")()
+
+	intro := g.dbgStack.Tag()
+
+	verbose.Println(intro, " History file: "+g.historyFile)
+
+	he := historyEntry{
+		Time:       time.Now(),
+		Dir:        g.runDir,
+		Args:       os.Args[1:],
+		ExitStatus: g.exitStatus,
+	}
+
+	if err := appendHistory(g.historyFile, he); err != nil {
+		fmt.Fprintf(os.Stderr,
+			"gosh: Warning: could not record the history in %q: %v\n",
+			g.historyFile, err)
+	}
+}
+
+// handleHistory lists or searches the history or re-runs an entry from it
+// as requested. If anything is done then the program will exit afterwards.
+func handleHistory(g *gosh) {
+	if !g.historyList && g.historySearch == nil &&
+		g.historyRun == 0 && g.historyEdit == 0 {
+		return
+	}
+
+	entries, err := readHistory(g.historyFile)
+	g.reportFatalError("read the history file", g.historyFile, err)
+
+	if g.historyList || g.historySearch != nil {
+		listHistory(entries, g.historySearch)
+		os.Exit(0)
+	}
+
+	idx, args := g.historyRun, []string{}
+	if g.historyEdit != 0 {
+		idx, args = g.historyEdit, []string{"-" + paramNameEditScript}
+	}
+
+	i, err := historyIndex(idx, len(entries))
+	g.reportFatalError("find the history entry", g.historyFile, err)
+
+	os.Exit(rerunHistory(g, entries[i], args))
+}
+
+// listHistory prints the history entries which match the regular
+// expression (or all of them if it is nil)
+func listHistory(entries []historyEntry, re *regexp.Regexp) {
+	for i, he := range entries {
+		cmd := he.cmdLine()
+		if re != nil && !re.MatchString(cmd) && !re.MatchString(he.Dir) {
+			continue
+		}
+
+		fmt.Printf("%4d: %s  exit: %d  %s\n      %s\n",
+			i+1, he.Time.Format(time.DateTime), he.ExitStatus, he.Dir, cmd)
+	}
+}
+
+// rerunHistory runs gosh again with the arguments from the history entry,
+// preceded by any extra arguments, in the directory where the entry was
+// run. It returns the exit status of the re-run.
+func rerunHistory(g *gosh, he historyEntry, extraArgs []string) int {
+	defer g.dbgStack.Start("rerunHistory", "Re-running a history entry")()
+
+	intro := g.dbgStack.Tag()
+
+	goshPath, err := os.Executable()
+	g.reportFatalError("find the gosh executable", "", err)
+
+	args := append(extraArgs, he.Args...)
+
+	fmt.Fprintf(os.Stderr, "gosh: re-running in %s:\n    %s\n",
+		he.Dir, historyEntry{Args: args}.cmdLine())
+	verbose.Println(intro, " Command: "+goshPath+" "+strings.Join(args, " "))
+
+	cmd := exec.Command(goshPath, args...) //nolint:gosec
+	cmd.Dir = he.Dir
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	err = cmd.Run()
+	if err == nil {
+		return 0
+	}
+
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+
+	g.reportFatalError("re-run the history entry", he.cmdLine(), err)
+
+	return goshExitStatusMisc
+}
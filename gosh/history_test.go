@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestShellQuote(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		s   string
+		exp string
+	}{
+		{ID: testhelper.MkID("plain"), s: "-exec-file", exp: "-exec-file"},
+		{ID: testhelper.MkID("path"), s: "a/b.go", exp: "a/b.go"},
+		{ID: testhelper.MkID("empty"), s: "", exp: "''"},
+		{ID: testhelper.MkID("space"), s: "a b", exp: "'a b'"},
+		{ID: testhelper.MkID("quote"), s: "it's", exp: `'it'\''s'`},
+		{ID: testhelper.MkID("dollar"), s: "$x", exp: "'$x'"},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "quoted", shellQuote(tc.s), tc.exp)
+	}
+}
+
+func TestHistoryIndex(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		idx    int64
+		count  int
+		expIdx int
+	}{
+		{ID: testhelper.MkID("first"), idx: 1, count: 3, expIdx: 0},
+		{ID: testhelper.MkID("last"), idx: 3, count: 3, expIdx: 2},
+		{ID: testhelper.MkID("last, negative"), idx: -1, count: 3, expIdx: 2},
+		{ID: testhelper.MkID("first, negative"), idx: -3, count: 3, expIdx: 0},
+		{
+			ID:     testhelper.MkID("too big"),
+			idx:    4,
+			count:  3,
+			ExpErr: testhelper.MkExpErr("there is no history entry 4"),
+		},
+		{
+			ID:     testhelper.MkID("too small"),
+			idx:    -4,
+			count:  3,
+			ExpErr: testhelper.MkExpErr("there is no history entry -4"),
+		},
+		{
+			ID:     testhelper.MkID("empty"),
+			idx:    1,
+			count:  0,
+			ExpErr: testhelper.MkExpErr("there are 0 entries"),
+		},
+	}
+
+	for _, tc := range testCases {
+		i, err := historyIndex(tc.idx, tc.count)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffInt(t, tc.IDStr(), "index", i, tc.expIdx)
+		}
+	}
+}
+
+func TestReadWriteHistory(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "state", historyFileName)
+
+	entries, err := readHistory(fileName)
+	if err != nil {
+		t.Fatal("reading a missing history file should not fail:", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatal("a missing history file should have no entries")
+	}
+
+	now := time.Date(2026, time.March, 4, 5, 6, 7, 0, time.UTC)
+
+	for i := range historyMaxEntries + 2 {
+		entries = append(entries, historyEntry{
+			Time:       now,
+			Dir:        "/tmp",
+			Args:       []string{"-e", "fmt.Println(" + strconv.Itoa(i) + ")"},
+			ExitStatus: i % 2,
+		})
+	}
+
+	if err := writeHistory(fileName, entries); err != nil {
+		t.Fatal("cannot write the history:", err)
+	}
+
+	got, err := readHistory(fileName)
+	if err != nil {
+		t.Fatal("cannot read the history:", err)
+	}
+
+	if err := testhelper.DiffVals(got, entries[2:]); err != nil {
+		t.Errorf("the history read differs from that written: %s", err)
+	}
+
+	if err := os.WriteFile(fileName, []byte("not json\n"), 0o600); err != nil {
+		t.Fatal("cannot write the bad history file:", err)
+	}
+
+	_, err = readHistory(fileName)
+	if err == nil ||
+		!strings.Contains(err.Error(), "bad history entry at line 1") {
+		t.Errorf("a bad history file should give an error, got: %v", err)
+	}
+}
+
+func TestAppendHistory(t *testing.T) {
+	const appenders = 10
+
+	fileName := filepath.Join(t.TempDir(), "state", historyFileName)
+	lockName := fileName + historyLockSfx
+
+	var wg sync.WaitGroup
+
+	for i := range appenders {
+		wg.Go(func() {
+			err := appendHistory(fileName, historyEntry{
+				Dir:  "/tmp",
+				Args: []string{"-e", "fmt.Println(" + strconv.Itoa(i) + ")"},
+			})
+			if err != nil {
+				t.Error("cannot append to the history:", err)
+			}
+		})
+	}
+
+	wg.Wait()
+
+	entries, err := readHistory(fileName)
+	if err != nil {
+		t.Fatal("cannot read the history:", err)
+	}
+
+	testhelper.DiffInt(t, "concurrent appends", "entries",
+		len(entries), appenders)
+
+	if _, err := os.Stat(lockName); err == nil {
+		t.Error("the history lock file should have been removed")
+	}
+
+	if err := os.WriteFile(lockName, []byte{}, 0o600); err != nil {
+		t.Fatal("cannot write the lock file:", err)
+	}
+
+	stale := time.Now().Add(-2 * historyLockStale)
+	if err := os.Chtimes(lockName, stale, stale); err != nil {
+		t.Fatal("cannot age the lock file:", err)
+	}
+
+	if err := appendHistory(fileName, historyEntry{Dir: "/tmp"}); err != nil {
+		t.Fatal("a stale lock should not stop the history being added to:",
+			err)
+	}
+
+	entries, err = readHistory(fileName)
+	if err != nil {
+		t.Fatal("cannot read the history:", err)
+	}
+
+	testhelper.DiffInt(t, "stale lock", "entries",
+		len(entries), appenders+1)
+}
+
+func TestStateHome(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+
+	t.Setenv(xdgStateHomeEnvVar, "/var/state")
+	testhelper.DiffString(t, "absolute", "state home",
+		stateHome(), "/var/state")
+
+	t.Setenv(xdgStateHomeEnvVar, "relative/state")
+	testhelper.DiffString(t, "relative", "state home",
+		stateHome(), "/home/test/.local/state")
+
+	t.Setenv(xdgStateHomeEnvVar, "")
+	testhelper.DiffString(t, "unset", "state home",
+		stateHome(), "/home/test/.local/state")
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/nickwells/cli.mod/cli/responder"
 	"github.com/nickwells/gogen.mod/gogen"
+	"github.com/nickwells/param.mod/v7/param"
 	"github.com/nickwells/snippet.mod/snippet"
 	"github.com/nickwells/verbose.mod/verbose"
 )
@@ -21,14 +22,21 @@ func main() {
 	g := newGosh()
 	slp := &snippetListParams{}
 
-	ps := makeParamSet(g, slp)
+	var ps *param.PSet
+	if preCheckRequested(os.Args[1:]) {
+		ps = makePreCheckParamSet(g, slp)
+	} else {
+		ps = makeParamSet(g, slp)
+	}
 
-	ps.Parse()
+	parseErrs := ps.Parse()
 	g.HandleRemainder(ps.TrailingParams())
 
-	preCheck(g)
+	preCheck(g, ps, parseErrs)
 
 	listSnippets(g, slp)
+	listProfiles(g)
+	handleHistory(g)
 
 	defer func() { os.Exit(g.exitStatus) }()
 	defer g.dbgStack.Start("main", os.Args[0])()
@@ -40,6 +48,9 @@ func main() {
 	g.setEditor()
 	g.reportErrors()
 
+	g.setOfflineEnv()
+	g.setToolchain()
+
 	g.constructGoProgram()
 	g.reportErrors()
 
@@ -48,6 +59,7 @@ func main() {
 
 		g.editGoFile()
 		g.populateImports()
+		g.saveSnippet()
 		g.formatFile()
 		g.tidyModule()
 		g.runGoFile()
@@ -59,6 +71,7 @@ func main() {
 		g.chdirInto(g.goshDir)
 	}
 
+	g.recordHistory()
 	g.cleanup()
 }
 
@@ -237,7 +250,10 @@ func (g *gosh) createGoshTmpDir() {
 	g.initWorkspace()
 }
 
-// makeExecutable runs go build to make the executable file
+// makeExecutable runs go build to make the executable file. If the build
+// fails any compiler errors are reported against the parameter, section or
+// snippet that supplied the offending code. The raw compiler output is
+// available with the verbose parameter.
 func (g *gosh) makeExecutable() bool {
 	defer g.dbgStack.Start("makeExecutable", "Building the program")()
 
@@ -248,8 +264,14 @@ func (g *gosh) makeExecutable() bool {
 
 	verbose.Println(intro, " Command: go "+strings.Join(buildCmd, " "))
 
-	if !gogen.ExecGoCmdNoExit(gogen.ShowCmdIO, buildCmd...) {
-		verbose.Println(intro, " Build failed")
+	out, err := exec.Command( //nolint:gosec
+		gogen.GetGoCmdName(), buildCmd...).CombinedOutput()
+	if err != nil {
+		verbose.Println(intro, " Build failed: ", err)
+		verbose.Println(intro, " go build output:\n", string(out))
+
+		fmt.Fprintln(os.Stderr, "gosh: the program could not be built:")
+		fmt.Fprint(os.Stderr, mapGoshPositions(string(out), g.readGoshCode()))
 
 		g.exitStatus = goshExitStatusBuildFail
 		g.dontCleanup = true
@@ -257,16 +279,22 @@ func (g *gosh) makeExecutable() bool {
 		return false
 	}
 
+	os.Stderr.Write(out) //nolint:errcheck
+
 	return true
 }
 
-// runGoFile will call go build to generate the executable and then will run
-// it unless dontRun is set.
+// runGoFile will call go vet (if requested) and go build to generate the
+// executable and then will run it unless dontRun is set.
 func (g *gosh) runGoFile() {
 	defer g.dbgStack.Start("runGoFile", "Running the program")()
 
 	intro := g.dbgStack.Tag()
 
+	if !g.vetGoFile() {
+		return
+	}
+
 	if !g.makeExecutable() {
 		return
 	}
@@ -289,8 +317,8 @@ func (g *gosh) executeProgram() {
 	intro := g.dbgStack.Tag()
 
 	cmd := exec.Command( //nolint:gosec
-		filepath.Join(g.goshDir, g.execName), g.args...)
-	cmd.Stdin = os.Stdin
+		filepath.Join(g.goshDir, g.execName), g.programArgs()...)
+	cmd.Stdin = g.programStdin()
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	env := os.Environ()
@@ -428,6 +456,7 @@ func (g *gosh) initModule() {
 
 	verbose.Println(intro, " Command: go mod init "+g.execName)
 	gogen.ExecGoCmd(gogen.NoCmdIO, "mod", "init", g.execName)
+	g.setGoDirective()
 
 	keys := slices.Sorted(maps.Keys(g.localModules))
 
@@ -443,6 +472,8 @@ func (g *gosh) initModule() {
 				"-replace="+importPath+"="+g.localModules[k])
 		}
 	}
+
+	g.getImportVersions()
 }
 
 // initWorkspace initialises the workspace file if any workspace use values
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/gogen.mod/gogen"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// checkImportVersion checks that any version given with the import (after
+// an '@') is not empty and that there is only one version.
+func checkImportVersion(v string) error {
+	_, version, ok := strings.Cut(v, "@")
+	if !ok {
+		return nil
+	}
+
+	if version == "" {
+		return errors.New(
+			"an import of the form import@version" +
+				" must have a non-empty version")
+	}
+
+	if strings.Contains(version, "@") {
+		return errors.New(
+			"an import of the form import@version" +
+				" must have only one version")
+	}
+
+	return nil
+}
+
+// splitImportVersions removes any versions from the imports and returns
+// the imports without the versions and a map from the import path (without
+// any alias) to the version. It returns a non-nil error if the same import
+// is given with different versions.
+func splitImportVersions(imports []string) (
+	[]string, map[string]string, error,
+) {
+	var versions map[string]string
+
+	unversioned := make([]string, 0, len(imports))
+
+	for _, imp := range imports {
+		imp, version, ok := strings.Cut(imp, "@")
+		unversioned = append(unversioned, imp)
+
+		if !ok {
+			continue
+		}
+
+		_, path, hasAlias := strings.Cut(imp, "=")
+		if !hasAlias {
+			path = imp
+		}
+
+		if versions == nil {
+			versions = map[string]string{}
+		}
+
+		if v, ok := versions[path]; ok && v != version {
+			return nil, nil,
+				fmt.Errorf("the import %q is given with different versions:"+
+					" %q and %q",
+					path, v, version)
+		}
+
+		versions[path] = version
+	}
+
+	return unversioned, versions, nil
+}
+
+// setImportVersions removes any versions from the imports and records
+// them so that they can be fetched before the module is tidied.
+func (g *gosh) setImportVersions() error {
+	imports, versions, err := splitImportVersions(g.imports)
+	if err != nil {
+		return err
+	}
+
+	g.imports = imports
+	g.importVersions = versions
+
+	return nil
+}
+
+// setOfflineEnv sets the environment so that the go commands which gosh
+// runs will only use the local module cache.
+func (g *gosh) setOfflineEnv() {
+	if !g.offline {
+		return
+	}
+
+	defer g.dbgStack.Start("setOfflineEnv",
+		"Setting the environment for offline use")()
+
+	intro := g.dbgStack.Tag()
+
+	goFlags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -mod=mod")
+
+	verbose.Println(intro, " Setting GOPROXY=off")
+	verbose.Println(intro, " Setting GOFLAGS="+goFlags)
+
+	err := os.Setenv("GOPROXY", "off")
+	g.reportFatalError("set the environment variable", "GOPROXY", err)
+
+	err = os.Setenv("GOFLAGS", goFlags)
+	g.reportFatalError("set the environment variable", "GOFLAGS", err)
+}
+
+// getImportVersions runs go get for each import which was given with a
+// version. This adds the required version to the go.mod file before the
+// module is tidied.
+func (g *gosh) getImportVersions() {
+	if len(g.importVersions) == 0 {
+		return
+	}
+
+	defer g.dbgStack.Start("getImportVersions",
+		"Getting the requested import versions")()
+
+	intro := g.dbgStack.Tag()
+
+	for _, path := range slices.Sorted(maps.Keys(g.importVersions)) {
+		pv := path + "@" + g.importVersions[path]
+
+		verbose.Println(intro, " Command: go get "+pv)
+
+		out, err := exec.Command( //nolint:gosec
+			gogen.GetGoCmdName(), "get", pv).CombinedOutput()
+		if err == nil {
+			continue
+		}
+
+		verbose.Println(intro, " go get output:\n", string(out))
+
+		if g.offline && strings.Contains(string(out), "GOPROXY=off") {
+			err = fmt.Errorf(
+				"the version is not available in the local module cache"+
+					" (gosh is running offline): %w\n%s",
+				err, out)
+		} else {
+			err = fmt.Errorf("%w\n%s", err, out)
+		}
+
+		g.reportFatalError("get the import version", pv, err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCheckImportVersion(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		imp string
+	}{
+		{
+			ID:  testhelper.MkID("no version"),
+			imp: "a/b",
+		},
+		{
+			ID:  testhelper.MkID("version"),
+			imp: "a/b@v1.2.3",
+		},
+		{
+			ID:  testhelper.MkID("alias and version"),
+			imp: "x=a/b@latest",
+		},
+		{
+			ID:     testhelper.MkID("empty version"),
+			imp:    "a/b@",
+			ExpErr: testhelper.MkExpErr("must have a non-empty version"),
+		},
+		{
+			ID:     testhelper.MkID("two versions"),
+			imp:    "a/b@v1@v2",
+			ExpErr: testhelper.MkExpErr("must have only one version"),
+		},
+	}
+
+	for _, tc := range testCases {
+		err := checkImportVersion(tc.imp)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestSplitImportVersions(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		imports     []string
+		expImports  []string
+		expVersions map[string]string
+	}{
+		{
+			ID:         testhelper.MkID("no versions"),
+			imports:    []string{"a/b", "x=c/d"},
+			expImports: []string{"a/b", "x=c/d"},
+		},
+		{
+			ID:         testhelper.MkID("versions"),
+			imports:    []string{"a/b@v1.2.3", "x=c/d@latest", "e/f"},
+			expImports: []string{"a/b", "x=c/d", "e/f"},
+			expVersions: map[string]string{
+				"a/b": "v1.2.3",
+				"c/d": "latest",
+			},
+		},
+		{
+			ID:         testhelper.MkID("repeated version"),
+			imports:    []string{"a/b@v1.2.3", "y=a/b@v1.2.3"},
+			expImports: []string{"a/b", "y=a/b"},
+			expVersions: map[string]string{
+				"a/b": "v1.2.3",
+			},
+		},
+		{
+			ID:      testhelper.MkID("conflicting versions"),
+			imports: []string{"a/b@v1.2.3", "a/b@v1.2.4"},
+			ExpErr: testhelper.MkExpErr(
+				`the import "a/b" is given with different versions`),
+		},
+	}
+
+	for _, tc := range testCases {
+		imports, versions, err := splitImportVersions(tc.imports)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffStringSlice(t, tc.IDStr(), "imports",
+				imports, tc.expImports)
+
+			if err := testhelper.DiffVals(versions, tc.expVersions); err != nil {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: versions: %s", err)
+			}
+		}
+	}
+}
@@ -20,9 +20,15 @@ func paramOptFuncs(g *gosh, slp *snippetListParams) []param.PSetOptFunc {
 
 		addSnippetListParams(slp),
 		addSnippetParams(g),
+		addSnippetSaveParams(g),
+		addProfileParams(g),
+		addHistoryParams(g),
 		addWebParams(g),
 		addReadloopParams(g),
 		addGoshParams(g),
+		addProfilingParams(g),
+		addProgParamParams(g),
+		addTemplateParams(g),
 		addStdinParams(g),
 		addParams(g),
 
@@ -53,7 +59,13 @@ func paramOptFuncs(g *gosh, slp *snippetListParams) []param.PSetOptFunc {
 				" command and if that is sufficient then just stop. If" +
 				" you need to do more then save the file and edit it just" +
 				" like a regular Go program."),
+	}
+}
 
+// configFileOptFuncs returns the option functions which add the gosh
+// configuration files to the paramset
+func configFileOptFuncs() []param.PSetOptFunc {
+	return []param.PSetOptFunc{
 		SetGlobalConfigFile,
 		SetConfigFile,
 	}
@@ -61,5 +73,15 @@ func paramOptFuncs(g *gosh, slp *snippetListParams) []param.PSetOptFunc {
 
 // makeParamSet creates the parameter set ready for argument parsing
 func makeParamSet(g *gosh, slp *snippetListParams) *param.PSet {
-	return paramset.New(paramOptFuncs(g, slp)...)
+	return paramset.New(
+		append(paramOptFuncs(g, slp), configFileOptFuncs()...)...)
+}
+
+// makePreCheckParamSet creates the parameter set used when the pre-check
+// has been requested. Any errors found while parsing the parameters do not
+// stop the program so that problems in the configuration files can be
+// given in the pre-check report.
+func makePreCheckParamSet(g *gosh, slp *snippetListParams) *param.PSet {
+	return paramset.NewNoHelpNoExitNoErrRpt(
+		append(paramOptFuncs(g, slp), configFileOptFuncs()...)...)
 }
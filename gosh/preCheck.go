@@ -1,49 +1,177 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/nickwells/errutil.mod/errutil"
+	"github.com/nickwells/filecheck.mod/filecheck"
 	"github.com/nickwells/gogen.mod/gogen"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/paramset"
 	"github.com/nickwells/twrap.mod/twrap"
 )
 
 const (
 	preChkStdIndent  = 4
 	preChkListIndent = 8
+
+	worldWritable = 0o002
+
+	// paramFinalChecksCat is the category of the errors found by the
+	// final checks when the parameters are parsed
+	paramFinalChecksCat = "Final Checks"
 )
 
+// preChkResult records the outcome of a single check
+type preChkResult struct {
+	Name    string   `json:"name"`
+	OK      bool     `json:"ok"`
+	Problem string   `json:"problem,omitempty"`
+	Details []string `json:"details,omitempty"`
+}
+
+// preChkReport records the outcome of all the checks
+type preChkReport struct {
+	OK      bool           `json:"ok"`
+	Results []preChkResult `json:"results"`
+}
+
+// preChkEnv holds the values which are shared between the checks
+type preChkEnv struct {
+	ps        *param.PSet
+	parseErrs errutil.ErrMap
+	goEnv     map[string]string
+	goEnvErr  error
+}
+
+// preChk describes a check. The check func performs the check and the
+// advise func reports the problem and describes potential remedies.
+type preChk struct {
+	name   string
+	title  string
+	check  func(g *gosh, env preChkEnv) preChkResult
+	advise func(g *gosh, twc *twrap.TWConf, r preChkResult)
+}
+
+// preChecks returns the checks to be performed in the order in which they
+// are to be run
+func preChecks() []preChk {
+	return []preChk{
+		{
+			name:   "parameters",
+			title:  "The parameters",
+			check:  checkParams,
+			advise: adviseParams,
+		},
+		{
+			name:   "go-command",
+			title:  "The Go command",
+			check:  checkGoCmd,
+			advise: adviseGoCmd,
+		},
+		{
+			name:   "go-environment",
+			title:  "The Go environment",
+			check:  checkGoEnv,
+			advise: adviseGoEnv,
+		},
+		{
+			name:   "module-cache",
+			title:  "The module cache",
+			check:  checkModCache,
+			advise: adviseModCache,
+		},
+		{
+			name:   "import-populator",
+			title:  "The import populator",
+			check:  checkImporter,
+			advise: adviseImporter,
+		},
+		{
+			name:   "formatter",
+			title:  "The formatter",
+			check:  checkFormatter,
+			advise: adviseFormatter,
+		},
+		{
+			name:   "base-temp-dir",
+			title:  "The temporary directory",
+			check:  checkBaseTempDir,
+			advise: adviseBaseTempDir,
+		},
+		{
+			name:   "config-files",
+			title:  "Configuration files",
+			check:  checkConfigFiles,
+			advise: adviseConfigFiles,
+		},
+		{
+			name:   "snippets",
+			title:  "Snippets",
+			check:  checkSnippets,
+			advise: adviseSnippets,
+		},
+		{
+			name:   "snippet-permissions",
+			title:  "Snippet directory permissions",
+			check:  checkSnippetPerms,
+			advise: adviseSnippetPerms,
+		},
+	}
+}
+
 // PreCheck will test the availability of the various components that gosh
 // needs and make recommendations as to how to fix any missing components.
-func preCheck(g *gosh) {
+// If the preCheckJSON flag is set the results are reported as JSON rather
+// than as text. Any errors found when the parameters were parsed are
+// reported as one of the results.
+func preCheck(g *gosh, ps *param.PSet, parseErrs errutil.ErrMap) {
 	if !g.preCheck {
 		return
 	}
 
-	var problemsFound bool
+	env := preChkEnv{ps: ps, parseErrs: parseErrs}
+	env.goEnv, env.goEnvErr = goEnvVals("GO111MODULE", "GOFLAGS", "GOMODCACHE")
 
-	exitStatus := 0
+	report := preChkReport{OK: true}
 	twc := twrap.NewTWConfOrPanic()
 
-	if goCmdBad(twc) {
-		problemsFound = true
-		exitStatus = goshExitStatusPreCheck
-	}
+	for _, pc := range preChecks() {
+		r := pc.check(g, env)
+		r.Name = pc.name
+		report.Results = append(report.Results, r)
 
-	if importersBad(g, twc) {
-		problemsFound = true
-		exitStatus = goshExitStatusPreCheck
-	}
+		if r.OK {
+			continue
+		}
 
-	if snippetsBad(g, twc) {
-		problemsFound = true
-		exitStatus = goshExitStatusPreCheck
+		report.OK = false
+
+		if !g.preCheckJSON {
+			fmt.Print(pc.title + "\n\n")
+			pc.advise(g, twc, r)
+			fmt.Println()
+		}
 	}
 
-	if problemsFound {
+	if g.preCheckJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't report the pre-check results:",
+				err)
+			os.Exit(goshExitStatusMisc)
+		}
+
+		fmt.Println(string(out))
+	} else if !report.OK {
 		fmt.Print("Setting parameters in configuration files\n\n")
 		twc.Wrap("Parameters can be set through the command line but also"+
 			" through entries in the gosh configuration files. These are"+
@@ -59,21 +187,84 @@ func preCheck(g *gosh) {
 			preChkStdIndent)
 	}
 
+	exitStatus := 0
+	if !report.OK {
+		exitStatus = goshExitStatusPreCheck
+	}
+
 	os.Exit(exitStatus)
 }
 
-// goCmdBad checks for problems with the Go command. If it is available and
-// executable it returns false. Otherwise it reports the problem, describes
-// potential remedies and returns true.
-func goCmdBad(twc *twrap.TWConf) bool {
+// preCheckRequested returns true if the pre-check parameters are given in
+// the arguments. It is called before the parameters are parsed so that a
+// parameter set which will not stop on errors can be used; the errors can
+// then be given in the pre-check report.
+func preCheckRequested(args []string) bool {
+	preChkNames := []string{
+		paramNamePreCheck,
+		paramNamePreCheckJSON,
+		paramNamePreCheckJSONAlt,
+	}
+
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if slices.Contains(preChkNames, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// goEnvVals returns the values of the named Go environment variables as
+// reported by the Go command. This takes account of any values set with
+// 'go env -w' as well as those in the environment.
+func goEnvVals(names ...string) (map[string]string, error) {
+	goCmd := gogen.GetGoCmdName()
+
+	args := append([]string{"env", "-json"}, names...)
+
+	out, err := exec.Command(goCmd, args...).Output() //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("cannot run '%s env': %w", goCmd, err)
+	}
+
+	vals := map[string]string{}
+	if err := json.Unmarshal(out, &vals); err != nil {
+		return nil, fmt.Errorf("cannot parse the output of '%s env': %w",
+			goCmd, err)
+	}
+
+	return vals, nil
+}
+
+// checkGoCmd checks that the Go command is available and executable
+func checkGoCmd(_ *gosh, _ preChkEnv) preChkResult {
 	goCmd := gogen.GetGoCmdName()
 
 	_, err := exec.LookPath(goCmd)
 	if err == nil {
-		return false
+		return preChkResult{OK: true}
 	}
 
-	fmt.Print("The Go command\n\n")
+	return preChkResult{
+		Problem: fmt.Sprintf(
+			"%q is not executable or is not found in your PATH", goCmd),
+		Details: []string{err.Error()},
+	}
+}
+
+// adviseGoCmd describes the remedies for a missing Go command
+func adviseGoCmd(_ *gosh, twc *twrap.TWConf, _ preChkResult) {
+	goCmd := gogen.GetGoCmdName()
 
 	twc.Wrap("'"+goCmd+"' is not executable or is not"+
 		" found in your PATH. You should either",
@@ -86,32 +277,191 @@ func goCmdBad(twc *twrap.TWConf) bool {
 	twc.Wrap("If the Go command is not installed on your computer you"+
 		" will need to install it for gosh to work",
 		preChkStdIndent)
-	fmt.Println()
+}
 
-	return true
+// checkGoEnv checks that the Go environment settings are compatible with
+// the way that gosh builds programs
+func checkGoEnv(g *gosh, env preChkEnv) preChkResult {
+	if env.goEnvErr != nil {
+		return preChkResult{
+			Problem: "cannot get the Go environment settings",
+			Details: []string{env.goEnvErr.Error()},
+		}
+	}
+
+	problems := goEnvProblems(g,
+		env.goEnv["GO111MODULE"], env.goEnv["GOFLAGS"], os.Getenv("GOWORK"))
+	if len(problems) == 0 {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "the Go environment settings will interfere with gosh",
+		Details: problems,
+	}
+}
+
+// goEnvProblems returns a description of each way in which the values of
+// GO111MODULE, GOFLAGS and GOWORK conflict with the way that gosh builds
+// programs. gosh generates each program in a new module (unless module
+// mode is turned off) with no vendor directory and no workspace (unless
+// workspace directories are given).
+func goEnvProblems(g *gosh, go111module, goflags, gowork string) []string {
+	problems := []string{}
+
+	if go111module == "off" {
+		moduleParams := []string{}
+
+		if len(g.localModules) > 0 {
+			moduleParams = append(moduleParams, "local modules")
+		}
+
+		if len(g.importVersions) > 0 {
+			moduleParams = append(moduleParams, "import versions")
+		}
+
+		if len(g.workspace) > 0 {
+			moduleParams = append(moduleParams, "workspace directories")
+		}
+
+		if g.goVersion != "" {
+			moduleParams = append(moduleParams, "a Go version")
+		}
+
+		if len(moduleParams) > 0 {
+			problems = append(problems,
+				"GO111MODULE is 'off' so no module will be created but"+
+					" module settings have been given: "+
+					strings.Join(moduleParams, ", "))
+		}
+	}
+
+	for _, flag := range strings.Fields(goflags) {
+		name, val, _ := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+
+		switch name {
+		case "mod":
+			if val == "vendor" {
+				problems = append(problems,
+					"GOFLAGS contains '"+flag+"' but the generated"+
+						" program has no vendor directory")
+			}
+
+			if val == "readonly" && g.dontRunGoModTidy {
+				problems = append(problems,
+					"GOFLAGS contains '"+flag+"' and 'go mod tidy' is"+
+						" not being run so the go.mod file of the"+
+						" generated program cannot be updated")
+			}
+		case "modfile":
+			problems = append(problems,
+				"GOFLAGS contains '"+flag+"' so the go.mod file that"+
+					" gosh creates will not be used")
+		}
+	}
+
+	if gowork != "" && gowork != "off" {
+		problems = append(problems,
+			"GOWORK is set to '"+gowork+"' so the workspace file that"+
+				" gosh creates (if any) will not be used and the"+
+				" generated program may not be part of the workspace")
+	}
+
+	return problems
+}
+
+// adviseGoEnv describes the remedies for Go environment settings which
+// interfere with gosh
+func adviseGoEnv(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap("The Go environment has settings which will interfere with"+
+		" the way that gosh builds programs:",
+		preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("These values may be set in the environment or with"+
+		" 'go env -w'. You should change them (or unset them) for gosh,"+
+		" for instance by running gosh with the environment variable set"+
+		" to a different value.",
+		preChkStdIndent)
 }
 
-// importersBad checks for problems with the importers. If a valid importer is
-// available it returns false. Otherwise it reports the problem, describes
-// potential remedies and returns true.
-//
-// It will skip the check if the dontPopulateImports flag is set.
-func importersBad(g *gosh, twc *twrap.TWConf) bool {
+// checkModCache checks that the module cache exists and that gosh can
+// write to it
+func checkModCache(_ *gosh, env preChkEnv) preChkResult {
+	if env.goEnvErr != nil {
+		return preChkResult{
+			Problem: "cannot find the module cache",
+			Details: []string{env.goEnvErr.Error()},
+		}
+	}
+
+	dir := env.goEnv["GOMODCACHE"]
+	if dir == "" {
+		return preChkResult{Problem: "GOMODCACHE is not set"}
+	}
+
+	_, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		// the Go command will create it when it is first needed
+		return preChkResult{OK: true}
+	}
+
+	if err == nil {
+		err = dirWritable(dir)
+	}
+
+	if err != nil {
+		return preChkResult{
+			Problem: fmt.Sprintf("the module cache (%q) is not usable", dir),
+			Details: []string{err.Error()},
+		}
+	}
+
+	return preChkResult{OK: true}
+}
+
+// adviseModCache describes the remedies for an unusable module cache
+func adviseModCache(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap(r.Problem+":", preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("The Go command will not be able to download any modules"+
+		" that the generated program imports. You should either"+
+		" correct the permissions on the module cache or else set"+
+		" GOMODCACHE to a directory that you can write to.",
+		preChkStdIndent)
+}
+
+// checkImporter checks that an import populator is available. It will skip
+// the check if the dontPopulateImports flag is set.
+func checkImporter(g *gosh, _ preChkEnv) preChkResult {
 	if g.dontPopulateImports {
-		return false
+		return preChkResult{OK: true}
 	}
 
 	if g.importPopulatorSet {
 		_, err := exec.LookPath(g.importPopulator)
 		if err == nil {
-			return false
+			return preChkResult{OK: true}
+		}
+
+		return preChkResult{
+			Problem: fmt.Sprintf(
+				"the import populator (%q) cannot be found",
+				g.importPopulator),
+			Details: []string{err.Error()},
 		}
-	} else if _, _, ok := findImporter(g); ok {
-		return false
 	}
 
-	fmt.Print("The import populator\n\n")
+	if _, _, ok := findImporter(g); ok {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "none of the default import populators can be found",
+	}
+}
 
+// adviseImporter describes the remedies for a missing import populator
+func adviseImporter(g *gosh, twc *twrap.TWConf, _ preChkResult) {
 	twc.Wrap("There is no command to automatically populate the import"+
 		" statements. Although gosh can work without an import populator"+
 		" you will then need to give all the packages to be imported on"+
@@ -168,60 +518,341 @@ func importersBad(g *gosh, twc *twrap.TWConf) bool {
 	}
 
 	twc.List(iInstallCmds, preChkListIndent)
+}
 
+// checkFormatter checks that a formatter is available
+func checkFormatter(g *gosh, _ preChkEnv) preChkResult {
+	if g.formatterSet {
+		_, err := exec.LookPath(g.formatter)
+		if err == nil {
+			return preChkResult{OK: true}
+		}
+
+		return preChkResult{
+			Problem: fmt.Sprintf("the formatter (%q) cannot be found",
+				g.formatter),
+			Details: []string{err.Error()},
+		}
+	}
+
+	if _, _, ok := findFormatter(g); ok {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "none of the default formatters can be found",
+	}
+}
+
+// adviseFormatter describes the remedies for a missing formatter
+func adviseFormatter(g *gosh, twc *twrap.TWConf, _ preChkResult) {
+	twc.Wrap("There is no command to format the generated program. This"+
+		" is only needed if you ask gosh to format the code (with"+
+		" the '"+paramNameFormat+"' parameter).",
+		preChkStdIndent)
 	fmt.Println()
 
-	return true
+	if g.formatterSet {
+		twc.Wrap("You have set the formatter command"+
+			" as '"+g.formatter+"' but this cannot be found. Either you"+
+			" have entered the command incorrectly or else it cannot be"+
+			" found in your PATH.",
+			preChkStdIndent)
+		fmt.Println()
+	}
+
+	twc.Wrap("By default gosh will search for one of the following"+
+		" commands: "+formatterCmds()+". The 'gofmt' command is"+
+		" installed with Go so you should change the value of your"+
+		" PATH to include the directory containing it or else give the"+
+		" full pathname of a formatter with"+
+		" the '"+paramNameFormatter+"' parameter.",
+		preChkStdIndent)
 }
 
-// snippetsBad checks for problems with the snippets. If some snippets are
-// available and all the directories can be searched it returns
-// false. Otherwise it reports the problem, describes potential remedies and
-// returns true.
-func snippetsBad(g *gosh, twc *twrap.TWConf) bool {
-	snippetCount := 0
+// checkBaseTempDir checks that gosh can create the directories in which
+// the program is generated
+func checkBaseTempDir(g *gosh, _ preChkEnv) preChkResult {
+	dir := g.baseTempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	if err := dirWritable(dir); err != nil {
+		return preChkResult{
+			Problem: fmt.Sprintf(
+				"cannot create a directory in %q", dir),
+			Details: []string{err.Error()},
+		}
+	}
+
+	return preChkResult{OK: true}
+}
+
+// dirWritable returns a non-nil error if a new directory cannot be created
+// in the given directory. The new directory is removed after it is created.
+func dirWritable(dir string) error {
+	tmpDir, err := os.MkdirTemp(dir, "gosh-pre-check-*.d")
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(tmpDir)
+}
+
+// adviseBaseTempDir describes the remedies for an unusable temporary
+// directory
+func adviseBaseTempDir(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap("gosh generates the program in a new directory created in"+
+		" the base temporary directory but it "+r.Problem+":",
+		preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("You should either correct the permissions on this"+
+		" directory or else choose a different directory with"+
+		" the '"+paramNameBaseTempDir+"' parameter.",
+		preChkStdIndent)
+}
+
+// errMapDetails returns the errors in the error map, ordered by category,
+// as a list of strings. Any errors in the final checks category are
+// skipped if skipFinal is true.
+func errMapDetails(errs errutil.ErrMap, skipFinal bool) []string {
+	details := []string{}
+
+	cats := errs.Keys()
+	slices.Sort(cats)
+
+	for _, cat := range cats {
+		if skipFinal && cat == paramFinalChecksCat {
+			continue
+		}
+
+		for _, err := range errs[cat] {
+			details = append(details, err.Error())
+		}
+	}
+
+	return details
+}
+
+// checkParams checks that there were no errors when the parameters were
+// parsed
+func checkParams(_ *gosh, env preChkEnv) preChkResult {
+	problems := errMapDetails(env.parseErrs, false)
+	if len(problems) == 0 {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "some parameters cannot be parsed",
+		Details: problems,
+	}
+}
+
+// adviseParams describes the remedies for parameters which cannot be
+// parsed
+func adviseParams(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap("The following problems were found with the parameters:",
+		preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("You should correct the parameters given on the command"+
+		" line. Problems in the configuration files are also given"+
+		" line by line in the check of those files.",
+		preChkStdIndent)
+}
+
+// configFileProblems parses the named configuration file into a new
+// parameter set and returns a description of each problem found. Every
+// parameter in the file must be a gosh parameter which can be set in a
+// configuration file and must have a valid value.
+func configFileProblems(fName string) []string {
+	ps := paramset.NewNoHelpNoExitNoErrRpt(
+		paramOptFuncs(newGosh(), &snippetListParams{})...)
+	ps.AddConfigFileStrict(fName, filecheck.Optional)
+
+	return errMapDetails(ps.Parse([]string{}), true)
+}
+
+// checkConfigFiles checks that the configuration files, if they exist, can
+// be read and that every line in them is valid
+func checkConfigFiles(_ *gosh, env preChkEnv) preChkResult {
+	if env.ps == nil {
+		return preChkResult{OK: true}
+	}
+
+	problems := []string{}
+
+	for _, cf := range env.ps.ConfigFiles() {
+		_, err := os.ReadFile(cf.Name) //nolint:gosec
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		problems = append(problems, configFileProblems(cf.Name)...)
+	}
 
-	var snippetErrs error
+	if len(problems) == 0 {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "there are problems with the configuration files",
+		Details: problems,
+	}
+}
+
+// adviseConfigFiles describes the remedies for problems with the
+// configuration files
+func adviseConfigFiles(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap("The following problems were found in the gosh"+
+		" configuration files:",
+		preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("Each line in a configuration file should be blank, a"+
+		" comment (starting with '//') or a parameter name optionally"+
+		" followed by '=' and a value. You should correct or remove"+
+		" the lines in error and correct the permissions on any file"+
+		" which cannot be read.",
+		preChkStdIndent)
+}
+
+// checkSnippets checks that some snippets are available and that all the
+// directories can be searched
+func checkSnippets(g *gosh, _ preChkEnv) preChkResult {
+	snippetCount := 0
+	problems := []string{}
 
 	for _, dir := range g.snippetDirs {
 		count, err := countSnippets(0, dir)
 		snippetCount += count
-		snippetErrs = errors.Join(snippetErrs, err)
+
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return preChkResult{
+			Problem: "there is a problem with your snippet directories",
+			Details: problems,
+		}
 	}
 
-	if snippetErrs == nil && snippetCount > 0 {
-		return false
+	if snippetCount == 0 {
+		return preChkResult{Problem: "you have no snippets installed"}
 	}
 
-	fmt.Print("Snippets\n\n")
+	return preChkResult{OK: true}
+}
 
-	if snippetErrs != nil {
+// adviseSnippets describes the remedies for problems with the snippets
+func adviseSnippets(g *gosh, twc *twrap.TWConf, r preChkResult) {
+	if len(r.Details) > 0 {
 		twc.Wrap("There is a problem with your snippet directories: "+
-			snippetErrs.Error(), preChkStdIndent)
-	} else if snippetCount == 0 {
-		twc.Wrap("You have no snippets installed. You can use 'gosh'"+
-			" without snippets but you may find them useful. Use the"+
-			" 'gosh.snippet'"+
-			" program to install the standard snippets. You will need to"+
-			" install this program in the same way you installed gosh."+
-			" You can install the snippets in one of these directories:",
-			preChkStdIndent)
-		twc.List(g.snippetDirs, preChkListIndent)
-		twc.Wrap("Choose one of these to install the standard snippets"+
-			" and then run the gosh.snippet program as follows:"+
-			"\n\n"+
-			"    gosh.snippet -to <dir> -install"+
-			"\n\n"+
-			"Alternatively if you have snippets in another directory or"+
-			" you have the standard snippets alredy installed elsewhere"+
-			" you can add to the list of directories that gosh will"+
-			" search by using the '"+paramNameSnippetDir+"' parameter.",
-			preChkStdIndent)
+			strings.Join(r.Details, "\n"), preChkStdIndent)
+
+		return
 	}
 
-	fmt.Println()
+	twc.Wrap("You have no snippets installed. You can use 'gosh'"+
+		" without snippets but you may find them useful. Use the"+
+		" 'gosh.snippet'"+
+		" program to install the standard snippets. You will need to"+
+		" install this program in the same way you installed gosh."+
+		" You can install the snippets in one of these directories:",
+		preChkStdIndent)
+	twc.List(g.snippetDirs, preChkListIndent)
+	twc.Wrap("Choose one of these to install the standard snippets"+
+		" and then run the gosh.snippet program as follows:"+
+		"\n\n"+
+		"    gosh.snippet -to <dir> -install"+
+		"\n\n"+
+		"Alternatively if you have snippets in another directory or"+
+		" you have the standard snippets alredy installed elsewhere"+
+		" you can add to the list of directories that gosh will"+
+		" search by using the '"+paramNameSnippetDir+"' parameter.",
+		preChkStdIndent)
+}
+
+// checkSnippetPerms checks that the snippet directories are directories
+// and that none of the snippets can be changed by other users
+func checkSnippetPerms(g *gosh, _ preChkEnv) preChkResult {
+	problems := []string{}
+
+	for _, dir := range g.snippetDirs {
+		problems = append(problems, snippetPermProblems(dir)...)
+	}
 
-	return true
+	if len(problems) == 0 {
+		return preChkResult{OK: true}
+	}
+
+	return preChkResult{
+		Problem: "the snippet directory permissions are unsafe",
+		Details: problems,
+	}
+}
+
+// snippetPermProblems returns a description of each problem with the
+// permissions of the snippet directory and the files and directories it
+// contains. A missing directory has no problems.
+func snippetPermProblems(dir string) []string {
+	problems := []string{}
+
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return problems
+	}
+
+	if err != nil {
+		return append(problems, err.Error())
+	}
+
+	if !info.IsDir() {
+		return append(problems,
+			fmt.Sprintf("the snippet directory %q is not a directory", dir))
+	}
+
+	_ = filepath.WalkDir(dir,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// unreadable directories are reported by the snippets check
+				return nil //nolint:nilerr
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			if info.Mode().Perm()&worldWritable != 0 {
+				problems = append(problems,
+					fmt.Sprintf("%q can be changed by any user", path))
+			}
+
+			return nil
+		})
+
+	return problems
+}
+
+// adviseSnippetPerms describes the remedies for unsafe snippet
+// directory permissions
+func adviseSnippetPerms(_ *gosh, twc *twrap.TWConf, r preChkResult) {
+	twc.Wrap("The following problems were found with the permissions"+
+		" of the snippet directories:",
+		preChkStdIndent)
+	twc.List(r.Details, preChkListIndent)
+	twc.Wrap("Snippets are copied into the programs that gosh generates"+
+		" and so anyone who can change them can change what those"+
+		" programs do. You should remove write permission for other"+
+		" users, for instance with 'chmod o-w'.",
+		preChkStdIndent)
 }
 
 // countSnippets returns the number of snippet files found and any errors
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestGoEnvProblems(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		goshSetter  func(g *gosh)
+		go111module string
+		goflags     string
+		gowork      string
+		expProblems []string
+	}{
+		{
+			ID: testhelper.MkID("no problems"),
+		},
+		{
+			ID:          testhelper.MkID("modules off, no module settings"),
+			go111module: "off",
+		},
+		{
+			ID: testhelper.MkID("modules off, module settings"),
+			goshSetter: func(g *gosh) {
+				g.localModules = map[string]string{"example.com/m": "../m"}
+				g.goVersion = "1.22"
+			},
+			go111module: "off",
+			expProblems: []string{
+				"GO111MODULE is 'off' so no module will be created but" +
+					" module settings have been given:" +
+					" local modules, a Go version",
+			},
+		},
+		{
+			ID:      testhelper.MkID("vendor and modfile"),
+			goflags: "-v -mod=vendor -modfile=x.mod",
+			expProblems: []string{
+				"GOFLAGS contains '-mod=vendor' but the generated" +
+					" program has no vendor directory",
+				"GOFLAGS contains '-modfile=x.mod' so the go.mod file" +
+					" that gosh creates will not be used",
+			},
+		},
+		{
+			ID:      testhelper.MkID("readonly, tidy is run"),
+			goflags: "-mod=readonly",
+		},
+		{
+			ID:         testhelper.MkID("readonly, tidy is not run"),
+			goshSetter: func(g *gosh) { g.dontRunGoModTidy = true },
+			goflags:    "-mod=readonly",
+			expProblems: []string{
+				"GOFLAGS contains '-mod=readonly' and 'go mod tidy' is" +
+					" not being run so the go.mod file of the" +
+					" generated program cannot be updated",
+			},
+		},
+		{
+			ID:     testhelper.MkID("GOWORK off"),
+			gowork: "off",
+		},
+		{
+			ID:     testhelper.MkID("GOWORK set"),
+			gowork: "/tmp/go.work",
+			expProblems: []string{
+				"GOWORK is set to '/tmp/go.work' so the workspace file" +
+					" that gosh creates (if any) will not be used and the" +
+					" generated program may not be part of the workspace",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		g := newGosh()
+		if tc.goshSetter != nil {
+			tc.goshSetter(g)
+		}
+
+		problems := goEnvProblems(g, tc.go111module, tc.goflags, tc.gowork)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "problems",
+			problems, tc.expProblems)
+	}
+}
+
+func TestSnippetPermProblems(t *testing.T) {
+	dir := t.TempDir()
+
+	safeFile := filepath.Join(dir, "safe")
+	unsafeFile := filepath.Join(dir, "unsafe")
+
+	for _, f := range []string{safeFile, unsafeFile} {
+		if err := os.WriteFile(f, []byte("x"), 0o600); err != nil {
+			t.Fatal("cannot create the snippet file: ", err)
+		}
+	}
+
+	if err := os.Chmod(unsafeFile, 0o666); err != nil {
+		t.Fatal("cannot change the snippet file permissions: ", err)
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		dir         string
+		expProblems []string
+	}{
+		{
+			ID:  testhelper.MkID("missing dir"),
+			dir: filepath.Join(dir, "nonesuch"),
+		},
+		{
+			ID:  testhelper.MkID("not a dir"),
+			dir: safeFile,
+			expProblems: []string{
+				`the snippet directory "` + safeFile + `" is not a directory`,
+			},
+		},
+		{
+			ID:  testhelper.MkID("world-writable file"),
+			dir: dir,
+			expProblems: []string{
+				`"` + unsafeFile + `" can be changed by any user`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		problems := snippetPermProblems(tc.dir)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "problems",
+			problems, tc.expProblems)
+	}
+}
+
+func TestDirWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := dirWritable(dir); err != nil {
+		t.Error("the temporary directory should be writable: ", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal("cannot read the temporary directory: ", err)
+	}
+
+	if len(entries) != 0 {
+		t.Error("the directory created by the check was not removed")
+	}
+
+	if err := dirWritable(filepath.Join(dir, "nonesuch")); err == nil {
+		t.Error("a missing directory should not be writable")
+	}
+}
+
+func TestConfigFileProblems(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		content     string
+		expProblems int
+	}{
+		{
+			ID: testhelper.MkID("good"),
+			content: "// a comment\n" +
+				"\n" +
+				"add-comments\n",
+		},
+		{
+			ID: testhelper.MkID("bad"),
+			content: "add-comments\n" +
+				"nonesuch=1\n" +
+				"pre-check\n",
+			expProblems: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		fName := filepath.Join(t.TempDir(), "common.cfg")
+		if err := os.WriteFile(fName, []byte(tc.content), 0o600); err != nil {
+			t.Fatal("can't write the config file: ", err)
+		}
+
+		problems := configFileProblems(fName)
+		if len(problems) != tc.expProblems {
+			t.Log(tc.IDStr())
+			t.Logf("\t: problems: %q", problems)
+			t.Errorf("\t: expected %d problems, got %d",
+				tc.expProblems, len(problems))
+		}
+	}
+}
+
+func TestPreCheckRequested(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		args   []string
+		expReq bool
+	}{
+		{
+			ID:   testhelper.MkID("no args"),
+			args: []string{},
+		},
+		{
+			ID:     testhelper.MkID("pre-check"),
+			args:   []string{"-e", "x := 1", "-pre-check"},
+			expReq: true,
+		},
+		{
+			ID:     testhelper.MkID("pre-check-json, two dashes"),
+			args:   []string{"--pre-check-json"},
+			expReq: true,
+		},
+		{
+			ID:     testhelper.MkID("pre-check-as-json, with value"),
+			args:   []string{"-pre-check-as-json=true"},
+			expReq: true,
+		},
+		{
+			ID:   testhelper.MkID("after the terminator"),
+			args: []string{"--", "-pre-check"},
+		},
+		{
+			ID:   testhelper.MkID("as a value"),
+			args: []string{"-e", "pre-check"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffBool(t, tc.IDStr(), "pre-check requested",
+			preCheckRequested(tc.args), tc.expReq)
+	}
+}
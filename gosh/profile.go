@@ -0,0 +1,273 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/filecheck.mod/filecheck"
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+	"github.com/nickwells/xdg.mod/xdg"
+)
+
+const (
+	paramNameProfile     = "profile"
+	paramNameProfileDir  = "profiles-dir"
+	paramNameProfileList = "profile-list"
+	paramNameProfileShow = "profile-show"
+
+	profileParamGroup = "cmd-profile"
+
+	profileExt = ".cfg"
+)
+
+var profileParamNames = []string{
+	paramNameProfile,
+	paramNameProfileDir,
+	paramNameProfileList,
+	paramNameProfileShow,
+}
+
+// setDfltProfilePath populates the profileDirs slice with the default value.
+func (g *gosh) setDfltProfilePath() {
+	profilePath := []string{
+		"github.com",
+		"nickwells",
+		"utilities",
+		"gosh",
+		"profiles",
+	}
+
+	g.profileDirs = []string{
+		filepath.Join(append([]string{xdg.ConfigHome()}, profilePath...)...),
+	}
+
+	dirs := xdg.ConfigDirs()
+	if len(dirs) > 0 {
+		g.profileDirs = append(g.profileDirs,
+			filepath.Join(append(dirs[:1], profilePath...)...))
+	}
+}
+
+// addProfileParams returns a func that will add parameters concerned with
+// using and listing profiles.
+func addProfileParams(g *gosh) func(ps *param.PSet) error {
+	return func(ps *param.PSet) error {
+		ps.AddGroup(profileParamGroup,
+			"parameters relating to profiles. A profile is a named"+
+				" collection of gosh parameters which can be reused"+
+				" across runs.")
+
+		var profileName string
+
+		ps.Add(paramNameProfile,
+			psetter.String[string]{Value: &profileName},
+			"apply the parameters from the named profile."+
+				"\n\n"+
+				"A profile is a file in one of the profile directories"+
+				" with the name of the profile and a '"+profileExt+"'"+
+				" extension. It holds gosh parameters in the same"+
+				" format as a configuration file: one parameter per"+
+				" line, with any value following an '='. This lets"+
+				" you bundle section code, snippets, imports,"+
+				" readloop and split settings and environment values"+
+				" under a single name."+
+				"\n\n"+
+				"Profiles are applied at the point where this"+
+				" parameter is given and in the order given so you"+
+				" can layer several profiles. Parameters from a later"+
+				" profile or given later on the command line will"+
+				" override single-valued parameters set earlier and"+
+				" any code is added in order. A profile can itself"+
+				" use other profiles but not, directly or"+
+				" indirectly, itself."+
+				"\n\n"+
+				"The profile directories are searched in order and"+
+				" the first profile found is used.",
+			param.AltNames("prof"),
+			param.ValueName("profile-name"),
+			param.GroupName(profileParamGroup),
+			param.PostAction(profilePAF(g, &profileName)),
+			param.SeeAlso(profileParamNames...),
+		)
+
+		ps.Add(paramNameProfileDir,
+			psetter.PathnameListAppender{
+				Value:       &g.profileDirs,
+				Expectation: filecheck.DirExists(),
+				Prepend:     true,
+			},
+			"add a new profiles directory. The directory is added at"+
+				" the start of the list of profile directories and so"+
+				" will be searched before any existing directories."+
+				" Note that it must be given before any profile in it"+
+				" is used.",
+			param.AltNames("profile-dir"),
+			param.GroupName(profileParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(profileParamNames...),
+		)
+
+		ps.Add(paramNameProfileList,
+			psetter.Bool{Value: &g.profileList},
+			"list the available profiles and exit, no program is run."+
+				" The profiles are listed for each profile directory"+
+				" in the order in which they are searched.",
+			param.AltNames("profiles-list", "list-profiles"),
+			param.GroupName(profileParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(profileParamNames...),
+		)
+
+		ps.Add(paramNameProfileShow,
+			psetter.StrListAppender[string]{Value: &g.profileShow},
+			"show the contents of the named profile and exit, no"+
+				" program is run. This can be given multiple times to"+
+				" show several profiles.",
+			param.AltNames("show-profile"),
+			param.ValueName("profile-name"),
+			param.GroupName(profileParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(profileParamNames...),
+		)
+
+		return nil
+	}
+}
+
+// profilePAF generates the Post-Action func (PAF) that applies the
+// parameters in the named profile. It returns an error if the profile
+// cannot be found or if the profile is already being applied.
+//
+// Note that we pass a pointer to the profile name rather than the string -
+// this is necessary otherwise we are passing the text value at the point
+// the PAF is being generated not at the point where the parameter value is
+// given.
+func profilePAF(g *gosh, profileName *string) param.ActionFunc {
+	return func(loc location.L, p *param.BaseParam, _ []string) error {
+		name := *profileName
+
+		if slices.Contains(g.profilesInUse, name) {
+			return fmt.Errorf("the profile %q uses itself: %s",
+				name,
+				strings.Join(append(g.profilesInUse, name), " -> "))
+		}
+
+		path, err := g.findProfile(name)
+		if err != nil {
+			return err
+		}
+
+		g.profilesInUse = append(g.profilesInUse, name)
+		defer func() {
+			g.profilesInUse = g.profilesInUse[:len(g.profilesInUse)-1]
+		}()
+
+		return param.ConfigFileActionFunc(
+			loc, p, []string{paramNameProfile, path})
+	}
+}
+
+// findProfile returns the pathname of the named profile. It searches the
+// profile directories in order and returns the first profile found. A
+// non-nil error is returned if the name is invalid or no profile is found.
+func (g *gosh) findProfile(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf(
+			"the profile name %q must be a relative path"+
+				" within a profiles directory",
+			name)
+	}
+
+	for _, dir := range g.profileDirs {
+		path := filepath.Join(dir, name+profileExt)
+		if filecheck.FileExists().StatusCheck(path) == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("the profile %q was not found in: %s",
+		name, strings.Join(g.profileDirs, ", "))
+}
+
+// profileNames returns the names of the profiles in the given directory. A
+// missing directory has no profiles.
+func profileNames(dir string) ([]string, error) {
+	names := []string{}
+
+	err := filepath.WalkDir(dir,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == dir && errors.Is(err, fs.ErrNotExist) {
+					return fs.SkipAll
+				}
+
+				return err
+			}
+
+			if d.IsDir() || !strings.HasSuffix(path, profileExt) {
+				return nil
+			}
+
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+
+			names = append(names, strings.TrimSuffix(name, profileExt))
+
+			return nil
+		})
+
+	return names, err
+}
+
+// listProfiles lists the available profiles and shows the contents of any
+// profiles requested. If any listing is done then the program will exit
+// after listing is complete.
+func listProfiles(g *gosh) {
+	if !g.profileList && len(g.profileShow) == 0 {
+		return
+	}
+
+	if g.profileList {
+		seen := map[string]bool{}
+
+		for _, dir := range g.profileDirs {
+			names, err := profileNames(dir)
+			g.reportFatalError("list the profiles in", dir, err)
+
+			fmt.Println(dir + ":")
+
+			for _, name := range names {
+				hidden := ""
+				if seen[name] {
+					hidden = " (hidden by an earlier profile)"
+				}
+
+				seen[name] = true
+
+				fmt.Println("    " + name + hidden)
+			}
+		}
+	}
+
+	for _, name := range g.profileShow {
+		path, err := g.findProfile(name)
+		g.reportFatalError("find the profile", name, err)
+
+		content, err := os.ReadFile(path) //nolint:gosec
+		g.reportFatalError("read the profile", path, err)
+
+		fmt.Println("profile: " + name + " (" + path + ")")
+		fmt.Print(string(content))
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+const testProfileDir = "testdata/profiles"
+
+func TestProfileNames(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		dir      string
+		expNames []string
+	}{
+		{
+			ID:  testhelper.MkID("profiles"),
+			dir: testProfileDir,
+			expNames: []string{
+				"count",
+				filepath.Join("csv", "split"),
+				filepath.Join("cycle", "a"),
+				filepath.Join("cycle", "b"),
+			},
+		},
+		{
+			ID:       testhelper.MkID("missing dir"),
+			dir:      filepath.Join(testProfileDir, "nosuchdir"),
+			expNames: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		names, err := profileNames(tc.dir)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffStringSlice(t, tc.IDStr(), "names",
+				names, tc.expNames)
+		}
+	}
+}
+
+func TestFindProfile(t *testing.T) {
+	g := &gosh{
+		profileDirs: []string{
+			filepath.Join(testProfileDir, "nosuchdir"),
+			testProfileDir,
+		},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		name    string
+		expPath string
+	}{
+		{
+			ID:      testhelper.MkID("found"),
+			name:    "count",
+			expPath: filepath.Join(testProfileDir, "count.cfg"),
+		},
+		{
+			ID:      testhelper.MkID("found, sub-dir"),
+			name:    "csv/split",
+			expPath: filepath.Join(testProfileDir, "csv", "split.cfg"),
+		},
+		{
+			ID:     testhelper.MkID("not found"),
+			name:   "nosuchprofile",
+			ExpErr: testhelper.MkExpErr(`the profile "nosuchprofile"`),
+		},
+		{
+			ID:     testhelper.MkID("bad name"),
+			name:   "../count",
+			ExpErr: testhelper.MkExpErr("must be a relative path"),
+		},
+	}
+
+	for _, tc := range testCases {
+		path, err := g.findProfile(tc.name)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "path", path, tc.expPath)
+		}
+	}
+}
+
+func TestProfileCycle(t *testing.T) {
+	g := &gosh{
+		profileDirs:   []string{testProfileDir},
+		profilesInUse: []string{"count", "csv/split"},
+	}
+	name := "count"
+
+	err := profilePAF(g, &name)(location.L{}, nil, nil)
+	testhelper.DiffErr(t, "profile cycle", "error", err,
+		errors.New(`the profile "count" uses itself:`+
+			" count -> csv/split -> count"))
+}
+
+// scriptValues returns the values of the script entries in the named
+// section
+func scriptValues(g *gosh, sName string) []string {
+	vals := []string{}
+	for _, se := range g.scripts[sName] {
+		vals = append(vals, se.value)
+	}
+
+	return vals
+}
+
+func TestProfileParse(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		profiles      []string
+		expErr        string
+		expReadLoop   bool
+		expSplitLine  bool
+		expSplitPat   string
+		expBeforeSect []string
+		expExecSect   []string
+		expAfterSect  []string
+	}{
+		{
+			ID:            testhelper.MkID("count"),
+			profiles:      []string{"count"},
+			expReadLoop:   true,
+			expSplitPat:   dfltSplitPattern,
+			expBeforeSect: []string{"count := 0"},
+			expExecSect:   []string{"count++"},
+			expAfterSect:  []string{"fmt.Println(count)"},
+		},
+		{
+			ID:            testhelper.MkID("layered profiles"),
+			profiles:      []string{"csv/split", "count"},
+			expReadLoop:   true,
+			expSplitLine:  true,
+			expSplitPat:   ",",
+			expBeforeSect: []string{"count := 0"},
+			expExecSect:   []string{"count++"},
+			expAfterSect:  []string{"fmt.Println(count)"},
+		},
+		{
+			ID:       testhelper.MkID("cycle"),
+			profiles: []string{"cycle/a"},
+			expErr: `the profile "cycle/a" uses itself:` +
+				" cycle/a -> cycle/b -> cycle/a",
+			expSplitPat:   dfltSplitPattern,
+			expBeforeSect: []string{},
+			expExecSect:   []string{},
+			expAfterSect:  []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		g := newGosh()
+		ps := makePSet(g)
+
+		args := []string{"-" + paramNameProfileDir, testProfileDir}
+		for _, p := range tc.profiles {
+			args = append(args, "-"+paramNameProfile, p)
+		}
+
+		errFound := false
+
+		for _, errs := range ps.Parse(args) {
+			for _, err := range errs {
+				if tc.expErr != "" && strings.Contains(err.Error(), tc.expErr) {
+					errFound = true
+					continue
+				}
+
+				t.Log(tc.IDStr())
+				t.Errorf("\t: unexpected error: %s", err)
+			}
+		}
+
+		if tc.expErr != "" && !errFound {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the expected error was not found: %s", tc.expErr)
+		}
+
+		testhelper.DiffBool(t, tc.IDStr(), "run in readloop",
+			g.runInReadLoop, tc.expReadLoop)
+		testhelper.DiffBool(t, tc.IDStr(), "split line",
+			g.splitLine, tc.expSplitLine)
+		testhelper.DiffString(t, tc.IDStr(), "split pattern",
+			g.splitPattern, tc.expSplitPat)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "before section",
+			scriptValues(g, beforeSect), tc.expBeforeSect)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "exec section",
+			scriptValues(g, execSect), tc.expExecSect)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "after section",
+			scriptValues(g, afterSect), tc.expAfterSect)
+		testhelper.DiffInt(t, tc.IDStr(), "profiles in use",
+			len(g.profilesInUse), 0)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/location.mod/location"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+)
+
+const (
+	paramNameProgParam = "prog-param"
+
+	progParamSource = "the " + paramNameProgParam + " parameters"
+
+	progParamTerminal = "--"
+)
+
+// progParamTypeInfo records the details of a type of parameter that the
+// generated program can be given
+type progParamTypeInfo struct {
+	goType  string
+	setter  string
+	desc    string
+	literal func(string) (string, error)
+}
+
+// progParamTypes maps the type names which can be given to the
+// details needed to generate the code
+var progParamTypes = map[string]progParamTypeInfo{
+	"string": {
+		goType:  "string",
+		setter:  "psetter.String[string]",
+		desc:    "a string",
+		literal: func(s string) (string, error) { return strconv.Quote(s), nil },
+	},
+	"int": {
+		goType: "int64",
+		setter: "psetter.Int[int64]",
+		desc:   "an integer",
+		literal: func(s string) (string, error) {
+			i, err := strconv.ParseInt(s, 0, 64)
+			if err != nil {
+				return "", err
+			}
+
+			return strconv.FormatInt(i, 10), nil
+		},
+	},
+	"float": {
+		goType: "float64",
+		setter: "psetter.Float[float64]",
+		desc:   "a floating point number",
+		literal: func(s string) (string, error) {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return "", err
+			}
+
+			return strconv.FormatFloat(f, 'g', -1, 64), nil
+		},
+	},
+	"bool": {
+		goType: "bool",
+		setter: "psetter.Bool",
+		desc:   "a flag (true or false)",
+		literal: func(s string) (string, error) {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return "", err
+			}
+
+			return strconv.FormatBool(b), nil
+		},
+	},
+	"strings": {
+		goType: "[]string",
+		setter: "psetter.StrList[string]",
+		desc:   "a comma-separated list of strings",
+		literal: func(s string) (string, error) {
+			vals := []string{}
+			for v := range strings.SplitSeq(s, ",") {
+				vals = append(vals, strconv.Quote(v))
+			}
+
+			return "[]string{" + strings.Join(vals, ", ") + "}", nil
+		},
+	},
+}
+
+// progParamReservedNames holds the names which cannot be used for the
+// variable holding a program parameter value. These are the names of the
+// packages imported by the generated code and the names it declares. Note
+// that the names which gosh declares all start with "gosh" and these are
+// rejected separately.
+var progParamReservedNames = []string{
+	"bufio",
+	"filepath",
+	"fmt",
+	"http",
+	"init",
+	"io",
+	"log",
+	"main",
+	"os",
+	"param",
+	"paramset",
+	"pprof",
+	"ps",
+	"psetter",
+	"regexp",
+	"runtime",
+	"signal",
+	"syscall",
+	"template",
+	"trace",
+}
+
+var progParamNameRE = regexp.MustCompile(
+	`^[a-zA-Z][a-zA-Z0-9]*(-[a-zA-Z0-9]+)*$`)
+
+// progParam holds the details of a parameter to the generated program
+type progParam struct {
+	name     string
+	varName  string
+	typeName string
+	dflt     string
+	help     string
+}
+
+// progParamVarName converts the parameter name into the name of the Go
+// variable which will hold its value. Any dashes are removed and the
+// following letter is converted to upper case.
+func progParamVarName(name string) string {
+	parts := strings.Split(name, "-")
+	for i, p := range parts[1:] {
+		parts[i+1] = strings.ToUpper(p[:1]) + p[1:]
+	}
+
+	return strings.Join(parts, "")
+}
+
+// parseProgParam parses the description of a parameter for the generated
+// program. This has the form: name,type[,default[,help]]. It returns a
+// non-nil error if the description is invalid.
+func parseProgParam(s string) (progParam, error) {
+	const (
+		typePart = 1
+		dfltPart = 2
+		helpPart = 3
+		maxParts = 4
+	)
+
+	parts := strings.SplitN(s, ",", maxParts)
+	if len(parts) <= typePart {
+		return progParam{}, errors.New(
+			"the program parameter must be given as:" +
+				" name,type[,default[,help]]")
+	}
+
+	pp := progParam{
+		name:     strings.TrimSpace(parts[0]),
+		typeName: strings.TrimSpace(parts[typePart]),
+	}
+
+	if !progParamNameRE.MatchString(pp.name) {
+		return progParam{}, fmt.Errorf(
+			"the program parameter name %q is invalid: it must start"+
+				" with a letter and have only letters and digits"+
+				" separated by single dashes",
+			pp.name)
+	}
+
+	if strings.HasPrefix(pp.name, "help") {
+		return progParam{}, fmt.Errorf(
+			"the program parameter name %q is invalid:"+
+				" names starting with 'help' are reserved",
+			pp.name)
+	}
+
+	pp.varName = progParamVarName(pp.name)
+	if err := checkProgParamVarName(pp); err != nil {
+		return progParam{}, err
+	}
+
+	ti, ok := progParamTypes[pp.typeName]
+	if !ok {
+		return progParam{}, fmt.Errorf(
+			"the program parameter type %q is unknown, it must be one of: %s",
+			pp.typeName,
+			strings.Join(progParamTypeNames(), ", "))
+	}
+
+	if len(parts) > dfltPart && parts[dfltPart] != "" {
+		dflt, err := ti.literal(parts[dfltPart])
+		if err != nil {
+			return progParam{}, fmt.Errorf(
+				"the default value for the program parameter %q"+
+					" should be %s: %w",
+				pp.name, ti.desc, err)
+		}
+
+		pp.dflt = dflt
+	}
+
+	if len(parts) > helpPart {
+		pp.help = strings.TrimSpace(parts[helpPart])
+	}
+
+	if pp.help == "" {
+		pp.help = "set the value of " + pp.varName
+	}
+
+	return pp, nil
+}
+
+// checkProgParamVarName returns a non-nil error if the variable name of the
+// program parameter would clash with a Go keyword, a predeclared
+// identifier or a name used by the generated program.
+func checkProgParamVarName(pp progParam) error {
+	switch {
+	case token.IsKeyword(pp.varName):
+		return fmt.Errorf(
+			"the program parameter name %q is a Go keyword", pp.name)
+	case types.Universe.Lookup(pp.varName) != nil:
+		return fmt.Errorf(
+			"the program parameter name %q is a predeclared Go identifier",
+			pp.name)
+	case slices.Contains(progParamReservedNames, pp.varName),
+		strings.HasPrefix(pp.varName, "gosh"):
+		return fmt.Errorf(
+			"the program parameter name %q is invalid:"+
+				" %q is used by the generated program",
+			pp.name, pp.varName)
+	}
+
+	return nil
+}
+
+// progParamTypeNames returns the sorted names of the allowed types
+func progParamTypeNames() []string {
+	return slices.Sorted(maps.Keys(progParamTypes))
+}
+
+// addProgParamParams returns a func that will add the parameter for
+// declaring parameters to the generated program
+func addProgParamParams(g *gosh) func(ps *param.PSet) error {
+	return func(ps *param.PSet) error {
+		var ppDesc string
+
+		ps.Add(paramNameProgParam,
+			psetter.String[string]{Value: &ppDesc},
+			"declare a parameter for the generated program. The"+
+				" value is given as: name,type[,default[,help]]."+
+				" The type must be one of: "+
+				strings.Join(progParamTypeNames(), ", ")+"."+
+				" The help text is the last part and so may contain"+
+				" commas."+
+				"\n\n"+
+				"The generated program will parse its parameters"+
+				" using the same param.mod package that gosh uses"+
+				" and so it will have a full '-help' parameter. The"+
+				" value will be available in every section in a"+
+				" variable with the parameter name converted to"+
+				" camel-case ('max-count' becomes 'maxCount'). This"+
+				" variable name must not be a Go keyword, a"+
+				" predeclared identifier, such as 'len', or a name"+
+				" used by the generated program, such as 'os' or"+
+				" 'main'."+
+				"\n\n"+
+				"Parameters for the generated program are given after"+
+				" the '"+ps.TerminalParam()+"' that ends the gosh"+
+				" parameters. If the program should also be given"+
+				" files or arguments then its parameters must be"+
+				" followed by another '"+progParamTerminal+"' and then"+
+				" the files or arguments. Without this second '"+
+				progParamTerminal+"' everything is taken as a file"+
+				" or argument.",
+			param.AltNames("program-param", "prog-flag", "program-flag"),
+			param.ValueName("name,type,default,help"),
+			param.PostAction(progParamPAF(g, &ppDesc)),
+			param.Attrs(param.DontShowInStdUsage),
+		)
+
+		ps.AddFinalCheck(func() error {
+			g.addProgParams()
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// progParamPAF generates the Post-Action func (PAF) that parses the
+// description of the program parameter and adds it to the list.
+//
+// Note that we pass a pointer to the description rather than the string -
+// this is necessary otherwise we are passing the text value at the point
+// the PAF is being generated not at the point where the parameter value is
+// given.
+func progParamPAF(g *gosh, ppDesc *string) param.ActionFunc {
+	return func(_ location.L, _ *param.BaseParam, _ []string) error {
+		pp, err := parseProgParam(*ppDesc)
+		if err != nil {
+			return err
+		}
+
+		for _, existing := range g.progParams {
+			if existing.varName == pp.varName {
+				return fmt.Errorf(
+					"the program parameter %q has already been given",
+					pp.name)
+			}
+		}
+
+		g.progParams = append(g.progParams, pp)
+
+		return nil
+	}
+}
+
+// addProgParams adds the code to declare and parse the program parameters
+// to the global section.
+func (g *gosh) addProgParams() {
+	if len(g.progParams) == 0 {
+		return
+	}
+
+	g.imports = append(g.imports,
+		"os",
+		"github.com/nickwells/param.mod/v7/param",
+		"github.com/nickwells/param.mod/v7/paramset",
+		"github.com/nickwells/param.mod/v7/psetter")
+
+	g.AddScriptEntry(globalSect, progParamCode(g.progParams),
+		verbatim, progParamSource)
+}
+
+// progParamCode returns the code to declare the variables holding the
+// program parameter values and to parse the program parameters. The
+// parameters are parsed in an init func so that they are set before any
+// section is run. Any trailing parameters replace the program arguments so
+// that the argument and file loops work as usual.
+func progParamCode(pps []progParam) string {
+	var code strings.Builder
+
+	code.WriteString("\nvar (\n")
+
+	for _, pp := range pps {
+		ti := progParamTypes[pp.typeName]
+
+		fmt.Fprintf(&code, "\t%s %s", pp.varName, ti.goType)
+
+		if pp.dflt != "" {
+			code.WriteString(" = " + pp.dflt)
+		}
+
+		code.WriteString("\n")
+	}
+
+	code.WriteString(`)
+
+func init() {
+	ps := paramset.New(
+		func(ps *param.PSet) error {
+`)
+
+	for _, pp := range pps {
+		ti := progParamTypes[pp.typeName]
+
+		fmt.Fprintf(&code, "\t\t\tps.Add(%q, %s{Value: &%s},\n\t\t\t\t%q)\n",
+			pp.name, ti.setter, pp.varName, pp.help)
+	}
+
+	code.WriteString(`
+			return nil
+		},
+	)
+
+	ps.Parse()
+
+	os.Args = append(os.Args[:1], ps.TrailingParams()...)
+}`)
+
+	return code.String()
+}
+
+// programArgs returns the arguments to be passed to the generated program.
+// If there are program parameters then the arguments are preceded by any
+// values for the program parameters and a terminal parameter.
+func (g *gosh) programArgs() []string {
+	if len(g.progParams) == 0 {
+		return g.args
+	}
+
+	args := slices.Clone(g.progParamArgs)
+	args = append(args, progParamTerminal)
+
+	return append(args, g.args...)
+}
+
+// splitProgParamArgs splits the arguments into those for the program
+// parameters and the rest. The program parameters are those before the
+// first terminal parameter; if there is no terminal parameter then all the
+// arguments are returned as the rest.
+func splitProgParamArgs(args []string) ([]string, []string) {
+	i := slices.Index(args, progParamTerminal)
+	if i < 0 {
+		return nil, args
+	}
+
+	return args[:i], args[i+1:]
+}
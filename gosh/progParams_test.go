@@ -0,0 +1,233 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestParseProgParam(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		desc  string
+		expPP progParam
+	}{
+		{
+			ID:   testhelper.MkID("name and type"),
+			desc: "name,string",
+			expPP: progParam{
+				name:     "name",
+				varName:  "name",
+				typeName: "string",
+				help:     "set the value of name",
+			},
+		},
+		{
+			ID:   testhelper.MkID("all parts, help with commas"),
+			desc: "max-count,int,0x10,the maximum count, or zero",
+			expPP: progParam{
+				name:     "max-count",
+				varName:  "maxCount",
+				typeName: "int",
+				dflt:     "16",
+				help:     "the maximum count, or zero",
+			},
+		},
+		{
+			ID:   testhelper.MkID("strings, no default"),
+			desc: "words,strings,,the words",
+			expPP: progParam{
+				name:     "words",
+				varName:  "words",
+				typeName: "strings",
+				help:     "the words",
+			},
+		},
+		{
+			ID:   testhelper.MkID("strings, default"),
+			desc: "words,strings,a",
+			expPP: progParam{
+				name:     "words",
+				varName:  "words",
+				typeName: "strings",
+				dflt:     `[]string{"a"}`,
+				help:     "set the value of words",
+			},
+		},
+		{
+			ID:     testhelper.MkID("no type"),
+			desc:   "name",
+			ExpErr: testhelper.MkExpErr("must be given as"),
+		},
+		{
+			ID:     testhelper.MkID("bad name"),
+			desc:   "a--b,int",
+			ExpErr: testhelper.MkExpErr(`name "a--b" is invalid`),
+		},
+		{
+			ID:     testhelper.MkID("reserved name"),
+			desc:   "help-me,bool",
+			ExpErr: testhelper.MkExpErr("are reserved"),
+		},
+		{
+			ID:     testhelper.MkID("keyword"),
+			desc:   "func,bool",
+			ExpErr: testhelper.MkExpErr("is a Go keyword"),
+		},
+		{
+			ID:     testhelper.MkID("keyword: type"),
+			desc:   "type,string",
+			ExpErr: testhelper.MkExpErr("is a Go keyword"),
+		},
+		{
+			ID:     testhelper.MkID("keyword: range"),
+			desc:   "range,int",
+			ExpErr: testhelper.MkExpErr("is a Go keyword"),
+		},
+		{
+			ID:     testhelper.MkID("predeclared identifier"),
+			desc:   "len,int",
+			ExpErr: testhelper.MkExpErr("is a predeclared Go identifier"),
+		},
+		{
+			ID:   testhelper.MkID("generated import: os"),
+			desc: "os,string",
+			ExpErr: testhelper.MkExpErr(
+				`"os" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated import: param"),
+			desc: "param,string",
+			ExpErr: testhelper.MkExpErr(
+				`"param" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated import: psetter"),
+			desc: "psetter,string",
+			ExpErr: testhelper.MkExpErr(
+				`"psetter" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated import: paramset"),
+			desc: "paramset,string",
+			ExpErr: testhelper.MkExpErr(
+				`"paramset" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated import: fmt"),
+			desc: "fmt,string",
+			ExpErr: testhelper.MkExpErr(
+				`"fmt" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated identifier: main"),
+			desc: "main,bool",
+			ExpErr: testhelper.MkExpErr(
+				`"main" is used by the generated program`),
+		},
+		{
+			ID:   testhelper.MkID("generated identifier: gosh prefix"),
+			desc: "gosh-templates,string",
+			ExpErr: testhelper.MkExpErr(
+				`"goshTemplates" is used by the generated program`),
+		},
+		{
+			ID:     testhelper.MkID("bad type"),
+			desc:   "n,complex",
+			ExpErr: testhelper.MkExpErr(`type "complex" is unknown`),
+		},
+		{
+			ID:     testhelper.MkID("bad default"),
+			desc:   "n,float,x",
+			ExpErr: testhelper.MkExpErr("should be a floating point number"),
+		},
+	}
+
+	for _, tc := range testCases {
+		pp, err := parseProgParam(tc.desc)
+		if testhelper.CheckExpErr(t, err, tc) && err == nil {
+			testhelper.DiffString(t, tc.IDStr(), "name",
+				pp.name, tc.expPP.name)
+			testhelper.DiffString(t, tc.IDStr(), "var name",
+				pp.varName, tc.expPP.varName)
+			testhelper.DiffString(t, tc.IDStr(), "type name",
+				pp.typeName, tc.expPP.typeName)
+			testhelper.DiffString(t, tc.IDStr(), "default",
+				pp.dflt, tc.expPP.dflt)
+			testhelper.DiffString(t, tc.IDStr(), "help",
+				pp.help, tc.expPP.help)
+		}
+	}
+}
+
+func TestProgParamCode(t *testing.T) {
+	pps := []progParam{}
+
+	for _, desc := range []string{
+		"name,string,world",
+		"max-count,int,3,the maximum",
+		"ratio,float",
+		"verbose,bool",
+		"words,strings,a,b",
+	} {
+		pp, err := parseProgParam(desc)
+		if err != nil {
+			t.Fatalf("cannot parse the program parameter %q: %v", desc, err)
+		}
+
+		pps = append(pps, pp)
+	}
+
+	code := progParamCode(pps)
+
+	_, err := parser.ParseFile(token.NewFileSet(), "",
+		"package main\n"+code+"\nfunc main() {}\n", 0)
+	if err != nil {
+		t.Errorf("the generated code doesn't parse: %v\n%s", err, code)
+	}
+
+	for _, s := range []string{
+		`name string = "world"`,
+		"maxCount int64 = 3",
+		"ratio float64\n",
+		`ps.Add("max-count", psetter.Int[int64]{Value: &maxCount},`,
+		`ps.Add("words", psetter.StrList[string]{Value: &words},`,
+		"os.Args = append(os.Args[:1], ps.TrailingParams()...)",
+	} {
+		if !strings.Contains(code, s) {
+			t.Errorf("the generated code should contain %q\n%s", s, code)
+		}
+	}
+}
+
+func TestSplitProgParamArgs(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		args      []string
+		expParams []string
+		expRest   []string
+	}{
+		{
+			ID:      testhelper.MkID("no terminal"),
+			args:    []string{"a", "b"},
+			expRest: []string{"a", "b"},
+		},
+		{
+			ID:        testhelper.MkID("terminal"),
+			args:      []string{"-n", "3", "--", "a", "--"},
+			expParams: []string{"-n", "3"},
+			expRest:   []string{"a", "--"},
+		},
+	}
+
+	for _, tc := range testCases {
+		params, rest := splitProgParamArgs(tc.args)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "params",
+			params, tc.expParams)
+		testhelper.DiffStringSlice(t, tc.IDStr(), "rest", rest, tc.expRest)
+	}
+}
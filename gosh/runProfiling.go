@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nickwells/check.mod/v2/check"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+)
+
+const (
+	paramNameRace       = "race"
+	paramNameCPUProfile = "cpu-profile"
+	paramNameMemProfile = "mem-profile"
+	paramNameTrace      = "trace"
+
+	profilingParamGroup = "cmd-profiling"
+
+	profilingSource = "the profiling parameters"
+)
+
+var profilingParamNames = []string{
+	paramNameRace,
+	paramNameCPUProfile,
+	paramNameMemProfile,
+	paramNameTrace,
+}
+
+// addProfilingParams returns a func that will add parameters concerned
+// with building the program with the race detector and running it under
+// the profiler or tracer.
+func addProfilingParams(g *gosh) func(ps *param.PSet) error {
+	checkStringNotEmpty := check.StringLength[string](check.ValGT(0))
+
+	return func(ps *param.PSet) error {
+		ps.AddGroup(profilingParamGroup,
+			"parameters relating to running the generated program"+
+				" under the race detector, the profiler or the tracer.")
+
+		const profilingNote = "\n\n" +
+			"The profiling is started before main() is called and" +
+			" stopped by a call deferred at the start of main() so" +
+			" it is stopped after all the section code has run," +
+			" even if the code returns early. Deferred calls are" +
+			" not made when the program calls os.Exit (directly or" +
+			" through log.Fatal for instance) and so in that case" +
+			" the profiling is not stopped and the profiles may be" +
+			" incomplete or missing. If the program is run as a" +
+			" webserver the profiling is stopped when the program" +
+			" is interrupted." +
+			"\n\n" +
+			"When the profiling is stopped a summary of where each" +
+			" profile was written is printed to standard error."
+
+		ps.Add(paramNameRace, psetter.Bool{Value: &g.buildWithRace},
+			"build the program with the race detector enabled."+
+				" This is useful when prototyping concurrent code.",
+			param.AltNames("race-detector"),
+			param.GroupName(profilingParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(profilingParamNames...),
+		)
+
+		ps.Add(paramNameCPUProfile,
+			psetter.String[string]{
+				Value:  &g.cpuProfile,
+				Checks: []check.String{checkStringNotEmpty},
+			},
+			"write a CPU profile of the generated program to the named"+
+				" file. The profile can be examined with 'go tool"+
+				" pprof'."+profilingNote,
+			param.AltNames("cpu-prof", "cpuprofile"),
+			param.ValueName("filename"),
+			param.GroupName(profilingParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(profilingParamNames...),
+		)
+
+		ps.Add(paramNameMemProfile,
+			psetter.String[string]{
+				Value:  &g.memProfile,
+				Checks: []check.String{checkStringNotEmpty},
+			},
+			"write a heap profile of the generated program to the named"+
+				" file. The profile is taken when the profiling is"+
+				" stopped and can be examined with 'go tool"+
+				" pprof'."+profilingNote,
+			param.AltNames("mem-prof", "memprofile", "heap-profile"),
+			param.ValueName("filename"),
+			param.GroupName(profilingParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(profilingParamNames...),
+		)
+
+		ps.Add(paramNameTrace,
+			psetter.String[string]{
+				Value:  &g.traceFile,
+				Checks: []check.String{checkStringNotEmpty},
+			},
+			"write an execution trace of the generated program to the"+
+				" named file. The trace can be examined with 'go tool"+
+				" trace'."+profilingNote,
+			param.AltNames("trace-file", "exec-trace"),
+			param.ValueName("filename"),
+			param.GroupName(profilingParamGroup),
+			param.Attrs(param.DontShowInStdUsage),
+			param.SeeAlso(profilingParamNames...),
+		)
+
+		ps.AddFinalCheck(func() error {
+			g.addProfiling()
+			return nil
+		})
+
+		return nil
+	}
+}
+
+// profiling returns true if the generated program is to be profiled or
+// traced.
+func (g *gosh) profiling() bool {
+	return g.cpuProfile != "" || g.memProfile != "" || g.traceFile != ""
+}
+
+// addProfiling adds the race detector to the build arguments and the
+// profiling code to the global section, as requested. The call to stop the
+// profiling is written at the start of main (see writeMainOpen).
+func (g *gosh) addProfiling() {
+	if g.buildWithRace {
+		g.buildArgs = append(g.buildArgs, "-race")
+	}
+
+	if !g.profiling() {
+		return
+	}
+
+	g.imports = append(g.imports, "fmt", "os")
+
+	if g.cpuProfile != "" || g.memProfile != "" {
+		g.imports = append(g.imports, "runtime/pprof")
+	}
+
+	if g.memProfile != "" {
+		g.imports = append(g.imports, "runtime")
+	}
+
+	if g.traceFile != "" {
+		g.imports = append(g.imports, "runtime/trace")
+	}
+
+	if g.runAsWebserver {
+		g.imports = append(g.imports, "os/signal", "syscall")
+	}
+
+	g.AddScriptEntry(globalSect,
+		profilingGlobalCode(
+			g.absRunPath(g.cpuProfile),
+			g.absRunPath(g.memProfile),
+			g.absRunPath(g.traceFile),
+			g.runAsWebserver),
+		verbatim, profilingSource)
+}
+
+// absRunPath returns the name as an absolute pathname relative to the
+// directory where the program will be run. An empty name is returned
+// unchanged.
+func (g *gosh) absRunPath(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(g.runDir, name)
+}
+
+// profilingGlobalCode returns the code to be added to the global section to
+// start and stop the profiling. Profiling is started in an init func and
+// stopped by calling goshProfStop, which is deferred at the start of main.
+// If the program is a webserver the profiling is also stopped when the
+// program is interrupted.
+func profilingGlobalCode(cpuProfile, memProfile, traceFile string,
+	webserver bool,
+) string {
+	var code strings.Builder
+
+	code.WriteString(`
+// goshProfStops holds the funcs which stop the profiling
+var goshProfStops []func()
+
+// goshProfFiles records the profile files written
+var goshProfFiles []string
+
+// goshProfCreate creates the profile file, exiting on failure
+func goshProfCreate(name string) *os.File {
+	f, err := os.Create(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr,
+			"Error creating the profile file %q: %v\n", name, err)
+		os.Exit(1)
+	}
+
+	return f
+}
+
+// goshProfStop stops the profiling and reports the profile files
+func goshProfStop() {
+	for _, stop := range goshProfStops {
+		stop()
+	}
+
+	goshProfStops = nil
+
+	for _, pf := range goshProfFiles {
+		fmt.Fprintln(os.Stderr, "gosh profiling: "+pf)
+	}
+}
+
+func init() {
+`)
+
+	if cpuProfile != "" {
+		fmt.Fprintf(&code, `	{
+		f := goshProfCreate(%q)
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting the CPU profile: %%v\n", err)
+			os.Exit(1)
+		}
+
+		goshProfStops = append(goshProfStops, func() {
+			pprof.StopCPUProfile()
+			f.Close()
+			goshProfFiles = append(goshProfFiles, "CPU profile: "+f.Name())
+		})
+	}
+`, cpuProfile)
+	}
+
+	if traceFile != "" {
+		fmt.Fprintf(&code, `	{
+		f := goshProfCreate(%q)
+		if err := trace.Start(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting the trace: %%v\n", err)
+			os.Exit(1)
+		}
+
+		goshProfStops = append(goshProfStops, func() {
+			trace.Stop()
+			f.Close()
+			goshProfFiles = append(goshProfFiles, "trace: "+f.Name())
+		})
+	}
+`, traceFile)
+	}
+
+	if memProfile != "" {
+		fmt.Fprintf(&code, `	goshProfStops = append(goshProfStops, func() {
+		f := goshProfCreate(%q)
+		defer f.Close()
+
+		runtime.GC()
+
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the heap profile: %%v\n", err)
+			return
+		}
+
+		goshProfFiles = append(goshProfFiles, "heap profile: "+f.Name())
+	})
+`, memProfile)
+	}
+
+	if webserver {
+		code.WriteString(`
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		<-sigs
+		goshProfStop()
+		os.Exit(1)
+	}()
+`)
+	}
+
+	code.WriteString("}")
+
+	return code.String()
+}
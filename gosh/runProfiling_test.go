@@ -0,0 +1,122 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestProfilingGlobalCode(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		cpu, mem, trc string
+		webserver     bool
+		expContains   []string
+		expMissing    []string
+	}{
+		{
+			ID:  testhelper.MkID("cpu only"),
+			cpu: "/tmp/cpu.prof",
+			expContains: []string{
+				`goshProfCreate("/tmp/cpu.prof")`,
+				"pprof.StartCPUProfile(f)",
+			},
+			expMissing: []string{
+				"trace.Start",
+				"pprof.WriteHeapProfile",
+				"signal.Notify",
+			},
+		},
+		{
+			ID:        testhelper.MkID("all, webserver"),
+			cpu:       "/tmp/cpu.prof",
+			mem:       "/tmp/mem.prof",
+			trc:       "/tmp/trace.out",
+			webserver: true,
+			expContains: []string{
+				"pprof.StartCPUProfile(f)",
+				`goshProfCreate("/tmp/mem.prof")`,
+				"pprof.WriteHeapProfile(f)",
+				`goshProfCreate("/tmp/trace.out")`,
+				"trace.Start(f)",
+				"signal.Notify",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		code := profilingGlobalCode(tc.cpu, tc.mem, tc.trc, tc.webserver)
+
+		_, err := parser.ParseFile(token.NewFileSet(), "",
+			"package main\n"+code+"\nfunc main() {}\n", 0)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: the generated code doesn't parse: %v", err)
+		}
+
+		for _, s := range tc.expContains {
+			if !strings.Contains(code, s) {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the generated code should contain %q", s)
+			}
+		}
+
+		for _, s := range tc.expMissing {
+			if strings.Contains(code, s) {
+				t.Log(tc.IDStr())
+				t.Errorf("\t: the generated code should not contain %q", s)
+			}
+		}
+	}
+}
+
+func TestProfilingStop(t *testing.T) {
+	const (
+		afterCode = `fmt.Println("done")`
+		stopCode  = "defer goshProfStop()"
+	)
+
+	g := newGosh()
+	g.cpuProfile = "cpu.prof"
+	g.AddScriptEntry(afterSect, afterCode, verbatim, "after")
+	g.addProfiling()
+
+	testhelper.DiffInt(t, "profiling stop", "after section entries",
+		len(g.scripts[afterSect]), 1)
+
+	fName := filepath.Join(t.TempDir(), goshFilename)
+
+	f, err := os.Create(fName)
+	if err != nil {
+		t.Fatal("can't create the program file: ", err)
+	}
+
+	g.w = f
+	g.writeMainOpen()
+	g.writeScript(afterSect)
+	g.writeMainClose()
+
+	if err := f.Close(); err != nil {
+		t.Fatal("can't close the program file: ", err)
+	}
+
+	content, err := os.ReadFile(fName)
+	if err != nil {
+		t.Fatal("can't read the program file: ", err)
+	}
+
+	code := string(content)
+	stopIdx := strings.Index(code, stopCode)
+	afterIdx := strings.Index(code, afterCode)
+
+	if stopIdx < 0 || afterIdx < 0 || stopIdx > afterIdx {
+		t.Log("profiling stop")
+		t.Errorf("\t: the profiling should be stopped by a deferred call"+
+			" before the section code:\n%s", code)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nickwells/filecheck.mod/filecheck"
+	"github.com/nickwells/param.mod/v7/param"
+	"github.com/nickwells/param.mod/v7/psetter"
+	"github.com/nickwells/snippet.mod/snippet"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+const (
+	paramNameSnippetSave          = "snippet-save"
+	paramNameSnippetSaveSection   = "snippet-save-section"
+	paramNameSnippetSaveDoc       = "snippet-save-doc"
+	paramNameSnippetSaveOverwrite = "snippet-save-overwrite"
+
+	snippetSaveParamGroup = "cmd-snippet-save"
+
+	snippetFilePerms = 0o644 // Owner: Read/Write, the rest: Read
+	snippetDirPerms  = 0o755 // Owner: Read/Write/Search, the rest: Read/Search
+)
+
+var majorVersionRE = regexp.MustCompile(`^v[0-9]+$`)
+
+// snippetHdrIntro starts each snippet header line written by saveSnippet
+var snippetHdrIntro = "// " + snippet.CommentStr + " "
+
+var snippetSaveParamNames = []string{
+	paramNameSnippetSave,
+	paramNameSnippetSaveSection,
+	paramNameSnippetSaveDoc,
+	paramNameSnippetSaveOverwrite,
+}
+
+// addSnippetSaveParams returns a func that will add parameters concerned
+// with saving the code from a section of the script as a snippet.
+func addSnippetSaveParams(g *gosh) func(ps *param.PSet) error {
+	return func(ps *param.PSet) error {
+		ps.AddGroup(snippetSaveParamGroup,
+			"parameters relating to saving the code as a snippet.")
+
+		ps.Add(paramNameSnippetSave,
+			psetter.String[string]{Value: &g.snippetSaveName},
+			"save the code supplied for one of the sections of the"+
+				" program as a snippet with the given name. The snippet"+
+				" is written into the first of the snippets directories"+
+				" and the name may include sub-directories. The snippet"+
+				" will have its imports set from those of the generated"+
+				" program after the imports have been populated."+
+				"\n\n"+
+				"An existing snippet will not be replaced unless"+
+				" the '"+paramNameSnippetSaveOverwrite+"' parameter"+
+				" is also given.",
+			param.AltNames("save-snippet", "snippet-write"),
+			param.ValueName("snippet-name"),
+			param.GroupName(snippetSaveParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(snippetSaveParamNames...),
+			param.SeeNote(noteSnippetsComments),
+		)
+
+		allowedSects := psetter.AllowedVals[string]{}
+		for _, sect := range []string{
+			globalSect,
+			beforeSect, beforeInnerSect,
+			fileBeginSect,
+			execSect,
+			fileEndSect,
+			afterInnerSect, afterSect,
+		} {
+			allowedSects[sect] = "save the code from the '" + sect + "' section"
+		}
+
+		ps.Add(paramNameSnippetSaveSection,
+			psetter.Enum[string]{
+				Value:       &g.snippetSaveSect,
+				AllowedVals: allowedSects,
+			},
+			"the section of the program whose code is to be saved"+
+				" as a snippet.",
+			param.AltNames("snippet-save-sect"),
+			param.GroupName(snippetSaveParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(snippetSaveParamNames...),
+		)
+
+		ps.Add(paramNameSnippetSaveDoc,
+			psetter.StrListAppender[string]{Value: &g.snippetSaveDocs},
+			"a line of documentation to be added to the saved snippet."+
+				" This can be given multiple times and each value will"+
+				" be added as a separate line.",
+			param.AltNames("snippet-save-docs"),
+			param.ValueName("text"),
+			param.GroupName(snippetSaveParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(snippetSaveParamNames...),
+		)
+
+		ps.Add(paramNameSnippetSaveOverwrite,
+			psetter.Bool{Value: &g.snippetSaveOverwrite},
+			"allow the saved snippet to replace an existing snippet"+
+				" of the same name.",
+			param.GroupName(snippetSaveParamGroup),
+			param.Attrs(param.CommandLineOnly|param.DontShowInStdUsage),
+			param.SeeAlso(snippetSaveParamNames...),
+		)
+
+		ps.AddFinalCheck(func() error {
+			if g.snippetSaveName == "" {
+				return nil
+			}
+
+			if g.userEntryCount(g.snippetSaveSect) == 0 {
+				return fmt.Errorf(
+					"the snippet %q cannot be saved:"+
+						" no code has been given for the %q section",
+					g.snippetSaveName, g.snippetSaveSect)
+			}
+
+			_, err := g.snippetSavePath()
+
+			return err
+		})
+
+		return nil
+	}
+}
+
+// snippetSavePath returns the pathname of the snippet to be saved. It
+// returns a non-nil error if the name is not a valid snippet name, if
+// there is no snippet directory or if the snippet already exists and the
+// overwrite flag is not set.
+func (g *gosh) snippetSavePath() (string, error) {
+	name := filepath.Clean(g.snippetSaveName)
+
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf(
+			"the snippet name %q must be a relative path"+
+				" within the snippets directory",
+			g.snippetSaveName)
+	}
+
+	if len(g.snippetDirs) == 0 {
+		return "", errors.New("there is no snippets directory to save into")
+	}
+
+	pathname := filepath.Join(g.snippetDirs[0], name)
+
+	if g.snippetSaveOverwrite {
+		return pathname, nil
+	}
+
+	if err := filecheck.IsNew().StatusCheck(pathname); err != nil {
+		return "", fmt.Errorf(
+			"the snippet %q already exists (give the %q parameter"+
+				" to replace it): %w",
+			pathname, "-"+paramNameSnippetSaveOverwrite, err)
+	}
+
+	return pathname, nil
+}
+
+// saveSnippet writes the code from the chosen section into a snippet
+// file. Only the code supplied by the user is saved; any code which gosh
+// adds itself, such as for profiling or for the program parameters, is
+// not. It is run after the imports have been populated so that the
+// imports can be taken from the generated program.
+func (g *gosh) saveSnippet() {
+	if g.snippetSaveName == "" || g.snippetSaved {
+		return
+	}
+
+	defer g.dbgStack.Start("saveSnippet", "Saving the snippet")()
+
+	intro := g.dbgStack.Tag()
+
+	g.snippetSaved = true
+
+	pathname, err := g.snippetSavePath()
+	g.reportFatalError("save the snippet", g.snippetSaveName, err)
+
+	imports, err := fileImports(filepath.Join(g.goshDir, goshFilename))
+	g.reportFatalError("get the imports for the snippet", goshFilename, err)
+
+	code := stripGoshSnippetComments(g.sectionText[g.snippetSaveSect])
+	content := makeSnippetContent(g.snippetSaveDocs,
+		snippetImportsUsed(imports, code), code)
+
+	verbose.Println(intro, " Writing the snippet: ", pathname)
+
+	err = os.MkdirAll(filepath.Dir(pathname), snippetDirPerms)
+	g.reportFatalError("create the snippet directory",
+		filepath.Dir(pathname), err)
+
+	err = os.WriteFile(pathname, []byte(content), snippetFilePerms)
+	g.reportFatalError("write the snippet", pathname, err)
+
+	fmt.Println("snippet saved: " + pathname)
+}
+
+// fileImports parses the named Go file and returns its imports. Each entry
+// is the import path, prefixed with the package alias and an '=' if the
+// import is named. This is the form expected by the import parameter and
+// in snippet files.
+func fileImports(fileName string) ([]string, error) {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, fileName, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make([]string, 0, len(f.Imports))
+
+	for _, is := range f.Imports {
+		path, err := strconv.Unquote(is.Path.Value)
+		if err != nil {
+			return nil, fmt.Errorf("bad import path %s: %w", is.Path.Value, err)
+		}
+
+		if is.Name != nil {
+			path = is.Name.Name + "=" + path
+		}
+
+		imports = append(imports, path)
+	}
+
+	return imports, nil
+}
+
+// importPkgName returns the name by which the imported package will be
+// referred to in the code. This is the alias if one is given or else a
+// best guess at the package name from the import path.
+func importPkgName(imp string) string {
+	if alias, _, ok := strings.Cut(imp, "="); ok {
+		return alias
+	}
+
+	parts := strings.Split(imp, "/")
+	name := parts[len(parts)-1]
+
+	if len(parts) > 1 && majorVersionRE.MatchString(name) {
+		name = parts[len(parts)-2]
+	}
+
+	name, _, _ = strings.Cut(name, ".")
+
+	return strings.TrimPrefix(name, "go-")
+}
+
+// snippetImportsUsed returns those imports which are used in the code. An
+// import is taken to be used if the package name is followed by a '.'
+// somewhere in the code. Blank and dot imports are always kept as their use
+// cannot be detected.
+func snippetImportsUsed(imports, code []string) []string {
+	text := strings.Join(code, "\n")
+	used := []string{}
+
+	for _, imp := range imports {
+		name := importPkgName(imp)
+		if name == "_" || name == "." {
+			used = append(used, imp)
+			continue
+		}
+
+		useRE := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\.`)
+		if useRE.MatchString(text) {
+			used = append(used, imp)
+		}
+	}
+
+	return used
+}
+
+// stripGoshSnippetComments returns the lines of code with the comments
+// which gosh adds around expanded snippets removed. This includes the
+// comment giving the snippet pathname which follows the BEGIN comment.
+func stripGoshSnippetComments(lines []string) []string {
+	snippetIntro := "//" + goshCommentIntro + "snippet : "
+	stripped := make([]string, 0, len(lines))
+	skipNext := false
+
+	for _, l := range lines {
+		if skipNext {
+			skipNext = false
+			continue
+		}
+
+		if strings.HasPrefix(l, snippetIntro) {
+			skipNext = strings.HasPrefix(l, snippetIntro+"BEGIN ")
+			continue
+		}
+
+		stripped = append(stripped, l)
+	}
+
+	return stripped
+}
+
+// makeSnippetContent returns the text of a snippet file having the given
+// documentation, imports and code.
+func makeSnippetContent(docs, imports, code []string) string {
+	var content strings.Builder
+
+	content.WriteString(snippetHdrIntro + "-*- go -*-\n")
+
+	for _, d := range docs {
+		for dl := range strings.SplitSeq(d, "\n") {
+			content.WriteString(
+				strings.TrimRight(snippetHdrIntro+"Doc: "+dl, " ") + "\n")
+		}
+	}
+
+	for _, imp := range imports {
+		content.WriteString(snippetHdrIntro + "Imports: " + imp + "\n")
+	}
+
+	for _, c := range code {
+		content.WriteString(strings.TrimSuffix(c, "\n") + "\n")
+	}
+
+	return content.String()
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestImportPkgName(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		imp     string
+		expName string
+	}{
+		{
+			ID:      testhelper.MkID("simple"),
+			imp:     "fmt",
+			expName: "fmt",
+		},
+		{
+			ID:      testhelper.MkID("path"),
+			imp:     "path/filepath",
+			expName: "filepath",
+		},
+		{
+			ID:      testhelper.MkID("aliased"),
+			imp:     "fp=path/filepath",
+			expName: "fp",
+		},
+		{
+			ID:      testhelper.MkID("major version"),
+			imp:     "math/rand/v2",
+			expName: "rand",
+		},
+		{
+			ID:      testhelper.MkID("module suffix"),
+			imp:     "github.com/nickwells/twrap.mod/twrap",
+			expName: "twrap",
+		},
+		{
+			ID:      testhelper.MkID("dotted"),
+			imp:     "gopkg.in/yaml.v3",
+			expName: "yaml",
+		},
+		{
+			ID:      testhelper.MkID("go- prefix"),
+			imp:     "github.com/mattn/go-isatty",
+			expName: "isatty",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "package name",
+			importPkgName(tc.imp), tc.expName)
+	}
+}
+
+func TestSnippetImportsUsed(t *testing.T) {
+	imports := []string{
+		"fmt",
+		"os",
+		"strings",
+		"_=embed",
+		"fp=path/filepath",
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		code       []string
+		expImports []string
+	}{
+		{
+			ID:         testhelper.MkID("no code"),
+			expImports: []string{"_=embed"},
+		},
+		{
+			ID: testhelper.MkID("some used"),
+			code: []string{
+				`fmt.Println(strings.ToUpper("x"))`,
+				`_ = fp.Join("a", "b")`,
+			},
+			expImports: []string{
+				"fmt",
+				"strings",
+				"_=embed",
+				"fp=path/filepath",
+			},
+		},
+		{
+			ID: testhelper.MkID("name without selector"),
+			code: []string{
+				`os := 1`,
+				`xfmt.Println(os)`,
+			},
+			expImports: []string{"_=embed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffStringSlice(t, tc.IDStr(), "imports",
+			snippetImportsUsed(imports, tc.code), tc.expImports)
+	}
+}
+
+func TestStripGoshSnippetComments(t *testing.T) {
+	lines := []string{
+		"a := 1",
+		"//" + goshCommentIntro + "snippet : BEGIN s0",
+		"// /path/to/s0",
+		"b := 2",
+		"//" + goshCommentIntro + "snippet : END",
+		"// user comment",
+	}
+	expLines := []string{
+		"a := 1",
+		"b := 2",
+		"// user comment",
+	}
+
+	testhelper.DiffStringSlice(t, "strip comments", "lines",
+		stripGoshSnippetComments(lines), expLines)
+}
+
+func TestMakeSnippetContent(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		docs, imports, code []string
+		expContent          string
+	}{
+		{
+			ID:         testhelper.MkID("code only"),
+			code:       []string{"a := 1"},
+			expContent: "// snippet: -*- go -*-\na := 1\n",
+		},
+		{
+			ID:      testhelper.MkID("all parts"),
+			docs:    []string{"first line", "second\n\nthird"},
+			imports: []string{"fmt", "fp=path/filepath"},
+			code:    []string{"a := 1", "fmt.Println(fp.Base(\"x\"))\n"},
+			expContent: "// snippet: -*- go -*-\n" +
+				"// snippet: Doc: first line\n" +
+				"// snippet: Doc: second\n" +
+				"// snippet: Doc:\n" +
+				"// snippet: Doc: third\n" +
+				"// snippet: Imports: fmt\n" +
+				"// snippet: Imports: fp=path/filepath\n" +
+				"a := 1\n" +
+				"fmt.Println(fp.Base(\"x\"))\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffString(t, tc.IDStr(), "content",
+			makeSnippetContent(tc.docs, tc.imports, tc.code), tc.expContent)
+	}
+}
+
+func TestSnippetSaveUserCode(t *testing.T) {
+	const (
+		userCode   = "var x = 1"
+		userSource = `[command line]: Supplied Parameter:2: "-g" "var x = 1"`
+	)
+
+	g := newGosh()
+	g.AddScriptEntry(globalSect, userCode, verbatim, userSource)
+
+	g.cpuProfile = "cpu.prof"
+	g.addProfiling()
+
+	g.progParams = []progParam{{name: "n", varName: "n", typeName: "int"}}
+	g.addProgParams()
+
+	testhelper.DiffInt(t, "snippet save", "global entries",
+		len(g.scripts[globalSect]), 3)
+	testhelper.DiffInt(t, "snippet save", "user entries",
+		g.userEntryCount(globalSect), 1)
+
+	f, err := os.Create(filepath.Join(t.TempDir(), goshFilename))
+	if err != nil {
+		t.Fatal("can't create the program file: ", err)
+	}
+
+	g.w = f
+	g.writeScript(globalSect)
+
+	if err := f.Close(); err != nil {
+		t.Fatal("can't close the program file: ", err)
+	}
+
+	testhelper.DiffStringSlice(t, "snippet save", "section text",
+		g.sectionText[globalSect], []string{userCode})
+}
@@ -1,16 +1,110 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 )
 
-// readFromStdin will return the text read from os.Stdin
-func readFromStdin(_ *gosh, _ string) ([]string, error) {
-	b, err := io.ReadAll(os.Stdin)
+// dfltStdinDataMarker is the line used to separate the code from the data
+// on standard input if the marker is not given explicitly
+const dfltStdinDataMarker = "__DATA__"
+
+// readFromStdin will return the text read from os.Stdin. If a data marker
+// has been given then only the text up to the marker line is returned and
+// the rest of standard input is kept to be passed to the generated program.
+// Otherwise it is an error if the readloop expects to read its data from
+// standard input as there will be none left.
+func readFromStdin(g *gosh, _ string) ([]string, error) {
+	if g.stdinDataMarker == "" {
+		if g.readsStdinData() {
+			return nil, fmt.Errorf(
+				"the code is being read from standard input and so"+
+					" there is no data for the readloop to read. Give"+
+					" the %q parameter to separate the code from the data",
+				"-"+paramNameStdinData)
+		}
+
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{string(b)}, nil
+	}
+
+	g.stdinData = bufio.NewReader(os.Stdin)
+
+	code, found, err := readUntilMarker(g.stdinData, g.stdinDataMarker)
 	if err != nil {
 		return nil, err
 	}
 
-	return []string{string(b)}, nil
+	if g.readsStdinData() {
+		if !found {
+			return nil, fmt.Errorf(
+				"the readloop expects data on standard input after"+
+					" the marker line (%q) but no marker was found",
+				g.stdinDataMarker)
+		}
+
+		if _, err := g.stdinData.Peek(1); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf(
+					"the readloop expects data on standard input after"+
+						" the marker line (%q) but there is none",
+					g.stdinDataMarker)
+			}
+
+			return nil, err
+		}
+	}
+
+	return []string{code}, nil
+}
+
+// readUntilMarker reads lines from the reader until it finds a line which
+// matches the marker. It returns the text read before the marker line and
+// a flag indicating whether the marker was found. The marker line itself is
+// discarded and the reader is left positioned at the start of the following
+// line.
+func readUntilMarker(r *bufio.Reader, marker string) (string, bool, error) {
+	var text strings.Builder
+
+	for {
+		line, err := r.ReadString('\n')
+		if strings.TrimRight(line, "\r\n") == marker {
+			return text.String(), true, nil
+		}
+
+		text.WriteString(line)
+
+		if errors.Is(err, io.EOF) {
+			return text.String(), false, nil
+		}
+
+		if err != nil {
+			return "", false, err
+		}
+	}
+}
+
+// readsStdinData returns true if the generated program will read its data
+// from standard input
+func (g *gosh) readsStdinData() bool {
+	return g.runInReadLoop && !g.filesToRead
+}
+
+// programStdin returns the reader to be used as the standard input of the
+// generated program. This is the remainder of standard input if the code
+// was read from it up to a data marker line.
+func (g *gosh) programStdin() io.Reader {
+	if g.stdinData != nil {
+		return g.stdinData
+	}
+
+	return os.Stdin
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestReadUntilMarker(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		input    string
+		expText  string
+		expFound bool
+		expRest  string
+	}{
+		{
+			ID:       testhelper.MkID("marker"),
+			input:    "a\nb\n__DATA__\nx\ny\n",
+			expText:  "a\nb\n",
+			expFound: true,
+			expRest:  "x\ny\n",
+		},
+		{
+			ID:       testhelper.MkID("marker, CRLF"),
+			input:    "a\r\n__DATA__\r\nx\r\n",
+			expText:  "a\r\n",
+			expFound: true,
+			expRest:  "x\r\n",
+		},
+		{
+			ID:       testhelper.MkID("marker at the end, no newline"),
+			input:    "a\n__DATA__",
+			expText:  "a\n",
+			expFound: true,
+		},
+		{
+			ID:       testhelper.MkID("marker within a line"),
+			input:    "a\n __DATA__\nb",
+			expText:  "a\n __DATA__\nb",
+			expFound: false,
+		},
+		{
+			ID:       testhelper.MkID("no marker"),
+			input:    "a\nb\n",
+			expText:  "a\nb\n",
+			expFound: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		r := bufio.NewReader(strings.NewReader(tc.input))
+
+		text, found, err := readUntilMarker(r, dfltStdinDataMarker)
+		if err != nil {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: unexpected error: %v", err)
+
+			continue
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "text", text, tc.expText)
+
+		if found != tc.expFound {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: found: expected: %t, got: %t", tc.expFound, found)
+		}
+
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal("cannot read the rest of the input:", err)
+		}
+
+		testhelper.DiffString(t, tc.IDStr(), "rest", string(rest), tc.expRest)
+	}
+}
+
+func TestReadFromStdinNoData(t *testing.T) {
+	tc := struct {
+		testhelper.ID
+		testhelper.ExpErr
+	}{
+		ID: testhelper.MkID("readloop, no files, no data marker"),
+		ExpErr: testhelper.MkExpErr(
+			"there is no data for the readloop to read"),
+	}
+
+	_, err := readFromStdin(&gosh{runInReadLoop: true}, "")
+	testhelper.CheckExpErr(t, err, tc)
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/version"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/nickwells/gogen.mod/gogen"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+const (
+	paramNameGoVersion = "go-version"
+
+	envGoToolchain = "GOTOOLCHAIN"
+
+	toolchainModPrefix = "v0.0.1-"
+
+	anyExecBits = 0o111
+)
+
+// goToolchain records the details of a locally installed Go toolchain
+type goToolchain struct {
+	version string // the toolchain version, for instance go1.22.3
+	goCmd   string // the Go command to run
+	source  string // where the toolchain was found
+}
+
+// String returns a description of the toolchain
+func (tc goToolchain) String() string {
+	return tc.version + " (" + tc.source + ": " + tc.goCmd + ")"
+}
+
+// checkGoVersion checks that the value is a valid Go version for the go
+// directive in a go.mod file (such as 1.22 or 1.22.3). A leading 'go' is
+// allowed.
+func checkGoVersion(v string) error {
+	if !version.IsValid("go" + strings.TrimPrefix(v, "go")) {
+		return fmt.Errorf("%q is not a valid Go version"+
+			" (it should be of the form 1.22 or 1.22.3)", v)
+	}
+
+	return nil
+}
+
+// goDirectiveVersion returns the version in the form used in the go
+// directive (without the leading 'go')
+func goDirectiveVersion(v string) string {
+	return strings.TrimPrefix(v, "go")
+}
+
+// toolchainFromModName returns the Go version from the name of a toolchain
+// module directory in the module cache. These have names like
+// 'v0.0.1-go1.22.3.linux-amd64'. It returns false if the name is not that
+// of a toolchain for the given OS and architecture.
+func toolchainFromModName(name, goos, goarch string) (string, bool) {
+	v, ok := strings.CutPrefix(name, toolchainModPrefix)
+	if !ok {
+		return "", false
+	}
+
+	v, ok = strings.CutSuffix(v, "."+goos+"-"+goarch)
+	if !ok || !version.IsValid(v) {
+		return "", false
+	}
+
+	return v, true
+}
+
+// chooseToolchain returns the toolchain to use for the required version.
+// The current toolchain is used if it is new enough, otherwise the newest
+// of the other toolchains that is new enough is used. It returns false if
+// no toolchain is new enough.
+func chooseToolchain(required string, current *goToolchain,
+	others []goToolchain,
+) (goToolchain, bool) {
+	required = "go" + goDirectiveVersion(required)
+
+	if current != nil && version.Compare(current.version, required) >= 0 {
+		return *current, true
+	}
+
+	var best *goToolchain
+
+	for i, tc := range others {
+		if version.Compare(tc.version, required) < 0 {
+			continue
+		}
+
+		if best == nil || version.Compare(tc.version, best.version) > 0 {
+			best = &others[i]
+		}
+	}
+
+	if best == nil {
+		return goToolchain{}, false
+	}
+
+	return *best, true
+}
+
+// currentToolchain returns the toolchain of the Go command gosh will use.
+// The Go command is run with toolchain switching turned off so that it
+// reports its own version.
+func currentToolchain() (*goToolchain, error) {
+	goCmd := gogen.GetGoCmdName()
+
+	cmd := exec.Command(goCmd, "env", "GOVERSION") //nolint:gosec
+	cmd.Env = append(os.Environ(), envGoToolchain+"=local")
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the version of %q: %w", goCmd, err)
+	}
+
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 || !version.IsValid(fields[0]) {
+		return nil, fmt.Errorf("cannot get the version of %q: bad version: %q",
+			goCmd, strings.TrimSpace(string(out)))
+	}
+
+	return &goToolchain{
+		version: fields[0],
+		goCmd:   goCmd,
+		source:  "the Go command",
+	}, nil
+}
+
+// localToolchains returns the toolchains installed in the module cache
+// (by an earlier automatic download) and in the SDK directory (by the
+// golang.org/dl commands).
+func localToolchains() []goToolchain {
+	toolchains := []goToolchain{}
+
+	cmd := exec.Command(gogen.GetGoCmdName(), "env", "GOMODCACHE") //nolint:gosec
+	cmd.Env = append(os.Environ(), envGoToolchain+"=local")
+
+	if out, err := cmd.Output(); err == nil {
+		dir := filepath.Join(strings.TrimSpace(string(out)),
+			"golang.org", "toolchain@")
+
+		matches, _ := filepath.Glob(dir + toolchainModPrefix + "*")
+		for _, m := range matches {
+			v, ok := toolchainFromModName(
+				strings.TrimPrefix(m, dir), runtime.GOOS, runtime.GOARCH)
+			if !ok {
+				continue
+			}
+
+			toolchains = appendIfExecutable(toolchains, goToolchain{
+				version: v,
+				goCmd:   filepath.Join(m, "bin", "go"),
+				source:  "the module cache",
+			})
+		}
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		matches, _ := filepath.Glob(filepath.Join(home, "sdk", "go*"))
+		for _, m := range matches {
+			v := filepath.Base(m)
+			if !version.IsValid(v) {
+				continue
+			}
+
+			toolchains = appendIfExecutable(toolchains, goToolchain{
+				version: v,
+				goCmd:   filepath.Join(m, "bin", "go"),
+				source:  "the SDK directory",
+			})
+		}
+	}
+
+	return toolchains
+}
+
+// appendIfExecutable appends the toolchain to the slice if its Go command
+// is an executable file
+func appendIfExecutable(toolchains []goToolchain, tc goToolchain,
+) []goToolchain {
+	info, err := os.Stat(tc.goCmd)
+	if err != nil ||
+		!info.Mode().IsRegular() ||
+		info.Mode().Perm()&anyExecBits == 0 {
+		return toolchains
+	}
+
+	return append(toolchains, tc)
+}
+
+// setToolchain finds a locally installed toolchain which supports the
+// required Go version and sets the Go command to use it. Toolchain
+// switching is turned off so that the Go command will never try to
+// download a toolchain. It is a fatal error if there is no suitable
+// toolchain.
+func (g *gosh) setToolchain() {
+	if g.goVersion == "" {
+		return
+	}
+
+	defer g.dbgStack.Start("setToolchain",
+		"Finding a Go toolchain for version "+g.goVersion)()
+
+	intro := g.dbgStack.Tag()
+
+	current, err := currentToolchain()
+	if err != nil {
+		verbose.Println(intro, " ", err)
+	}
+
+	others := localToolchains()
+
+	tc, ok := chooseToolchain(g.goVersion, current, others)
+	if !ok {
+		g.reportFatalError("find a Go toolchain for version", g.goVersion,
+			noToolchainError(g.goVersion, current, others))
+	}
+
+	verbose.Println(intro, " Using: "+tc.String())
+
+	if tc.goCmd != gogen.GetGoCmdName() {
+		err = gogen.SetGoCmdName(tc.goCmd)
+		g.reportFatalError("set the Go command", tc.goCmd, err)
+	}
+
+	verbose.Println(intro, " Setting "+envGoToolchain+"=local")
+
+	err = os.Setenv(envGoToolchain, "local")
+	g.reportFatalError("set the environment variable", envGoToolchain, err)
+}
+
+// noToolchainError returns an error explaining that no suitable toolchain
+// is installed and listing those that are.
+func noToolchainError(required string, current *goToolchain,
+	others []goToolchain,
+) error {
+	var msg strings.Builder
+
+	msg.WriteString("no locally installed Go toolchain supports this version.")
+
+	all := slices.Clone(others)
+	if current != nil {
+		all = append([]goToolchain{*current}, all...)
+	}
+
+	if len(all) == 0 {
+		msg.WriteString(" No toolchains were found.")
+	} else {
+		msg.WriteString(" The toolchains found are:")
+
+		for _, tc := range all {
+			msg.WriteString("\n    " + tc.String())
+		}
+	}
+
+	msg.WriteString("\ngosh will not download a toolchain;" +
+		" install one supporting go" + goDirectiveVersion(required) +
+		" (for instance, using the golang.org/dl commands) and try again")
+
+	return errors.New(msg.String())
+}
+
+// setGoDirective sets the go directive in the go.mod file to the required
+// Go version.
+func (g *gosh) setGoDirective() {
+	if g.goVersion == "" {
+		return
+	}
+
+	defer g.dbgStack.Start("setGoDirective", "Setting the go directive")()
+
+	intro := g.dbgStack.Tag()
+
+	arg := "-go=" + goDirectiveVersion(g.goVersion)
+
+	verbose.Println(intro, " Command: go mod edit "+arg)
+	gogen.ExecGoCmd(gogen.NoCmdIO, "mod", "edit", arg)
+}
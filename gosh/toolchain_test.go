@@ -0,0 +1,123 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestCheckGoVersion(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		testhelper.ExpErr
+		v string
+	}{
+		{ID: testhelper.MkID("language version"), v: "1.22"},
+		{ID: testhelper.MkID("release"), v: "1.22.3"},
+		{ID: testhelper.MkID("release, go prefix"), v: "go1.22.3"},
+		{ID: testhelper.MkID("release candidate"), v: "1.23rc1"},
+		{
+			ID:     testhelper.MkID("bad version"),
+			v:      "1.x",
+			ExpErr: testhelper.MkExpErr(`"1.x" is not a valid Go version`),
+		},
+		{
+			ID:     testhelper.MkID("empty"),
+			v:      "",
+			ExpErr: testhelper.MkExpErr(`"" is not a valid Go version`),
+		},
+	}
+
+	for _, tc := range testCases {
+		err := checkGoVersion(tc.v)
+		testhelper.CheckExpErr(t, err, tc)
+	}
+}
+
+func TestToolchainFromModName(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		name       string
+		expVersion string
+		expOK      bool
+	}{
+		{
+			ID:         testhelper.MkID("good"),
+			name:       "v0.0.1-go1.22.3.linux-amd64",
+			expVersion: "go1.22.3",
+			expOK:      true,
+		},
+		{
+			ID:   testhelper.MkID("other architecture"),
+			name: "v0.0.1-go1.22.3.linux-arm64",
+		},
+		{
+			ID:   testhelper.MkID("not a toolchain"),
+			name: "v0.1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		v, ok := toolchainFromModName(tc.name, "linux", "amd64")
+		testhelper.DiffString(t, tc.IDStr(), "version", v, tc.expVersion)
+
+		if ok != tc.expOK {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: ok: expected: %t, got: %t", tc.expOK, ok)
+		}
+	}
+}
+
+func TestChooseToolchain(t *testing.T) {
+	current := &goToolchain{version: "go1.22.3", goCmd: "go"}
+	others := []goToolchain{
+		{version: "go1.24.1", goCmd: "/sdk/go1.24.1/bin/go"},
+		{version: "go1.25.0", goCmd: "/cache/go1.25.0/bin/go"},
+		{version: "go1.23.0", goCmd: "/sdk/go1.23.0/bin/go"},
+	}
+
+	testCases := []struct {
+		testhelper.ID
+		required string
+		current  *goToolchain
+		expGoCmd string
+		expOK    bool
+	}{
+		{
+			ID:       testhelper.MkID("current is new enough"),
+			required: "1.22",
+			current:  current,
+			expGoCmd: "go",
+			expOK:    true,
+		},
+		{
+			ID:       testhelper.MkID("current is too old"),
+			required: "1.23",
+			current:  current,
+			expGoCmd: "/cache/go1.25.0/bin/go",
+			expOK:    true,
+		},
+		{
+			ID:       testhelper.MkID("no current toolchain"),
+			required: "go1.24.1",
+			expGoCmd: "/cache/go1.25.0/bin/go",
+			expOK:    true,
+		},
+		{
+			ID:       testhelper.MkID("none new enough"),
+			required: "1.26",
+			current:  current,
+		},
+	}
+
+	for _, tc := range testCases {
+		chosen, ok := chooseToolchain(tc.required, tc.current, others)
+		testhelper.DiffString(t, tc.IDStr(), "go command",
+			chosen.goCmd, tc.expGoCmd)
+
+		if ok != tc.expOK {
+			t.Log(tc.IDStr())
+			t.Errorf("\t: ok: expected: %t, got: %t", tc.expOK, ok)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/nickwells/gogen.mod/gogen"
+	"github.com/nickwells/verbose.mod/verbose"
+)
+
+// vetGoFile runs go vet over the generated program. Any problems found are
+// reported against the parameter, section or snippet that supplied the
+// offending code rather than against the generated program. It returns
+// false if go vet fails.
+func (g *gosh) vetGoFile() bool {
+	if !g.vet {
+		return true
+	}
+
+	defer g.dbgStack.Start("vetGoFile", "Vetting the program")()
+
+	intro := g.dbgStack.Tag()
+
+	vetCmd := []string{"vet"}
+	vetCmd = append(vetCmd, g.vetArgs...)
+	vetCmd = append(vetCmd, ".")
+
+	verbose.Println(intro, " Command: go "+strings.Join(vetCmd, " "))
+
+	out, err := exec.Command( //nolint:gosec
+		gogen.GetGoCmdName(), vetCmd...).CombinedOutput()
+	if err == nil {
+		return true
+	}
+
+	verbose.Println(intro, " go vet failed: ", err)
+	verbose.Println(intro, " go vet output:\n", string(out))
+
+	fmt.Fprintln(os.Stderr, "gosh: go vet reports problems with the program:")
+	fmt.Fprint(os.Stderr, mapGoshPositions(string(out), g.readGoshCode()))
+
+	g.exitStatus = goshExitStatusVetFail
+	g.dontCleanup = true
+
+	return false
+}
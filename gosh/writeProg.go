@@ -16,6 +16,7 @@ const (
 	webTag   = "webserver"
 	argTag   = "argsloop"
 	rlTag    = "readloop"
+	profTag  = "profiling"
 
 	splitSfx = " - splitline"
 	filesSfx = " - filelist"
@@ -48,18 +49,27 @@ func (g *gosh) writeScript(scriptName string) {
 		g.print(g.comment(sectionFrame))
 	}
 
-	for _, se := range script {
+	for i, se := range script {
 		lines, err := se.expand(g, se.value)
 		if err != nil {
 			g.addError("script: "+scriptName, err)
 			continue
 		}
 
+		g.print(entryMarker(scriptName, i))
+
 		for _, s := range lines {
 			g.print(s)
 		}
+
+		if g.isUserEntry(scriptName, i) {
+			g.sectionText[scriptName] = append(g.sectionText[scriptName],
+				lines...)
+		}
 	}
 
+	g.print(entriesEndMarker(scriptName))
+
 	if g.addComments {
 		g.print(g.comment(sectionFrame))
 		g.print(g.comment(sectionEnd))
@@ -135,6 +145,12 @@ func (g *gosh) writeReadLoop() {
 			tag+splitSfx)
 	}
 
+	if g.inPlaceEditTxn {
+		g.gDecl("_ipeFiles", "", tag+ipeSfx)
+		g.gDecl("_ipeTemps", "", tag+ipeSfx)
+		g.gDecl("_ipeFailed", "", tag+ipeSfx)
+	}
+
 	g.writeScript(beforeSect)
 
 	if g.filesToRead {
@@ -145,17 +161,24 @@ func (g *gosh) writeReadLoop() {
 	}
 
 	g.writeScript(beforeInnerSect)
+	g.writeScript(fileBeginSect)
 	g.writeScanLoopOpen(tag)
 
 	g.writeScript(execSect)
 
 	g.writeScanLoopClose(tag)
+	g.writeScript(fileEndSect)
 	g.writeScript(afterInnerSect)
 
 	if g.filesToRead {
 		g.writeFileLoopClose(tag + filesSfx)
 	}
 
+	if g.inPlaceEditTxn {
+		g.gPrint("goshIPECommit(_ipeFiles, _ipeTemps, _ipeFailed)",
+			tag+ipeSfx)
+	}
+
 	g.writeScript(afterSect)
 }
 
@@ -178,6 +201,7 @@ func (g *gosh) writeScanLoopClose(tag string) {
 	g.gPrint("if _err := _l.Err(); _err != nil {", tag)
 	g.in()
 	g.gPrintErr(`"Error reading %q : %v\n", _fn, _err`, tag)
+	g.writeIPEFailed(tag)
 	g.out()
 	g.gPrint("}", tag)
 }
@@ -194,6 +218,7 @@ func (g *gosh) writeFileLoopOpen(tag string) {
 		{
 			g.in()
 			g.gPrintErr(`"Error opening: %q : %v\n", _fn, _err`, tag)
+			g.writeIPEFailed(tag)
 			g.gPrint(`continue`, tag)
 			g.out()
 		}
@@ -238,6 +263,7 @@ func (g *gosh) writeInPlaceEditOpen(tag string) {
 		g.in()
 		g.gPrintErr(`"Error creating the temp file for %q : %v\n", _fn, _err`,
 			tag)
+		g.writeIPEFailed(tag)
 		g.gPrint(`_f.Close()`, tag)
 		g.gPrint(`continue`, tag)
 		g.out()
@@ -289,6 +315,22 @@ func (g *gosh) writeInPlaceEditClose(tag string) {
 		return
 	}
 
+	if g.inPlaceEditTxn {
+		g.gPrint(`if _err := _w.Close(); _err != nil {`, tag)
+		{
+			g.in()
+			g.gPrintErr(`"Error writing the new %q : %v\n", _fn, _err`, tag)
+			g.gPrint(`_ipeFailed = true`, tag)
+			g.out()
+		}
+
+		g.gPrint("}", tag)
+		g.gPrint(`_ipeFiles = append(_ipeFiles, _fn)`, tag)
+		g.gPrint(`_ipeTemps = append(_ipeTemps, _w.Name())`, tag)
+
+		return
+	}
+
 	g.gPrint(`_w.Close()`, tag)
 	g.gPrint(`if _err := os.Rename(_fn, _fn+"`+origExt+`"); _err != nil {`, tag)
 	{
@@ -308,6 +350,95 @@ func (g *gosh) writeInPlaceEditClose(tag string) {
 	g.gPrint("}", tag)
 }
 
+// writeIPEFailed writes the code to record that a transactional in-place
+// edit has failed.
+func (g *gosh) writeIPEFailed(tag string) {
+	if !g.inPlaceEditTxn {
+		return
+	}
+
+	g.gPrint(`_ipeFailed = true`, tag+ipeSfx)
+}
+
+// writeIPECommitFunc writes the func which completes a transactional
+// in-place edit. If the edit has failed all the new files are removed and
+// none of the original files are changed. Otherwise each original file is
+// renamed and replaced by its new file. If any rename fails then the files
+// already replaced are restored from their originals and any remaining new
+// files are removed. The program exits with a non-zero status if the edit
+// fails or is rolled back.
+func (g *gosh) writeIPECommitFunc() {
+	if !g.inPlaceEditTxn {
+		return
+	}
+
+	tag := rlTag + ipeSfx
+
+	g.gPrint("", tag)
+
+	for _, line := range strings.Split(ipeCommitFunc, "\n") {
+		g.gPrint(line, tag)
+	}
+}
+
+// ipeCommitFunc is the text of the func which completes a transactional
+// in-place edit
+const ipeCommitFunc = `func goshIPECommit(files, temps []string, failed bool) {
+	removeTemps := func(temps []string) {
+		for _, t := range temps {
+			if err := os.Remove(t); err != nil {
+				fmt.Fprintf(os.Stderr,
+					"Error removing the new file %q : %v\n", t, err)
+			}
+		}
+	}
+
+	if failed {
+		removeTemps(temps)
+		fmt.Fprintln(os.Stderr,
+			"The in-place edit has failed, no files have been changed")
+		os.Exit(1)
+	}
+
+	replaced := 0
+	for i, fn := range files {
+		if err := os.Rename(fn, fn+"` + origExt + `"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error making copy of %q : %v\n", fn, err)
+			break
+		}
+
+		if err := os.Rename(temps[i], fn); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recreating %q : %v\n", fn, err)
+			if err := os.Rename(fn+"` + origExt + `", fn); err != nil {
+				fmt.Fprintf(os.Stderr,
+					"Error restoring %q : %v\n", fn, err)
+			}
+			break
+		}
+
+		replaced++
+	}
+
+	if replaced == len(files) {
+		return
+	}
+
+	for i := replaced - 1; i >= 0; i-- {
+		fn := files[i]
+		if err := os.Rename(fn+"` + origExt + `", fn); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring %q : %v\n", fn, err)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Restored %q\n", fn)
+	}
+
+	removeTemps(temps[replaced:])
+	fmt.Fprintln(os.Stderr,
+		"The in-place edit has been rolled back")
+	os.Exit(1)
+}`
+
 // writeWebserverInit writes the webserver boilerplate code
 // (if any) into the Go file
 func (g *gosh) writeWebserverInit() {
@@ -411,13 +542,22 @@ func (g *gosh) writeGoFile() {
 	if g.runAsWebserver {
 		g.writeWebserverHandler()
 	}
+
+	g.writeIPECommitFunc()
 }
 
-// writeMainOpen writes the opening of the main func.
+// writeMainOpen writes the opening of the main func. If the program is
+// being profiled then the profiling is stopped by a deferred call so that
+// it is stopped after all the section code has run.
 func (g *gosh) writeMainOpen() {
 	g.gPrint("", frameTag)
 	g.gPrint("func main() {", frameTag)
 	g.in()
+
+	if g.profiling() {
+		g.gPrint("defer goshProfStop()", profTag)
+		g.gPrint("", frameTag)
+	}
 }
 
 // writeMainClose writes the closing of the main func.
@@ -443,5 +583,8 @@ func (g *gosh) writeGoshComment() {
 	g.print(`//
 // All lines of code generated by gosh (apart from these) end
 // with a comment like this: '//` + goshCommentIntro + `...'.
-// User provided code has no automatic end-of-line comment.`)
+// User provided code has no automatic end-of-line comment.
+// Each piece of user provided code is preceded by a comment
+// like this: '//` + goshCommentIntro + `entry : ...'. These are used
+// to report problems against the parameter that supplied the code.`)
 }
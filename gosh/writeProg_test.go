@@ -0,0 +1,16 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestIPECommitFunc(t *testing.T) {
+	_, err := parser.ParseFile(token.NewFileSet(), "",
+		"package main\n"+ipeCommitFunc+"\n", 0)
+	if err != nil {
+		t.Errorf("the in-place edit commit func doesn't parse: %v\n%s",
+			err, ipeCommitFunc)
+	}
+}
@@ -0,0 +1,460 @@
+/*
+Package textdiff finds the differences between two texts and shows them,
+either in the unified diff format or in two columns, side by side. It is
+shared by the commands in this module that show differences between
+files.
+*/
+package textdiff
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The ways of treating white space when comparing lines
+const (
+	WSExact        = "exact"
+	WSIgnoreChange = "ignore-change"
+	WSIgnoreAll    = "ignore-all"
+)
+
+// NoNewlineMsg is shown after the last line of a text if it has no
+// trailing newline
+const NoNewlineMsg = `\ No newline at end of file`
+
+const (
+	sbsGutterWidth = 3
+	tabWidth       = 8
+)
+
+// ANSI escape sequences used to colour the diff output
+const (
+	colourReset = "\x1b[0m"
+	colourBold  = "\x1b[1m"
+	colourRed   = "\x1b[31m"
+	colourGreen = "\x1b[32m"
+	colourCyan  = "\x1b[36m"
+)
+
+// Op records a line of a diff: whether it is in both texts (' '),
+// deleted from the first ('-') or inserted in the second ('+'), and the
+// line from each text. The two lines of a line in both texts may differ in
+// white space if that is being ignored.
+type Op struct {
+	Kind byte
+	A    string
+	B    string
+}
+
+// Line returns the line to show for the diff operation; for a line in
+// both texts this is the line from the first text
+func (op Op) Line() string {
+	if op.Kind == '+' {
+		return op.B
+	}
+
+	return op.A
+}
+
+// SplitLines splits the text into lines, each keeping its newline. The
+// last line will have no newline if the text does not end with one.
+func SplitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// lineKey returns the value used to compare the line. Depending on the
+// white space setting, runs of white space are treated as a single space
+// and trailing white space is ignored, or white space is ignored
+// altogether.
+func lineKey(line, whiteSpace string) string {
+	switch whiteSpace {
+	case WSIgnoreChange:
+		key := strings.Join(strings.Fields(line), " ")
+		if strings.TrimLeftFunc(line, unicode.IsSpace) != line {
+			key = " " + key
+		}
+
+		return key
+	case WSIgnoreAll:
+		return strings.Join(strings.Fields(line), "")
+	}
+
+	return line
+}
+
+// lineKeys returns the comparison values of the lines
+func lineKeys(lines []string, whiteSpace string) []string {
+	keys := make([]string, 0, len(lines))
+	for _, l := range lines {
+		keys = append(keys, lineKey(l, whiteSpace))
+	}
+
+	return keys
+}
+
+// Diff returns the edits needed to change a into b, found using
+// Myers' O(ND) algorithm. This gives the shortest edit script; deleted
+// lines are given before the inserted lines which replace them.
+//
+// The furthest reaching point on each diagonal is recorded for each number
+// of edits so that the path can be traced back from the end.
+func Diff(a, b []string, whiteSpace string) []Op {
+	aKeys, bKeys := lineKeys(a, whiteSpace), lineKeys(b, whiteSpace)
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v[offset-d:offset+d+1]))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && aKeys[x] == bKeys[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	ops := make([]Op, 0, max(n, m))
+	x, y := n, m
+
+	for d := len(trace) - 1; d > 0; d-- {
+		vPrev := func(k int) int { return trace[d][k+d] }
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && vPrev(k-1) < vPrev(k+1)) {
+			prevK = k + 1
+		}
+
+		prevX := vPrev(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: ' ', A: a[x], B: b[y]})
+		}
+
+		if x == prevX {
+			y--
+			ops = append(ops, Op{Kind: '+', B: b[y]})
+		} else {
+			x--
+			ops = append(ops, Op{Kind: '-', A: a[x]})
+		}
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, Op{Kind: ' ', A: a[x], B: b[y]})
+	}
+
+	slices.Reverse(ops)
+
+	return ops
+}
+
+// Hunk records the start and end (exclusive) of a hunk in the diff
+// operations
+type Hunk struct {
+	Start, End int
+}
+
+// Hunks returns the hunks of the diff. Each hunk holds one or more
+// changes together with up to context unchanged lines before and after.
+// Changes separated by no more than twice the context are put in the same
+// hunk.
+func Hunks(ops []Op, context int) []Hunk {
+	hunks := []Hunk{}
+
+	nextChange := func(from int) int {
+		for k := from; k < len(ops); k++ {
+			if ops[k].Kind != ' ' {
+				return k
+			}
+		}
+
+		return -1
+	}
+
+	for c := nextChange(0); c >= 0; {
+		start := max(0, c-context)
+		last := c
+
+		for {
+			n := nextChange(last + 1)
+			if n < 0 || n-last-1 > 2*context {
+				break
+			}
+
+			last = n
+		}
+
+		end := min(len(ops), last+context+1)
+		hunks = append(hunks, Hunk{Start: start, End: end})
+
+		c = nextChange(end)
+	}
+
+	return hunks
+}
+
+// opPositions returns, for each diff operation, the number of lines of the
+// first and second texts which come before it
+func opPositions(ops []Op) ([]int, []int) {
+	aPos := make([]int, len(ops))
+	bPos := make([]int, len(ops))
+
+	a, b := 0, 0
+
+	for k, op := range ops {
+		aPos[k], bPos[k] = a, b
+
+		if op.Kind != '+' {
+			a++
+		}
+
+		if op.Kind != '-' {
+			b++
+		}
+	}
+
+	return aPos, bPos
+}
+
+// hunkRange returns the range of lines in the hunk header. The lines are
+// numbered from 1; an empty range is given as the line before the range.
+func hunkRange(before, length int) string {
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", before)
+	case 1:
+		return fmt.Sprintf("%d", before+1)
+	}
+
+	return fmt.Sprintf("%d,%d", before+1, length)
+}
+
+// hunkHeader returns the header line for the hunk giving the lines of each
+// text that it covers
+func hunkHeader(ops []Op, h Hunk, aPos, bPos []int) string {
+	aLen, bLen := 0, 0
+
+	for _, op := range ops[h.Start:h.End] {
+		if op.Kind != '+' {
+			aLen++
+		}
+
+		if op.Kind != '-' {
+			bLen++
+		}
+	}
+
+	return fmt.Sprintf("@@ -%s +%s @@",
+		hunkRange(aPos[h.Start], aLen), hunkRange(bPos[h.Start], bLen))
+}
+
+// Colours holds the colours to use for the parts of the diff
+type Colours struct {
+	Header, Hunk, Del, Ins, Reset string
+}
+
+// NewColours returns the colours to use; if useColour is false then
+// no colours are used
+func NewColours(useColour bool) Colours {
+	if !useColour {
+		return Colours{}
+	}
+
+	return Colours{
+		Header: colourBold,
+		Hunk:   colourCyan,
+		Del:    colourRed,
+		Ins:    colourGreen,
+		Reset:  colourReset,
+	}
+}
+
+// paint returns the text in the colour, if any
+func (dc Colours) paint(colour, text string) string {
+	if colour == "" {
+		return text
+	}
+
+	return colour + text + dc.Reset
+}
+
+// Unified returns the differences in the unified diff format
+func Unified(aName, bName string, ops []Op, hunks []Hunk,
+	dc Colours,
+) string {
+	var diff strings.Builder
+
+	diff.WriteString(dc.paint(dc.Header, "--- "+aName) + "\n")
+	diff.WriteString(dc.paint(dc.Header, "+++ "+bName) + "\n")
+
+	aPos, bPos := opPositions(ops)
+
+	for _, h := range hunks {
+		diff.WriteString(
+			dc.paint(dc.Hunk, hunkHeader(ops, h, aPos, bPos)) + "\n")
+
+		for _, op := range ops[h.Start:h.End] {
+			colour := ""
+
+			switch op.Kind {
+			case '-':
+				colour = dc.Del
+			case '+':
+				colour = dc.Ins
+			}
+
+			line, hasNewline := strings.CutSuffix(op.Line(), "\n")
+			diff.WriteString(dc.paint(colour, string(op.Kind)+line) + "\n")
+
+			if !hasNewline {
+				diff.WriteString(NoNewlineMsg + "\n")
+			}
+		}
+	}
+
+	return diff.String()
+}
+
+// expandTabs returns the line with any trailing newline removed, tabs
+// replaced by spaces and other control characters removed
+func expandTabs(line string) string {
+	var expanded strings.Builder
+
+	col := 0
+
+	for _, r := range strings.TrimSuffix(line, "\n") {
+		switch {
+		case r == '\t':
+			spaces := tabWidth - col%tabWidth
+			expanded.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+		case unicode.IsControl(r):
+		default:
+			expanded.WriteRune(r)
+			col++
+		}
+	}
+
+	return expanded.String()
+}
+
+// fitColumn returns the line, with tabs expanded, cut to the column width.
+// If pad is true the line is padded with spaces to fill the column.
+func fitColumn(line string, width int, pad bool) string {
+	runes := []rune(expandTabs(line))
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+
+	text := string(runes)
+	if pad {
+		text += strings.Repeat(" ", width-len(runes))
+	}
+
+	return text
+}
+
+// SideBySide returns the differences in two columns with the first
+// text on the left and the second on the right. The gutter between them
+// shows whether the line is changed ('|'), only in the first text ('<') or
+// only in the second ('>').
+func SideBySide(aName, bName string, ops []Op, hunks []Hunk,
+	width int, dc Colours,
+) string {
+	colWidth := (width - sbsGutterWidth) / 2
+
+	var diff strings.Builder
+
+	row := func(left, right string, mark byte) {
+		lColour, rColour := "", ""
+
+		switch mark {
+		case '|':
+			lColour, rColour = dc.Del, dc.Ins
+		case '<':
+			lColour = dc.Del
+		case '>':
+			rColour = dc.Ins
+		}
+
+		diff.WriteString(dc.paint(lColour, fitColumn(left, colWidth, true)) +
+			" " + string(mark) + " " +
+			dc.paint(rColour, fitColumn(right, colWidth, false)))
+		diff.WriteString("\n")
+	}
+
+	diff.WriteString(dc.paint(dc.Header,
+		fitColumn(aName, colWidth, true)+
+			strings.Repeat(" ", sbsGutterWidth)+
+			fitColumn(bName, colWidth, false)) + "\n")
+
+	aPos, bPos := opPositions(ops)
+
+	for _, h := range hunks {
+		diff.WriteString(
+			dc.paint(dc.Hunk, hunkHeader(ops, h, aPos, bPos)) + "\n")
+
+		for k := h.Start; k < h.End; {
+			if ops[k].Kind == ' ' {
+				row(ops[k].A, ops[k].B, ' ')
+				k++
+
+				continue
+			}
+
+			dels, ins := []string{}, []string{}
+
+			for ; k < h.End && ops[k].Kind != ' '; k++ {
+				if ops[k].Kind == '-' {
+					dels = append(dels, ops[k].A)
+				} else {
+					ins = append(ins, ops[k].B)
+				}
+			}
+
+			for i := range max(len(dels), len(ins)) {
+				switch {
+				case i >= len(ins):
+					row(dels[i], "", '<')
+				case i >= len(dels):
+					row("", ins[i], '>')
+				default:
+					row(dels[i], ins[i], '|')
+				}
+			}
+		}
+	}
+
+	return diff.String()
+}
@@ -0,0 +1,231 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nickwells/testhelper.mod/v2/testhelper"
+)
+
+func TestSplitLines(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		text     string
+		expLines []string
+	}{
+		{
+			ID: testhelper.MkID("empty"),
+		},
+		{
+			ID:       testhelper.MkID("trailing newline"),
+			text:     "a\nb\n",
+			expLines: []string{"a\n", "b\n"},
+		},
+		{
+			ID:       testhelper.MkID("no trailing newline"),
+			text:     "a\nb",
+			expLines: []string{"a\n", "b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		testhelper.DiffStringSlice(t, tc.IDStr(), "lines",
+			SplitLines(tc.text), tc.expLines)
+	}
+}
+
+// opsText returns the diff operations as a string, one line per
+// operation, for easy comparison
+func opsText(ops []Op) string {
+	var text strings.Builder
+	for _, op := range ops {
+		text.WriteString(string(op.Kind) + op.Line())
+	}
+
+	return text.String()
+}
+
+// lcsLen returns the length of the longest common subsequence of a and b
+func lcsLen(a, b []string) int {
+	l := make([][]int, len(a)+1)
+	for i := range l {
+		l[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				l[i][j] = l[i+1][j+1] + 1
+			} else {
+				l[i][j] = max(l[i+1][j], l[i][j+1])
+			}
+		}
+	}
+
+	return l[0][0]
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		testhelper.ID
+		a, b       string
+		whiteSpace string
+		expOps     string
+	}{
+		{
+			ID:     testhelper.MkID("both empty"),
+			expOps: "",
+		},
+		{
+			ID:     testhelper.MkID("all inserted"),
+			b:      "a\nb\n",
+			expOps: "+a\n+b\n",
+		},
+		{
+			ID:     testhelper.MkID("all deleted"),
+			a:      "a\nb\n",
+			expOps: "-a\n-b\n",
+		},
+		{
+			ID:     testhelper.MkID("changed line"),
+			a:      "a\nb\nc\n",
+			b:      "a\nB\nc\n",
+			expOps: " a\n-b\n+B\n c\n",
+		},
+		{
+			ID:     testhelper.MkID("no final newline"),
+			a:      "a\nb",
+			b:      "a\nb\n",
+			expOps: " a\n-b+b\n",
+		},
+		{
+			ID:         testhelper.MkID("white space changes ignored"),
+			a:          "a  b\nc\n",
+			b:          "a b \n c\n",
+			whiteSpace: WSIgnoreChange,
+			expOps:     " a  b\n-c\n+ c\n",
+		},
+		{
+			ID:         testhelper.MkID("all white space ignored"),
+			a:          "a  b\nc\n",
+			b:          "ab\n c\n",
+			whiteSpace: WSIgnoreAll,
+			expOps:     " a  b\n c\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		ops := Diff(SplitLines(tc.a), SplitLines(tc.b), tc.whiteSpace)
+		testhelper.DiffString(t, tc.IDStr(), "diff", opsText(ops), tc.expOps)
+	}
+}
+
+func TestDiffIsShortest(t *testing.T) {
+	texts := []string{
+		"",
+		"a\n",
+		"a\nb\nc\na\nb\nb\na\n",
+		"c\nb\na\nb\na\nc\n",
+		"x\na\ny\nb\nz\nc\n",
+		"a\nb\nc\nd\ne\nf\ng\n",
+		"g\nf\ne\nd\nc\nb\na\n",
+	}
+
+	for _, aText := range texts {
+		for _, bText := range texts {
+			a, b := SplitLines(aText), SplitLines(bText)
+			id := "diff " + strings.ReplaceAll(aText, "\n", ",") +
+				" => " + strings.ReplaceAll(bText, "\n", ",")
+
+			ops := Diff(a, b, WSExact)
+
+			var common, aLines, bLines []string
+
+			for _, op := range ops {
+				switch op.Kind {
+				case ' ':
+					common = append(common, op.A)
+					aLines = append(aLines, op.A)
+					bLines = append(bLines, op.B)
+				case '-':
+					aLines = append(aLines, op.A)
+				case '+':
+					bLines = append(bLines, op.B)
+				}
+			}
+
+			testhelper.DiffStringSlice(t, id, "first text", aLines, a)
+			testhelper.DiffStringSlice(t, id, "second text", bLines, b)
+			testhelper.DiffInt(t, id, "common lines",
+				len(common), lcsLen(a, b))
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\neleven"
+
+	testCases := []struct {
+		testhelper.ID
+		context int
+		expDiff string
+	}{
+		{
+			ID:      testhelper.MkID("no context"),
+			context: 0,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -3 +3 @@\n-3\n+three\n" +
+				"@@ -10,0 +11 @@\n+eleven\n" +
+				NoNewlineMsg + "\n",
+		},
+		{
+			ID:      testhelper.MkID("default context"),
+			context: 3,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+				"@@ -8,3 +8,4 @@\n 8\n 9\n 10\n+eleven\n" +
+				NoNewlineMsg + "\n",
+		},
+		{
+			ID:      testhelper.MkID("large context"),
+			context: 4,
+			expDiff: "--- a\n+++ b\n" +
+				"@@ -1,10 +1,11 @@\n 1\n 2\n-3\n+three\n" +
+				" 4\n 5\n 6\n 7\n 8\n 9\n 10\n+eleven\n" +
+				NoNewlineMsg + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		ops := Diff(SplitLines(a), SplitLines(b), WSExact)
+		hunks := Hunks(ops, tc.context)
+		testhelper.DiffString(t, tc.IDStr(), "diff",
+			Unified("a", "b", ops, hunks, NewColours(false)),
+			tc.expDiff)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	a := "one\ntwo\n\tthree\nfour\n"
+	b := "one\n2\nthree\nfour\nfive\nsix\n"
+
+	ops := Diff(SplitLines(a), SplitLines(b), WSExact)
+	hunks := Hunks(ops, 1)
+
+	const width = 23
+
+	expDiff := "" +
+		"a            b\n" +
+		"@@ -1,4 +1,6 @@\n" +
+		"one          one\n" +
+		"two        | 2\n" +
+		"        th | three\n" +
+		"four         four\n" +
+		"           > five\n" +
+		"           > six\n"
+
+	testhelper.DiffString(t, "side-by-side", "diff",
+		SideBySide("a", "b", ops, hunks, width, NewColours(false)),
+		expDiff)
+}